@@ -12,10 +12,23 @@ type Cache interface {
 	Set(ctx context.Context, key string, value []byte, expiration time.Duration) error
 	Get(ctx context.Context, key string) ([]byte, error)
 	Delete(ctx context.Context, keys ...string) error
+	SetAdd(ctx context.Context, key string, expiration time.Duration, members ...string) error
+	SetMembers(ctx context.Context, key string) ([]string, error)
+	SetRemove(ctx context.Context, key string, members ...string) error
+	// Atomically increments the integer at key, the expiration is only applied when the key is created
+	Increment(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	// Remaining time to live of the key, zero when the key does not expire
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Replaces the expiration of an existing key
+	Expire(ctx context.Context, key string, expiration time.Duration) error
 }
 
 var ErrNotFound = NotFoundError{}
 
+// Returned when a key holds a different kind of value than the operation expects
+var ErrWrongType = errors.New("Operation against a key holding the wrong kind of value")
+
 type NotFoundError struct{}
 
 func (e NotFoundError) Error() string {
@@ -41,9 +54,72 @@ func (cache *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
 	if errors.Is(err, redis.Nil) {
 		return nil, ErrNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
 	return val, nil
 }
 
 func (cache *RedisCache) Delete(ctx context.Context, keys ...string) error {
 	return cache.client.Del(ctx, keys...).Err()
 }
+
+func (cache *RedisCache) SetAdd(ctx context.Context, key string, expiration time.Duration, members ...string) error {
+	pipe := cache.client.TxPipeline()
+	pipe.SAdd(ctx, key, members)
+	pipe.Expire(ctx, key, expiration)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (cache *RedisCache) SetMembers(ctx context.Context, key string) ([]string, error) {
+	return cache.client.SMembers(ctx, key).Result()
+}
+
+func (cache *RedisCache) SetRemove(ctx context.Context, key string, members ...string) error {
+	return cache.client.SRem(ctx, key, members).Err()
+}
+
+func (cache *RedisCache) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	// Sent as one transaction so the key can not be left without an expiration between the commands
+	pipe := cache.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, expiration)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (cache *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := cache.client.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (cache *RedisCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := cache.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	switch ttl {
+	case -2:
+		return 0, ErrNotFound
+	case -1:
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (cache *RedisCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	ok, err := cache.client.PExpire(ctx, key, expiration).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	value []byte
+	// Set when the entry was created through the Set* operations
+	members   map[string]struct{}
+	expiresAt time.Time
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// Keeps entries in process memory, for tests and single instance development servers.
+// Expired entries are never returned and are swept periodically when a cleanup interval is given.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	done    chan struct{}
+}
+
+func NewMemoryCache(cleanupInterval time.Duration) *MemoryCache {
+	cache := &MemoryCache{
+		entries: make(map[string]*memoryEntry),
+		done:    make(chan struct{}),
+	}
+	if cleanupInterval > 0 {
+		go cache.sweep(cleanupInterval)
+	}
+	return cache
+}
+
+// Stops the background sweep
+func (cache *MemoryCache) Close() {
+	close(cache.done)
+}
+
+func (cache *MemoryCache) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			cache.mu.Lock()
+			for key, entry := range cache.entries {
+				if entry.expired(now) {
+					delete(cache.entries, key)
+				}
+			}
+			cache.mu.Unlock()
+		case <-cache.done:
+			return
+		}
+	}
+}
+
+// Must be called with the lock held
+func (cache *MemoryCache) get(key string) *memoryEntry {
+	entry, ok := cache.entries[key]
+	if !ok {
+		return nil
+	}
+	if entry.expired(time.Now()) {
+		delete(cache.entries, key)
+		return nil
+	}
+	return entry
+}
+
+func expiresAt(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(expiration)
+}
+
+func (cache *MemoryCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.entries[key] = &memoryEntry{
+		value:     append([]byte(nil), value...),
+		expiresAt: expiresAt(expiration),
+	}
+	return nil
+}
+
+func (cache *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry := cache.get(key)
+	if entry == nil {
+		return nil, ErrNotFound
+	}
+	if entry.members != nil {
+		return nil, ErrWrongType
+	}
+	return append([]byte(nil), entry.value...), nil
+}
+
+func (cache *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	for _, key := range keys {
+		delete(cache.entries, key)
+	}
+	return nil
+}
+
+func (cache *MemoryCache) SetAdd(ctx context.Context, key string, expiration time.Duration, members ...string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry := cache.get(key)
+	if entry == nil {
+		entry = &memoryEntry{members: make(map[string]struct{})}
+		cache.entries[key] = entry
+	}
+	if entry.members == nil {
+		return ErrWrongType
+	}
+	for _, member := range members {
+		entry.members[member] = struct{}{}
+	}
+	entry.expiresAt = expiresAt(expiration)
+	return nil
+}
+
+func (cache *MemoryCache) SetMembers(ctx context.Context, key string) ([]string, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry := cache.get(key)
+	if entry == nil {
+		return []string{}, nil
+	}
+	if entry.members == nil {
+		return nil, ErrWrongType
+	}
+	members := make([]string, 0, len(entry.members))
+	for member := range entry.members {
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (cache *MemoryCache) SetRemove(ctx context.Context, key string, members ...string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry := cache.get(key)
+	if entry == nil {
+		return nil
+	}
+	if entry.members == nil {
+		return ErrWrongType
+	}
+	for _, member := range members {
+		delete(entry.members, member)
+	}
+	// Redis removes sets once they are empty
+	if len(entry.members) == 0 {
+		delete(cache.entries, key)
+	}
+	return nil
+}
+
+func (cache *MemoryCache) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry := cache.get(key)
+	if entry == nil {
+		entry = &memoryEntry{value: []byte("0"), expiresAt: expiresAt(expiration)}
+		cache.entries[key] = entry
+	}
+	if entry.members != nil {
+		return 0, ErrWrongType
+	}
+	val, err := strconv.ParseInt(string(entry.value), 10, 64)
+	if err != nil {
+		return 0, ErrWrongType
+	}
+	val++
+	entry.value = []byte(strconv.FormatInt(val, 10))
+	return val, nil
+}
+
+func (cache *MemoryCache) Exists(ctx context.Context, key string) (bool, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	return cache.get(key) != nil, nil
+}
+
+func (cache *MemoryCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry := cache.get(key)
+	if entry == nil {
+		return 0, ErrNotFound
+	}
+	if entry.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return time.Until(entry.expiresAt), nil
+}
+
+func (cache *MemoryCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry := cache.get(key)
+	if entry == nil {
+		return ErrNotFound
+	}
+	if expiration <= 0 {
+		// Matches redis, where a non-positive expiration deletes the key
+		delete(cache.entries, key)
+		return nil
+	}
+	entry.expiresAt = expiresAt(expiration)
+	return nil
+}
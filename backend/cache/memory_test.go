@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Short enough to wait out in tests
+const testExpiration = 20 * time.Millisecond
+
+func newTestMemoryCache(t *testing.T) *MemoryCache {
+	t.Helper()
+	cache := NewMemoryCache(0)
+	t.Cleanup(cache.Close)
+	return cache
+}
+
+// Every operation behaves the same through a prefix
+func testCaches(t *testing.T) map[string]Cache {
+	t.Helper()
+	return map[string]Cache{
+		"memory": newTestMemoryCache(t),
+		"prefix": NewPrefixCache(newTestMemoryCache(t), "test"),
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		setup func(c Cache) error
+	}{
+		{"set", func(c Cache) error {
+			return c.Set(ctx, "key", []byte("value"), testExpiration)
+		}},
+		{"set add", func(c Cache) error {
+			return c.SetAdd(ctx, "key", testExpiration, "a")
+		}},
+		{"increment", func(c Cache) error {
+			_, err := c.Increment(ctx, "key", testExpiration)
+			return err
+		}},
+		{"expire", func(c Cache) error {
+			if err := c.Set(ctx, "key", []byte("value"), 0); err != nil {
+				return err
+			}
+			return c.Expire(ctx, "key", testExpiration)
+		}},
+	}
+	for name, c := range testCaches(t) {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				if err := tt.setup(c); err != nil {
+					t.Fatal(err)
+				}
+				if ok, _ := c.Exists(ctx, "key"); !ok {
+					t.Fatal("key does not exist before expiring")
+				}
+				if ttl, err := c.TTL(ctx, "key"); err != nil || ttl <= 0 || ttl > testExpiration {
+					t.Fatalf("got ttl %v (%v), want within %v", ttl, err, testExpiration)
+				}
+
+				time.Sleep(testExpiration)
+				if ok, _ := c.Exists(ctx, "key"); ok {
+					t.Fatal("key exists after expiring")
+				}
+				if _, err := c.Get(ctx, "key"); !errors.Is(err, ErrNotFound) {
+					t.Fatalf("got %v, want ErrNotFound", err)
+				}
+				if members, err := c.SetMembers(ctx, "key"); err != nil || len(members) != 0 {
+					t.Fatalf("got members %v (%v), want none", members, err)
+				}
+				if _, err := c.TTL(ctx, "key"); !errors.Is(err, ErrNotFound) {
+					t.Fatalf("got %v, want ErrNotFound", err)
+				}
+			})
+		}
+	}
+}
+
+func TestCacheIncrement(t *testing.T) {
+	ctx := context.Background()
+	for name, c := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			for want := int64(1); want <= 3; want++ {
+				// The expiration of later increments must not extend the window
+				expiration := testExpiration
+				if want > 1 {
+					expiration = time.Hour
+				}
+				got, err := c.Increment(ctx, "count", expiration)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Fatalf("got %v, want %v", got, want)
+				}
+			}
+			if ttl, _ := c.TTL(ctx, "count"); ttl > testExpiration {
+				t.Fatalf("got ttl %v, want within the first expiration %v", ttl, testExpiration)
+			}
+
+			time.Sleep(testExpiration)
+			if got, err := c.Increment(ctx, "count", testExpiration); err != nil || got != 1 {
+				t.Fatalf("got %v (%v) after expiring, want 1", got, err)
+			}
+
+			if err := c.Set(ctx, "value", []byte("text"), 0); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Increment(ctx, "value", 0); !errors.Is(err, ErrWrongType) {
+				t.Fatalf("incrementing text got %v, want ErrWrongType", err)
+			}
+		})
+	}
+}
+
+func TestCacheSets(t *testing.T) {
+	ctx := context.Background()
+	for name, c := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := c.SetAdd(ctx, "set", 0, "a", "b"); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.SetAdd(ctx, "set", 0, "b", "c"); err != nil {
+				t.Fatal(err)
+			}
+			members, err := c.SetMembers(ctx, "set")
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(members)
+			if want := []string{"a", "b", "c"}; !slices.Equal(members, want) {
+				t.Fatalf("got members %v, want %v", members, want)
+			}
+
+			if err := c.SetRemove(ctx, "set", "a", "missing"); err != nil {
+				t.Fatal(err)
+			}
+			members, _ = c.SetMembers(ctx, "set")
+			slices.Sort(members)
+			if want := []string{"b", "c"}; !slices.Equal(members, want) {
+				t.Fatalf("got members %v after removing, want %v", members, want)
+			}
+
+			// Empty sets are removed
+			if err := c.SetRemove(ctx, "set", "b", "c"); err != nil {
+				t.Fatal(err)
+			}
+			if ok, _ := c.Exists(ctx, "set"); ok {
+				t.Fatal("empty set still exists")
+			}
+
+			if err := c.Set(ctx, "value", []byte("text"), 0); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.SetAdd(ctx, "value", 0, "a"); !errors.Is(err, ErrWrongType) {
+				t.Fatalf("adding to a value got %v, want ErrWrongType", err)
+			}
+			if _, err := c.SetMembers(ctx, "value"); !errors.Is(err, ErrWrongType) {
+				t.Fatalf("reading members of a value got %v, want ErrWrongType", err)
+			}
+			if err := c.SetAdd(ctx, "set", 0, "a"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Get(ctx, "set"); !errors.Is(err, ErrWrongType) {
+				t.Fatalf("reading a set as a value got %v, want ErrWrongType", err)
+			}
+		})
+	}
+}
+
+func TestCacheDelete(t *testing.T) {
+	ctx := context.Background()
+	for name, c := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range []string{"a", "b", "c"} {
+				if err := c.Set(ctx, key, []byte(key), 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := c.Delete(ctx, "a", "b", "missing"); err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range map[string]bool{"a": false, "b": false, "c": true} {
+				if ok, _ := c.Exists(ctx, key); ok != want {
+					t.Errorf("%v exists: got %v, want %v", key, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	for name, c := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := c.Set(ctx, "key", []byte("value"), 0); err != nil {
+				t.Fatal(err)
+			}
+			if ttl, err := c.TTL(ctx, "key"); err != nil || ttl != 0 {
+				t.Fatalf("got ttl %v (%v) for a key without expiration, want 0", ttl, err)
+			}
+			if err := c.Expire(ctx, "key", time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			if ttl, _ := c.TTL(ctx, "key"); ttl <= time.Hour-time.Minute || ttl > time.Hour {
+				t.Fatalf("got ttl %v, want about an hour", ttl)
+			}
+
+			// Matches redis, where a non-positive expiration deletes the key
+			if err := c.Expire(ctx, "key", 0); err != nil {
+				t.Fatal(err)
+			}
+			if ok, _ := c.Exists(ctx, "key"); ok {
+				t.Fatal("key exists after a zero expiration")
+			}
+			if err := c.Expire(ctx, "key", time.Hour); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expiring a missing key got %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
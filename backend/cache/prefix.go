@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Namespaces every key of the wrapped cache, so separate features can share one backend
+type PrefixCache struct {
+	cache  Cache
+	prefix string
+}
+
+func NewPrefixCache(cache Cache, prefix string) *PrefixCache {
+	return &PrefixCache{
+		cache:  cache,
+		prefix: prefix,
+	}
+}
+
+func (c *PrefixCache) key(key string) string {
+	return c.prefix + key
+}
+
+func (c *PrefixCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return c.cache.Set(ctx, c.key(key), value, expiration)
+}
+
+func (c *PrefixCache) Get(ctx context.Context, key string) ([]byte, error) {
+	return c.cache.Get(ctx, c.key(key))
+}
+
+func (c *PrefixCache) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.key(key)
+	}
+	return c.cache.Delete(ctx, prefixed...)
+}
+
+func (c *PrefixCache) SetAdd(ctx context.Context, key string, expiration time.Duration, members ...string) error {
+	return c.cache.SetAdd(ctx, c.key(key), expiration, members...)
+}
+
+func (c *PrefixCache) SetMembers(ctx context.Context, key string) ([]string, error) {
+	return c.cache.SetMembers(ctx, c.key(key))
+}
+
+func (c *PrefixCache) SetRemove(ctx context.Context, key string, members ...string) error {
+	return c.cache.SetRemove(ctx, c.key(key), members...)
+}
+
+func (c *PrefixCache) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return c.cache.Increment(ctx, c.key(key), expiration)
+}
+
+func (c *PrefixCache) Exists(ctx context.Context, key string) (bool, error) {
+	return c.cache.Exists(ctx, c.key(key))
+}
+
+func (c *PrefixCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return c.cache.TTL(ctx, c.key(key))
+}
+
+func (c *PrefixCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return c.cache.Expire(ctx, c.key(key), expiration)
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPrefixCacheIsolation(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestMemoryCache(t)
+	sessions := NewPrefixCache(backend, "session:")
+	limits := NewPrefixCache(backend, "limit:")
+
+	if err := sessions.Set(ctx, "key", []byte("session"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limits.Get(ctx, "key"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("other prefix got %v, want ErrNotFound", err)
+	}
+	if val, err := backend.Get(ctx, "session:key"); err != nil || string(val) != "session" {
+		t.Fatalf("backend got %q (%v), want the prefixed key", val, err)
+	}
+
+	// The same key under another prefix is a separate entry
+	if _, err := limits.Increment(ctx, "key", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := limits.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if val, err := sessions.Get(ctx, "key"); err != nil || string(val) != "session" {
+		t.Fatalf("got %q (%v) after deleting under another prefix, want the value kept", val, err)
+	}
+
+	if err := limits.SetAdd(ctx, "set", 0, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := sessions.Exists(ctx, "set"); ok {
+		t.Fatal("set is visible under another prefix")
+	}
+	if err := sessions.Expire(ctx, "set", 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expiring under another prefix got %v, want ErrNotFound", err)
+	}
+}
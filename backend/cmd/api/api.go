@@ -1,8 +1,11 @@
 package api
 
 import (
+	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/john-vh/college_testing/backend/cache"
@@ -11,8 +14,11 @@ import (
 	"github.com/john-vh/college_testing/backend/filestore"
 	"github.com/john-vh/college_testing/backend/services"
 	"github.com/john-vh/college_testing/backend/services/auth"
+	"github.com/john-vh/college_testing/backend/services/auth/fakeoidc"
 	"github.com/john-vh/college_testing/backend/services/business"
+	"github.com/john-vh/college_testing/backend/services/institution"
 	"github.com/john-vh/college_testing/backend/services/notifications"
+	"github.com/john-vh/college_testing/backend/services/ratelimit"
 	"github.com/john-vh/college_testing/backend/services/sessions"
 	"github.com/john-vh/college_testing/backend/services/user"
 	"github.com/redis/go-redis/v9"
@@ -37,7 +43,6 @@ func NewAPIServer(addr string, cfg env.Config, store *db.PgxStore, cache *redis.
 func (server *APIServer) Run() error {
 	router := http.NewServeMux()
 	apirouter := http.NewServeMux()
-	apirouter.Handle("/api/", http.StripPrefix("/api", router))
 
 	backgroundServices := make([]services.BackgroundService, 0)
 
@@ -46,45 +51,174 @@ func (server *APIServer) Run() error {
 	notificationsService := notifications.NewNotificationService(mailClient, server.cfg.UI_URI, server.cfg.TEMPLATES_DIR, slog.Default())
 	backgroundServices = append(backgroundServices, notificationsService)
 
+	var err error
+
+	// Cache, namespaced per feature so keys never collide
+	var baseCache cache.Cache = cache.NewRedisCache(server.cache)
+	if server.cfg.CACHE_DRIVER == "memory" {
+		memoryCache := cache.NewMemoryCache(time.Minute)
+		defer memoryCache.Close()
+		baseCache = memoryCache
+		slog.Warn("Using in-memory cache, data is lost on restart and not shared between instances")
+	}
+	sessionStore := cache.NewPrefixCache(baseCache, "session:")
+	rateLimitStore := cache.NewPrefixCache(baseCache, "ratelimit:")
+	authStateStore := cache.NewPrefixCache(baseCache, "auth:")
+	userCache := cache.NewPrefixCache(baseCache, "user:")
+
 	// Sessions
-	const authSessionTTL = time.Hour * 24 * 30
-	const unauthSessionTTL = time.Hour
-	sessionStore := cache.NewRedisCache(server.cache)
-	sessionsHandler := sessions.NewSessionHandler(slog.Default(), sessionStore, authSessionTTL, unauthSessionTTL)
+	sessionsHandler := sessions.NewSessionHandler(slog.Default(), sessionStore, sessions.SessionsConfig{
+		IdleTTL:          time.Hour * 24 * 7,
+		MaxLifetime:      time.Hour * 24 * 30,
+		RotationInterval: time.Hour * 24,
+		UnauthorizedTTL:  time.Hour,
+		ImpersonationTTL: time.Minute * 30,
+	})
+	csrf := sessionsHandler.CSRFMiddleware(sessions.CSRFConfig{
+		Rotation: sessions.CSRF_ROTATE_PER_SESSION,
+		Exempt: []string{
+			"/auth/{provider}/callback",
+		},
+	})
+	rateLimitRules := []ratelimit.Rule{
+		// More specific than the login pattern, so listing providers does not use up the login budget
+		{Pattern: "GET /auth/providers", Key: ratelimit.KEY_IP, Limit: 120, Window: ratelimit.Duration(time.Minute)},
+		{Pattern: "GET /auth/{provider}", Group: "auth", Key: ratelimit.KEY_IP, Limit: 20, Window: ratelimit.Duration(time.Minute)},
+		{Pattern: "GET /auth/{provider}/link", Group: "auth", Key: ratelimit.KEY_IP, Limit: 20, Window: ratelimit.Duration(time.Minute)},
+		{Pattern: "GET /auth/{provider}/callback", Group: "auth", Key: ratelimit.KEY_IP, Limit: 20, Window: ratelimit.Duration(time.Minute)},
+		{Pattern: "GET /public/businesses", Group: "public", Key: ratelimit.KEY_IP, Limit: 120, Window: ratelimit.Duration(time.Minute)},
+		{Pattern: "GET /public/businesses/{businessId}", Group: "public", Key: ratelimit.KEY_IP, Limit: 120, Window: ratelimit.Duration(time.Minute)},
+		{Pattern: "GET /public/posts", Group: "public", Key: ratelimit.KEY_IP, Limit: 120, Window: ratelimit.Duration(time.Minute)},
+		{Pattern: "POST /businesses/{businessId}/posts/{postId}/apply", Key: ratelimit.KEY_USER, Limit: 30, Window: ratelimit.Duration(time.Hour)},
+		{Pattern: "POST /users/0/businesses", Key: ratelimit.KEY_USER, Limit: 5, Window: ratelimit.Duration(time.Hour)},
+		{Pattern: "POST /users/0/student-verification", Key: ratelimit.KEY_USER, Limit: 5, Window: ratelimit.Duration(time.Hour)},
+		{Pattern: "GET /users/0/export", Key: ratelimit.KEY_USER, Limit: 5, Window: ratelimit.Duration(time.Hour)},
+		{Pattern: "POST /businesses/{businessId}/documents", Key: ratelimit.KEY_USER, Limit: 30, Window: ratelimit.Duration(time.Hour)},
+		{Pattern: "POST /businesses/{businessId}/domain-verification", Key: ratelimit.KEY_USER, Limit: 10, Window: ratelimit.Duration(time.Hour)},
+		{Pattern: "POST /businesses/{businessId}/reports", Group: "reports", Key: ratelimit.KEY_USER, Limit: 20, Window: ratelimit.Duration(time.Hour)},
+		{Pattern: "POST /businesses/{businessId}/posts/{postId}/reports", Group: "reports", Key: ratelimit.KEY_USER, Limit: 20, Window: ratelimit.Duration(time.Hour)},
+	}
+	if server.cfg.RATE_LIMITS_FILE != "" {
+		rateLimitRules, err = ratelimit.LoadRules(server.cfg.RATE_LIMITS_FILE)
+		if err != nil {
+			return err
+		}
+	}
+	limiter, err := ratelimit.NewLimiter(slog.Default(), ratelimit.NewCacheStore(rateLimitStore), sessionsHandler, rateLimitRules)
+	if err != nil {
+		return err
+	}
+	apirouter.Handle("/api/", http.StripPrefix("/api", sessionsHandler.SessionMiddleware(limiter.Middleware(csrf(router)))))
 
 	// Authorization
 	authProviders := make(map[string]auth.ProviderConfig)
-	authProviders["google"] = auth.ProviderConfig{
-		Issuer:       "https://accounts.google.com",
-		ClientID:     server.cfg.OAUTH2_GOOGLE_CLIENT_ID,
-		ClientSecret: server.cfg.OAUTH2_GOOGLE_CLIENT_SECRET,
-		Scopes:       []string{"openid", "profile", "email"},
+	if server.cfg.AUTH_PROVIDERS_FILE != "" {
+		authProviders, err = auth.LoadProviderConfigs(server.cfg.AUTH_PROVIDERS_FILE)
+		if err != nil {
+			return err
+		}
+	}
+	if _, ok := authProviders["google"]; !ok && server.cfg.OAUTH2_GOOGLE_CLIENT_ID != "" {
+		authProviders["google"] = auth.ProviderConfig{
+			DisplayName:  "Google",
+			Issuer:       "https://accounts.google.com",
+			ClientID:     server.cfg.OAUTH2_GOOGLE_CLIENT_ID,
+			ClientSecret: server.cfg.OAUTH2_GOOGLE_CLIENT_SECRET,
+			Scopes:       []string{"openid", "profile", "email"},
+		}
 	}
-	authHandler, err := auth.NewAuthHandler(slog.Default(), services.HandleHTTPError, sessionsHandler, server.store, server.cfg.BASE_URI, server.cfg.UI_URI, authProviders)
+	if server.cfg.OIDC_FAKE_ENABLED == "true" {
+		var identities []fakeoidc.Identity
+		if server.cfg.OIDC_FAKE_IDENTITIES_FILE != "" {
+			identities, err = fakeoidc.LoadIdentities(server.cfg.OIDC_FAKE_IDENTITIES_FILE)
+			if err != nil {
+				return err
+			}
+		}
+		fakeProvider, err := fakeoidc.NewProvider(slog.Default(), server.cfg.BASE_URI+"/fake-oidc", identities)
+		if err != nil {
+			return err
+		}
+		// Mounted on the outer mux, the issuer endpoints are called by the provider client and must not
+		// pass through the session, CSRF and rate limit middleware
+		apirouter.Handle(fakeProvider.Path()+"/", http.StripPrefix(fakeProvider.Path(), fakeProvider))
+		authProviders["fake"] = auth.ProviderConfig{
+			DisplayName:  "Test Identities",
+			Issuer:       fakeProvider.Issuer(),
+			ClientID:     "fake-client",
+			ClientSecret: "fake-secret",
+			Scopes:       []string{"openid", "profile", "email"},
+			PKCE:         true,
+			HTTPClient:   fakeProvider.Client(),
+		}
+		slog.Warn("Fake OIDC provider enabled, do not use in production", "issuer", fakeProvider.Issuer())
+	}
+	var redirectAllowlist []string
+	if server.cfg.AUTH_REDIRECT_ALLOWLIST != "" {
+		redirectAllowlist = strings.Split(server.cfg.AUTH_REDIRECT_ALLOWLIST, ",")
+	}
+	authHandler, err := auth.NewAuthHandler(slog.Default(), services.HandleHTTPError, sessionsHandler, server.store, authStateStore, server.cfg.BASE_URI, server.cfg.UI_URI, redirectAllowlist, authProviders)
 	if err != nil {
 		return err
 	}
 	authHandler.RegisterRoutes(router)
 
-	userHandler := user.NewUserHandler(slog.Default(), services.HandleHTTPError, sessionsHandler, server.store)
+	studentDomains := []string{"edu"}
+	if server.cfg.STUDENT_EMAIL_DOMAINS != "" {
+		studentDomains = strings.Split(server.cfg.STUDENT_EMAIL_DOMAINS, ",")
+	}
+	userHandler := user.NewUserHandler(
+		slog.Default(),
+		services.HandleHTTPError,
+		sessionsHandler,
+		server.store,
+		userCache,
+		notificationsService,
+		server.cfg.TEMPLATES_DIR,
+		user.StudentVerificationConfig{
+			Domains:  studentDomains,
+			CodeTTL:  time.Minute * 15,
+			ValidFor: time.Hour * 24 * 365,
+		})
+	sessionsHandler.SetTokenAuthenticator(userHandler)
+	sessionsHandler.SetUserValidator(userHandler)
+	sessionsHandler.SetImpersonationAuditor(userHandler)
+	sessionsHandler.SetImpersonatorValidator(userHandler)
 	userHandler.RegisterRoutes(router)
 
+	institutionHandler := institution.NewInstitutionHandler(slog.Default(), services.HandleHTTPError, sessionsHandler, server.store)
+	institutionHandler.RegisterRoutes(router)
+
 	imageS3, err := filestore.NewS3ImageStore(server.cfg.AWS_PROFILE, server.cfg.IMAGES_S3_BUCKET, server.cfg.AWS_REGION)
 	if err != nil {
 		return err
 	}
 
+	// Verification documents must never be served from the public image bucket
+	if server.cfg.DOCUMENTS_S3_BUCKET == server.cfg.IMAGES_S3_BUCKET {
+		return errors.New("DOCUMENTS_S3_BUCKET must be a separate, private bucket")
+	}
+	documentS3, err := filestore.NewS3Store(server.cfg.AWS_PROFILE, server.cfg.DOCUMENTS_S3_BUCKET, server.cfg.AWS_REGION)
+	if err != nil {
+		return err
+	}
+
+	backgroundServices = append(backgroundServices, user.NewAccountPurgeService(slog.Default(), server.store, sessionsHandler, time.Hour))
+	backgroundServices = append(backgroundServices, user.NewUserStatusExpiryService(slog.Default(), server.store, time.Minute))
+
 	businessHandler := business.NewBusinessHandler(
 		slog.Default(),
 		sessionsHandler,
 		userHandler,
 		server.store,
 		imageS3,
+		documentS3,
 		notificationsService,
 		server.cfg.TEMPLATES_DIR,
 		server.cfg.UI_URI,
 		services.HandleHTTPError)
 	businessHandler.RegisterRoutes(router)
+	backgroundServices = append(backgroundServices, business.NewDomainVerificationService(slog.Default(), server.store, business.NewHTTPDomainFetcher(time.Second*10), net.DefaultResolver, time.Minute))
 
 	for _, service := range backgroundServices {
 		service.Start()
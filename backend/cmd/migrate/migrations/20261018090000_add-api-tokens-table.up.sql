@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS api_tokens (
+  id UUID NOT NULL,
+  user_id UUID NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  token_hash VARCHAR(255) NOT NULL,
+  scopes VARCHAR(64)[] NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  last_used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(id),
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  UNIQUE(token_hash)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS student_verifications;
+DROP TYPE IF EXISTS student_verification_method;
@@ -0,0 +1,14 @@
+CREATE TYPE student_verification_method AS ENUM ('email', 'admin');
+
+CREATE TABLE IF NOT EXISTS student_verifications (
+  user_id UUID NOT NULL,
+  method student_verification_method NOT NULL,
+  school_email VARCHAR(255),
+  verified_by UUID,
+  verified_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL,
+
+  PRIMARY KEY(user_id),
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  FOREIGN KEY(verified_by) REFERENCES users(id)
+);
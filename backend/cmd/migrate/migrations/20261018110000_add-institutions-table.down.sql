@@ -0,0 +1,5 @@
+ALTER TABLE users
+DROP institution_id;
+
+DROP TABLE IF EXISTS institution_domains;
+DROP TABLE IF EXISTS institutions;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS institutions (
+  id UUID NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  country CHAR(2) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(id)
+);
+
+CREATE TABLE IF NOT EXISTS institution_domains (
+  domain VARCHAR(255) NOT NULL,
+  institution_id UUID NOT NULL,
+
+  PRIMARY KEY(domain),
+  FOREIGN KEY(institution_id) REFERENCES institutions(id) ON DELETE CASCADE
+);
+
+ALTER TABLE users
+ADD institution_id UUID REFERENCES institutions(id) ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+DROP COLUMN status_reason,
+DROP COLUMN status_expires_at,
+DROP COLUMN status_updated_by;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+ADD status_reason TEXT,
+ADD status_expires_at TIMESTAMPTZ,
+ADD status_updated_by UUID REFERENCES users(id);
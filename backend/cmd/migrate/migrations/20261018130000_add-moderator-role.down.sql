@@ -0,0 +1,6 @@
+DELETE FROM user_roles WHERE role = 'moderator';
+
+ALTER TYPE user_role RENAME TO user_role_old;
+CREATE TYPE user_role AS ENUM ('admin', 'user');
+ALTER TABLE user_roles ALTER COLUMN role TYPE user_role USING role::TEXT::user_role;
+DROP TYPE user_role_old;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS business_invitations;
+DROP TYPE IF EXISTS business_invitation_status;
+DROP TABLE IF EXISTS business_members;
+DROP TYPE IF EXISTS business_member_role;
@@ -0,0 +1,36 @@
+CREATE TYPE business_member_role AS ENUM ('owner', 'manager', 'reviewer', 'viewer');
+
+CREATE TABLE IF NOT EXISTS business_members (
+  business_id UUID NOT NULL,
+  user_id UUID NOT NULL,
+  role business_member_role NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(business_id, user_id),
+  FOREIGN KEY(business_id) REFERENCES businesses(id),
+  FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+INSERT INTO business_members (business_id, user_id, role)
+SELECT businesses.id, businesses.user_id, 'owner' FROM businesses;
+
+CREATE TYPE business_invitation_status AS ENUM ('pending', 'accepted', 'declined', 'revoked');
+
+CREATE TABLE IF NOT EXISTS business_invitations (
+  id UUID NOT NULL,
+  business_id UUID NOT NULL,
+  email VARCHAR(255) NOT NULL,
+  role business_member_role NOT NULL,
+  status business_invitation_status NOT NULL DEFAULT 'pending',
+  invited_by UUID NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  responded_at TIMESTAMPTZ,
+
+  PRIMARY KEY(id),
+  FOREIGN KEY(business_id) REFERENCES businesses(id),
+  FOREIGN KEY(invited_by) REFERENCES users(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS business_invitations_pending_email
+ON business_invitations (business_id, LOWER(email)) WHERE status = 'pending';
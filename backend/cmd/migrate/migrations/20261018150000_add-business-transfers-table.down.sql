@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS business_transfers;
+DROP TYPE IF EXISTS business_transfer_status;
@@ -0,0 +1,23 @@
+CREATE TYPE business_transfer_status AS ENUM ('pending', 'accepted', 'declined', 'cancelled', 'forced');
+
+CREATE TABLE IF NOT EXISTS business_transfers (
+  id UUID NOT NULL,
+  business_id UUID NOT NULL,
+  from_user_id UUID NOT NULL,
+  to_user_id UUID NOT NULL,
+  status business_transfer_status NOT NULL DEFAULT 'pending',
+  initiated_by UUID NOT NULL,
+  reason TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  responded_at TIMESTAMPTZ,
+
+  PRIMARY KEY(id),
+  FOREIGN KEY(business_id) REFERENCES businesses(id),
+  FOREIGN KEY(from_user_id) REFERENCES users(id),
+  FOREIGN KEY(to_user_id) REFERENCES users(id),
+  FOREIGN KEY(initiated_by) REFERENCES users(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS business_transfers_pending
+ON business_transfers (business_id) WHERE status = 'pending';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_deletion_scheduled_at;
+
+ALTER TABLE users
+DROP COLUMN deletion_scheduled_at,
+DROP COLUMN deleted_at;
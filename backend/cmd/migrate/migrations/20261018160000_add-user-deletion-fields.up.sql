@@ -0,0 +1,6 @@
+ALTER TABLE users
+ADD deletion_scheduled_at TIMESTAMPTZ,
+ADD deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS users_deletion_scheduled_at
+ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS account_email_history_account;
+
+DROP TABLE IF EXISTS account_email_history;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS account_email_history (
+  account_provider VARCHAR(255) NOT NULL,
+  account_id VARCHAR(255) NOT NULL,
+  old_email VARCHAR(255) NOT NULL,
+  new_email VARCHAR(255) NOT NULL,
+  old_email_verified BOOLEAN NOT NULL,
+  new_email_verified BOOLEAN NOT NULL,
+  changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY(account_provider, account_id) REFERENCES accounts(provider, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS account_email_history_account
+ON account_email_history (account_provider, account_id, changed_at);
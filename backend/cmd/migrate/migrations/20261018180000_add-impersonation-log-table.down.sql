@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS impersonation_log_impersonator;
+DROP INDEX IF EXISTS impersonation_log_user;
+
+DROP TABLE IF EXISTS impersonation_log;
+
+DROP TYPE IF EXISTS impersonation_action;
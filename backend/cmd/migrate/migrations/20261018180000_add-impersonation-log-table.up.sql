@@ -0,0 +1,21 @@
+CREATE TYPE impersonation_action AS ENUM ('start', 'request', 'end');
+
+CREATE TABLE IF NOT EXISTS impersonation_log (
+  id SERIAL NOT NULL,
+  impersonator_id UUID NOT NULL,
+  user_id UUID NOT NULL,
+  session_id VARCHAR(255) NOT NULL,
+  action impersonation_action NOT NULL,
+  method VARCHAR(16) NOT NULL,
+  path TEXT NOT NULL,
+  status_code INTEGER,
+  reason TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(id),
+  FOREIGN KEY(impersonator_id) REFERENCES users(id),
+  FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS impersonation_log_user ON impersonation_log (user_id, created_at);
+CREATE INDEX IF NOT EXISTS impersonation_log_impersonator ON impersonation_log (impersonator_id, created_at);
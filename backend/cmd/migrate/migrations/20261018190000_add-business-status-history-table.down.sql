@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS business_status_history_business;
+
+DROP TABLE IF EXISTS business_status_history;
+
+UPDATE businesses SET status = 'disabled' WHERE status = 'rejected';
+
+ALTER TYPE business_status RENAME TO business_status_old;
+CREATE TYPE business_status AS ENUM ('pending', 'active', 'disabled');
+ALTER TABLE businesses ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE businesses ALTER COLUMN status TYPE business_status USING status::TEXT::business_status;
+ALTER TABLE businesses ALTER COLUMN status SET DEFAULT 'pending';
+DROP TYPE business_status_old;
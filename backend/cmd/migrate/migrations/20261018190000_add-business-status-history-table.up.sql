@@ -0,0 +1,17 @@
+ALTER TYPE business_status ADD VALUE IF NOT EXISTS 'rejected';
+
+CREATE TABLE IF NOT EXISTS business_status_history (
+  id SERIAL NOT NULL,
+  business_id UUID NOT NULL,
+  from_status business_status NOT NULL,
+  to_status business_status NOT NULL,
+  reason TEXT,
+  changed_by UUID,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(id),
+  FOREIGN KEY(business_id) REFERENCES businesses(id),
+  FOREIGN KEY(changed_by) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS business_status_history_business ON business_status_history (business_id, created_at);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS business_reviews;
+
+DROP INDEX IF EXISTS business_documents_business;
+
+DROP TABLE IF EXISTS business_documents;
+
+DROP TYPE IF EXISTS business_document_kind;
@@ -0,0 +1,32 @@
+CREATE TYPE business_document_kind AS ENUM ('registration', 'domain_ownership', 'other');
+
+CREATE TABLE IF NOT EXISTS business_documents (
+  id UUID NOT NULL,
+  business_id UUID NOT NULL,
+  kind business_document_kind NOT NULL,
+  filename VARCHAR(255) NOT NULL,
+  content_type VARCHAR(255) NOT NULL,
+  size BIGINT NOT NULL,
+  uploaded_by UUID NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(id),
+  FOREIGN KEY(business_id) REFERENCES businesses(id),
+  FOREIGN KEY(uploaded_by) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS business_documents_business ON business_documents (business_id, created_at);
+
+CREATE TABLE IF NOT EXISTS business_reviews (
+  business_id UUID NOT NULL,
+  reviewer_id UUID NOT NULL,
+  checklist JSONB NOT NULL,
+  notes TEXT NOT NULL DEFAULT '',
+  completed_at TIMESTAMPTZ,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(business_id),
+  FOREIGN KEY(business_id) REFERENCES businesses(id),
+  FOREIGN KEY(reviewer_id) REFERENCES users(id)
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS business_domain_verifications_pending;
+
+DROP TABLE IF EXISTS business_domain_verifications;
+
+DROP TYPE IF EXISTS domain_verification_method;
+
+ALTER TABLE businesses DROP COLUMN IF EXISTS domain_verified;
@@ -0,0 +1,21 @@
+ALTER TABLE businesses ADD COLUMN IF NOT EXISTS domain_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TYPE domain_verification_method AS ENUM ('http', 'dns');
+
+CREATE TABLE IF NOT EXISTS business_domain_verifications (
+  business_id UUID NOT NULL,
+  domain VARCHAR(255) NOT NULL,
+  token VARCHAR(255) NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  last_checked_at TIMESTAMPTZ,
+  last_error TEXT,
+  method domain_verification_method,
+  verified_at TIMESTAMPTZ,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(business_id),
+  FOREIGN KEY(business_id) REFERENCES businesses(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS business_domain_verifications_pending ON business_domain_verifications (last_checked_at) WHERE verified_at IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE businesses DROP COLUMN IF EXISTS logo_variants;
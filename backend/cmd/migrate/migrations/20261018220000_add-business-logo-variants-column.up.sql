@@ -0,0 +1 @@
+ALTER TABLE businesses ADD COLUMN IF NOT EXISTS logo_variants JSONB;
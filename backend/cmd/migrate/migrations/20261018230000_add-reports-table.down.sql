@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS reports_open_reporter;
+
+DROP INDEX IF EXISTS reports_status;
+
+DROP TABLE IF EXISTS reports;
+
+DROP TYPE IF EXISTS report_status;
+
+DROP TYPE IF EXISTS report_reason;
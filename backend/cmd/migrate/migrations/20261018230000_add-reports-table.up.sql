@@ -0,0 +1,29 @@
+CREATE TYPE report_reason AS ENUM ('spam', 'inappropriate', 'misleading', 'other');
+
+CREATE TYPE report_status AS ENUM ('open', 'resolved', 'dismissed');
+
+CREATE TABLE IF NOT EXISTS reports (
+  id UUID NOT NULL,
+  reporter_id UUID NOT NULL,
+  business_id UUID NOT NULL,
+  post_id INT,
+  reason report_reason NOT NULL,
+  details TEXT NOT NULL DEFAULT '',
+  status report_status NOT NULL DEFAULT 'open',
+  reviewer_id UUID,
+  review_notes TEXT NOT NULL DEFAULT '',
+  reviewed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(id),
+  FOREIGN KEY(reporter_id) REFERENCES users(id),
+  FOREIGN KEY(business_id) REFERENCES businesses(id),
+  FOREIGN KEY(business_id, post_id) REFERENCES posts(business_id, id),
+  FOREIGN KEY(reviewer_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS reports_status ON reports (status, created_at);
+
+-- A user has at most one open report per business or post
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_reporter
+ON reports (reporter_id, business_id, COALESCE(post_id, 0)) WHERE status = 'open';
@@ -91,3 +91,21 @@ func (pq *PgxQueries) SetApplicationStatus(ctx context.Context, businessId *uuid
 
 	return nil
 }
+
+func (pq *PgxQueries) WithdrawPendingApplicationsForUser(ctx context.Context, userId *uuid.UUID) error {
+	_, err := pq.tx.Exec(ctx, `
+    UPDATE post_applications SET
+    status = @withdrawn
+    WHERE post_applications.user_id = @userId AND post_applications.status = @pending
+    `, pgx.NamedArgs{
+		"userId":    userId,
+		"pending":   models.APPLICATION_STATUS_PENDING,
+		"withdrawn": models.APPLICATION_STATUS_WITHDRAWN,
+	})
+
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
@@ -19,7 +19,7 @@ func (pq *PgxQueries) CreateBusiness(ctx context.Context, ownerId *uuid.UUID, da
 	rows, err := pq.tx.Query(ctx, `
     INSERT INTO businesses
     (user_id, id, name, website, description) VALUES (@userId, @businessId, @name, @website, @description)
-    RETURNING businesses.*
+    RETURNING businesses.*, '[]'::json AS members
     `, pgx.NamedArgs{
 		"userId":      ownerId,
 		"businessId":  businessId,
@@ -38,19 +38,54 @@ func (pq *PgxQueries) CreateBusiness(ctx context.Context, ownerId *uuid.UUID, da
 		return nil, handlePgxError(err)
 	}
 
+	member, err := pq.AddBusinessMember(ctx, &businessId, ownerId, models.BUSINESS_MEMBER_ROLE_OWNER)
+	if err != nil {
+		return nil, err
+	}
+	business.Members = []models.BusinessMember{*member}
+
 	return business, nil
 }
 
+// Counts the businesses the user owns, regardless of their status
+func (pq *PgxQueries) CountBusinessesOwnedByUser(ctx context.Context, userId *uuid.UUID) (int, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT COUNT(*) FROM businesses
+    WHERE businesses.user_id = @userId
+    `, pgx.NamedArgs{
+		"userId": userId,
+	})
+	if err != nil {
+		return 0, handlePgxError(err)
+	}
+
+	count, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[int])
+	if err != nil {
+		return 0, handlePgxError(err)
+	}
+
+	return count, nil
+}
+
 func (pq *PgxQueries) GetBusinessOwner(ctx context.Context, businessId *uuid.UUID) (*models.User, error) {
 	rows, err := pq.tx.Query(ctx, `
     SELECT 
-      users.*, accounts.email, accounts.email_verified, accounts.name,
+      users.*, COALESCE(accounts.email, '') AS email, COALESCE(accounts.email_verified, FALSE) AS email_verified,
+      COALESCE(accounts.name, 'Deleted user') AS name,
+      (SELECT jsonb_build_object('id', institutions.id, 'name', institutions.name, 'country', institutions.country)
+       FROM institutions
+       WHERE institutions.id = users.institution_id
+      ) as institution,
+      (SELECT to_jsonb(student_verifications.*)
+       FROM student_verifications
+       WHERE student_verifications.user_id = users.id
+      ) as student_verification,
       (SELECT array_remove(array_agg(user_roles.role), NULL) 
-       FROM user_roles WHERE user_roles.user_id = @userId) AS roles,
-      (SELECT COALESCE(json_agg(accounts.*) FILTER (WHERE accounts.id IS NOT NULL), '[]')
+       FROM user_roles WHERE user_roles.user_id = users.id) AS roles,
+      (SELECT COALESCE(json_agg(to_jsonb(accounts.*) || jsonb_build_object('is_primary', COALESCE(user_accounts.is_primary, FALSE))) FILTER (WHERE accounts.id IS NOT NULL), '[]')
        FROM user_accounts
        LEFT JOIN accounts ON user_accounts.account_provider = accounts.provider AND user_accounts.account_id = accounts.id 
-        WHERE user_accounts.user_id = @userId
+        WHERE user_accounts.user_id = users.id
       ) as accounts
     FROM users
     LEFT JOIN user_accounts ON users.id = user_accounts.user_id AND user_accounts.is_primary = TRUE
@@ -77,9 +112,17 @@ func (pq *PgxQueries) GetBusinesses(ctx context.Context, params *models.Business
 	}
 
 	rows, err := pq.tx.Query(ctx, `
-    SELECT * FROM businesses
+    SELECT businesses.*,
+      (SELECT COALESCE(json_agg(business_members.*), '[]')
+       FROM business_members
+       WHERE business_members.business_id = businesses.id
+      ) AS members
+    FROM businesses
     WHERE (@status::business_status IS NULL OR @status::business_status = businesses.status)
-    AND (@userId::UUID IS NULL OR @userId::UUID = businesses.user_id)
+    AND (@userId::UUID IS NULL OR EXISTS (
+      SELECT 1 FROM business_members
+      WHERE business_members.business_id = businesses.id AND business_members.user_id = @userId::UUID
+    ))
     `,
 		pgx.NamedArgs{
 			"status": params.Status,
@@ -99,8 +142,40 @@ func (pq *PgxQueries) GetBusinesses(ctx context.Context, params *models.Business
 
 func (pq *PgxQueries) GetBusinessForId(ctx context.Context, id *uuid.UUID) (*models.Business, error) {
 	rows, err := pq.tx.Query(ctx, `
-    SELECT * FROM businesses
+    SELECT businesses.*,
+      (SELECT COALESCE(json_agg(business_members.*), '[]')
+       FROM business_members
+       WHERE business_members.business_id = businesses.id
+      ) AS members
+    FROM businesses
+    WHERE businesses.id = @businessId
+    `,
+		pgx.NamedArgs{
+			"businessId": id,
+		})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	business, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[models.Business])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return business, nil
+}
+
+// Locks the business row until the transaction ends, for changes that depend on its current owner
+func (pq *PgxQueries) GetBusinessForIdForUpdate(ctx context.Context, id *uuid.UUID) (*models.Business, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT businesses.*,
+      (SELECT COALESCE(json_agg(business_members.*), '[]')
+       FROM business_members
+       WHERE business_members.business_id = businesses.id
+      ) AS members
+    FROM businesses
     WHERE businesses.id = @businessId
+    FOR UPDATE OF businesses
     `,
 		pgx.NamedArgs{
 			"businessId": id,
@@ -141,14 +216,27 @@ func (pq *PgxQueries) UpdateBusiness(ctx context.Context, businessId *uuid.UUID,
 	return nil
 }
 
-func (pq *PgxQueries) SetBusinessStatus(ctx context.Context, businessId *uuid.UUID, status models.BusinessStatus) error {
+// Sets the business status, recording the change in the business's status history
+func (pq *PgxQueries) SetBusinessStatus(ctx context.Context, businessId *uuid.UUID, status models.BusinessStatus, reason *string, changedBy *uuid.UUID) error {
 	res, err := pq.tx.Exec(ctx, `
-    UPDATE businesses SET
-    status = @status
-    WHERE businesses.id = @businessId
+    WITH prev AS (
+      SELECT businesses.id, businesses.status FROM businesses
+      WHERE businesses.id = @businessId
+    ), updated AS (
+      UPDATE businesses SET
+      status = @status
+      WHERE businesses.id = @businessId
+      RETURNING businesses.id, businesses.status
+    )
+    INSERT INTO business_status_history
+    (business_id, from_status, to_status, reason, changed_by)
+    SELECT updated.id, prev.status, updated.status, @reason, @changedBy
+    FROM updated JOIN prev ON prev.id = updated.id
     `, pgx.NamedArgs{
 		"businessId": businessId,
 		"status":     status,
+		"reason":     reason,
+		"changedBy":  changedBy,
 	})
 
 	if err != nil {
@@ -162,14 +250,75 @@ func (pq *PgxQueries) SetBusinessStatus(ctx context.Context, businessId *uuid.UU
 	return nil
 }
 
-func (pq *PgxQueries) SetBusinessLogo(ctx context.Context, businessId *uuid.UUID, url string) error {
+func (pq *PgxQueries) GetBusinessStatusHistory(ctx context.Context, businessId *uuid.UUID) ([]models.BusinessStatusChange, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT business_status_history.*
+    FROM business_status_history
+    WHERE business_status_history.business_id = @businessId
+    ORDER BY business_status_history.created_at DESC, business_status_history.id DESC
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	history, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.BusinessStatusChange])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return history, nil
+}
+
+// Disables every business owned by the user along with their active posts
+func (pq *PgxQueries) DisableBusinessesForUser(ctx context.Context, userId *uuid.UUID, reason *string, changedBy *uuid.UUID) error {
+	_, err := pq.tx.Exec(ctx, `
+    WITH disabled AS (
+      SELECT businesses.id, businesses.status FROM businesses
+      WHERE businesses.user_id = @userId AND businesses.status <> @disabled
+    ), updated AS (
+      UPDATE businesses SET
+      status = @disabled
+      FROM disabled
+      WHERE businesses.id = disabled.id
+      RETURNING businesses.id
+    ), posts_disabled AS (
+      UPDATE posts SET
+      status = @postDisabled
+      FROM updated
+      WHERE posts.business_id = updated.id AND posts.status = @postActive
+    )
+    INSERT INTO business_status_history
+    (business_id, from_status, to_status, reason, changed_by)
+    SELECT disabled.id, disabled.status, @disabled, @reason, @changedBy
+    FROM disabled
+    `, pgx.NamedArgs{
+		"userId":       userId,
+		"disabled":     models.BUSINESS_STATUS_DISABLED,
+		"postActive":   models.POST_STATUS_ACTIVE,
+		"postDisabled": models.POST_STATUS_DISABLED,
+		"reason":       reason,
+		"changedBy":    changedBy,
+	})
+
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
+
+// Stores the logo's variants, the full variant doubles as the logo url
+func (pq *PgxQueries) SetBusinessLogo(ctx context.Context, businessId *uuid.UUID, variants *models.BusinessLogoVariants) error {
 	res, err := pq.tx.Exec(ctx, `
     UPDATE businesses SET
-    logo_url = @url
+    (logo_url, logo_variants) = (@url, @variants)
     WHERE businesses.id = @businessId
     `, pgx.NamedArgs{
 		"businessId": businessId,
-		"url":        url,
+		"url":        variants.Full,
+		"variants":   variants,
 	})
 
 	if err != nil {
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/john-vh/college_testing/backend/models"
+)
+
+// Schedules the user for deletion, or cancels a scheduled deletion when at is nil
+func (pq *PgxQueries) SetUserDeletionSchedule(ctx context.Context, userId *uuid.UUID, at *time.Time) error {
+	res, err := pq.tx.Exec(ctx, `
+    UPDATE users SET
+    deletion_scheduled_at = @at
+    WHERE users.id = @userId AND users.deleted_at IS NULL
+    `, pgx.NamedArgs{
+		"userId": userId,
+		"at":     at,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) GetUsersDueForDeletion(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT users.id FROM users
+    WHERE users.deletion_scheduled_at <= NOW() AND users.deleted_at IS NULL
+    `)
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	userIds, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return userIds, nil
+}
+
+func (pq *PgxQueries) RevokeAPITokensForUser(ctx context.Context, userId *uuid.UUID) error {
+	_, err := pq.tx.Exec(ctx, `
+    UPDATE api_tokens SET
+    revoked_at = NOW()
+    WHERE api_tokens.user_id = @userId AND api_tokens.revoked_at IS NULL
+    `, pgx.NamedArgs{
+		"userId": userId,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
+
+// Removes the user's personal data while keeping the user row, so applications that
+// reference it remain intact for the business. Owned businesses must be transferred first.
+func (pq *PgxQueries) AnonymizeUser(ctx context.Context, userId *uuid.UUID) error {
+	args := pgx.NamedArgs{
+		"userId":    userId,
+		"pending":   models.BUSINESS_TRANSFER_STATUS_PENDING,
+		"cancelled": models.BUSINESS_TRANSFER_STATUS_CANCELLED,
+		"userRole":  models.USER_ROLE_USER,
+	}
+
+	statements := []string{
+		`UPDATE business_transfers SET (status, responded_at) = (@cancelled, NOW())
+     WHERE business_transfers.status = @pending
+     AND (business_transfers.from_user_id = @userId OR business_transfers.to_user_id = @userId)`,
+		`DELETE FROM business_members WHERE business_members.user_id = @userId`,
+		`DELETE FROM api_tokens WHERE api_tokens.user_id = @userId`,
+		`DELETE FROM student_verifications WHERE student_verifications.user_id = @userId`,
+		`UPDATE reports SET details = '' WHERE reports.reporter_id = @userId`,
+		`DELETE FROM user_roles WHERE user_roles.user_id = @userId AND user_roles.role <> @userRole`,
+		`WITH removed AS (
+       DELETE FROM user_accounts WHERE user_accounts.user_id = @userId
+       RETURNING user_accounts.account_provider, user_accounts.account_id
+     )
+     DELETE FROM accounts USING removed
+     WHERE accounts.provider = removed.account_provider AND accounts.id = removed.account_id`,
+		`UPDATE users SET
+     (status, status_reason, status_expires_at, institution_id, deletion_scheduled_at, deleted_at) =
+     ('disabled', 'Account deleted', NULL, NULL, NULL, NOW()),
+     (notify_application_updated, notify_application_received, notify_application_withdrawn) = (FALSE, FALSE, FALSE)
+     WHERE users.id = @userId`,
+	}
+	for _, statement := range statements {
+		if _, err := pq.tx.Exec(ctx, statement, args); err != nil {
+			return handlePgxError(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/john-vh/college_testing/backend/models"
+)
+
+// Issues a new token for the business's domain, replacing any previous verification
+func (pq *PgxQueries) CreateDomainVerification(ctx context.Context, businessId *uuid.UUID, domain string, token string, expiresAt time.Time) (*models.DomainVerification, error) {
+	rows, err := pq.tx.Query(ctx, `
+    INSERT INTO business_domain_verifications
+    (business_id, domain, token, expires_at)
+    VALUES (@businessId, @domain, @token, @expiresAt)
+    ON CONFLICT (business_id) DO UPDATE
+    SET (domain, token, attempts, last_checked_at, last_error, method, verified_at, expires_at, created_at) = (
+      excluded.domain,
+      excluded.token,
+      0,
+      NULL,
+      NULL,
+      NULL,
+      NULL,
+      excluded.expires_at,
+      NOW()
+    )
+    RETURNING business_domain_verifications.*
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"domain":     domain,
+		"token":      token,
+		"expiresAt":  expiresAt,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	verification, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.DomainVerification])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return verification, nil
+}
+
+func (pq *PgxQueries) GetDomainVerification(ctx context.Context, businessId *uuid.UUID) (*models.DomainVerification, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT * FROM business_domain_verifications
+    WHERE business_domain_verifications.business_id = @businessId
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	verification, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.DomainVerification])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return verification, nil
+}
+
+// Unverified, unexpired verifications that have not been checked since checkedBefore, least recently checked first
+func (pq *PgxQueries) GetDomainVerificationsDue(ctx context.Context, checkedBefore time.Time, limit int) ([]models.DomainVerification, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT * FROM business_domain_verifications
+    WHERE business_domain_verifications.verified_at IS NULL
+    AND business_domain_verifications.expires_at > NOW()
+    AND (business_domain_verifications.last_checked_at IS NULL OR business_domain_verifications.last_checked_at < @checkedBefore)
+    ORDER BY business_domain_verifications.last_checked_at NULLS FIRST
+    LIMIT @limit
+    `, pgx.NamedArgs{
+		"checkedBefore": checkedBefore,
+		"limit":         limit,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	verifications, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.DomainVerification])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return verifications, nil
+}
+
+// Records a failed check. The token is matched so a check of a replaced token is not recorded.
+func (pq *PgxQueries) RecordDomainVerificationCheck(ctx context.Context, businessId *uuid.UUID, token string, checkErr string) error {
+	_, err := pq.tx.Exec(ctx, `
+    UPDATE business_domain_verifications SET
+    (attempts, last_checked_at, last_error) = (business_domain_verifications.attempts + 1, NOW(), @checkErr)
+    WHERE business_domain_verifications.business_id = @businessId AND business_domain_verifications.token = @token
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"token":      token,
+		"checkErr":   checkErr,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
+
+// Marks the verification and its business as verified. Returns ErrNoRows if the token was replaced
+// or the verification removed in the meantime.
+func (pq *PgxQueries) CompleteDomainVerification(ctx context.Context, businessId *uuid.UUID, token string, method models.DomainVerificationMethod) error {
+	res, err := pq.tx.Exec(ctx, `
+    WITH verified AS (
+      UPDATE business_domain_verifications SET
+      (attempts, last_checked_at, last_error, method, verified_at) = (business_domain_verifications.attempts + 1, NOW(), NULL, @method, NOW())
+      WHERE business_domain_verifications.business_id = @businessId
+      AND business_domain_verifications.token = @token
+      AND business_domain_verifications.verified_at IS NULL
+      RETURNING business_domain_verifications.business_id
+    )
+    UPDATE businesses SET domain_verified = TRUE
+    FROM verified
+    WHERE businesses.id = verified.business_id
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"token":      token,
+		"method":     method,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// Removes the verification and the business's badge, e.g. once its website moved to another domain
+func (pq *PgxQueries) ClearDomainVerification(ctx context.Context, businessId *uuid.UUID) error {
+	_, err := pq.tx.Exec(ctx, `
+    WITH cleared AS (
+      DELETE FROM business_domain_verifications
+      WHERE business_domain_verifications.business_id = @businessId
+    )
+    UPDATE businesses SET domain_verified = FALSE
+    WHERE businesses.id = @businessId
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
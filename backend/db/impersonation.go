@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/john-vh/college_testing/backend/models"
+)
+
+func (pq *PgxQueries) CreateImpersonationLogEntry(ctx context.Context, entry *models.ImpersonationLogEntry) error {
+	_, err := pq.tx.Exec(ctx, `
+    INSERT INTO impersonation_log
+    (impersonator_id, user_id, session_id, action, method, path, status_code, reason)
+    VALUES (@impersonatorId, @userId, @sessionId, @action, @method, @path, @statusCode, @reason)
+    `, pgx.NamedArgs{
+		"impersonatorId": entry.ImpersonatorId,
+		"userId":         entry.UserId,
+		"sessionId":      entry.SessionId,
+		"action":         entry.Action,
+		"method":         entry.Method,
+		"path":           entry.Path,
+		"statusCode":     entry.StatusCode,
+		"reason":         entry.Reason,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) GetImpersonationLog(ctx context.Context, params *models.ImpersonationLogQueryParams) ([]models.ImpersonationLogEntry, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT impersonation_log.*
+    FROM impersonation_log
+    WHERE (@userId::UUID IS NULL OR impersonation_log.user_id = @userId)
+    AND (@impersonatorId::UUID IS NULL OR impersonation_log.impersonator_id = @impersonatorId)
+    ORDER BY impersonation_log.created_at DESC, impersonation_log.id DESC
+    LIMIT 500
+    `, pgx.NamedArgs{
+		"userId":         params.UserId,
+		"impersonatorId": params.ImpersonatorId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.ImpersonationLogEntry])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,247 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+)
+
+const institutionColumns = `
+      institutions.id, institutions.name, institutions.country, institutions.created_at, institutions.updated_at,
+      (SELECT COALESCE(json_agg(institution_domains.domain ORDER BY institution_domains.domain), '[]')
+       FROM institution_domains
+       WHERE institution_domains.institution_id = institutions.id
+      ) AS domains`
+
+func (pq *PgxQueries) CreateInstitution(ctx context.Context, data *models.InstitutionCreate) (*uuid.UUID, error) {
+	institutionId, err := uuid.NewRandom()
+	if err != nil {
+		return nil, services.NewInternalServiceError(err)
+	}
+
+	_, err = pq.tx.Exec(ctx, `
+    INSERT INTO institutions
+    (id, name, country) VALUES (@institutionId, @name, @country)
+    `, pgx.NamedArgs{
+		"institutionId": institutionId,
+		"name":          data.Name,
+		"country":       data.Country,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	if err := pq.setInstitutionDomains(ctx, &institutionId, data.Domains); err != nil {
+		return nil, err
+	}
+
+	return &institutionId, nil
+}
+
+func (pq *PgxQueries) UpdateInstitution(ctx context.Context, institutionId *uuid.UUID, data *models.InstitutionUpdate) error {
+	res, err := pq.tx.Exec(ctx, `
+    UPDATE institutions SET
+    (name, country, updated_at) = (@name, @country, NOW())
+    WHERE institutions.id = @institutionId
+    `, pgx.NamedArgs{
+		"institutionId": institutionId,
+		"name":          data.Name,
+		"country":       data.Country,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return pq.setInstitutionDomains(ctx, institutionId, data.Domains)
+}
+
+func (pq *PgxQueries) setInstitutionDomains(ctx context.Context, institutionId *uuid.UUID, domains []string) error {
+	_, err := pq.tx.Exec(ctx, `
+    DELETE FROM institution_domains
+    WHERE institution_domains.institution_id = @institutionId
+    `, pgx.NamedArgs{
+		"institutionId": institutionId,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	_, err = pq.tx.Exec(ctx, `
+    INSERT INTO institution_domains (domain, institution_id)
+    SELECT DISTINCT LOWER(domain), @institutionId::UUID FROM UNNEST(@domains::TEXT[]) AS domain
+    `, pgx.NamedArgs{
+		"institutionId": institutionId,
+		"domains":       domains,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) DeleteInstitution(ctx context.Context, institutionId *uuid.UUID) error {
+	res, err := pq.tx.Exec(ctx, `
+    DELETE FROM institutions
+    WHERE institutions.id = @institutionId
+    `, pgx.NamedArgs{
+		"institutionId": institutionId,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) GetInstitutions(ctx context.Context) ([]models.Institution, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT`+institutionColumns+`
+    FROM institutions
+    ORDER BY institutions.name
+    `)
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	institutions, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Institution])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return institutions, nil
+}
+
+func (pq *PgxQueries) GetInstitutionForId(ctx context.Context, institutionId *uuid.UUID) (*models.Institution, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT`+institutionColumns+`
+    FROM institutions
+    WHERE institutions.id = @institutionId
+    `, pgx.NamedArgs{
+		"institutionId": institutionId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	institution, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Institution])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return institution, nil
+}
+
+// Returns the institution with the most specific domain matching the email, subdomains included
+func (pq *PgxQueries) GetInstitutionForEmail(ctx context.Context, email string) (*models.Institution, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT`+institutionColumns+`
+    FROM institutions
+    JOIN institution_domains ON institution_domains.institution_id = institutions.id
+    WHERE SPLIT_PART(LOWER(@email), '@', 2) = institution_domains.domain
+    OR SPLIT_PART(LOWER(@email), '@', 2) LIKE '%.' || institution_domains.domain
+    ORDER BY LENGTH(institution_domains.domain) DESC
+    LIMIT 1
+    `, pgx.NamedArgs{
+		"email": email,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	institution, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Institution])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return institution, nil
+}
+
+// Associates users with the institution matching their verified emails, preferring a verified school
+// email over linked account emails. A nil userId syncs every user.
+// Most specific institution matching a current school email or verified account email of the user
+const userInstitutionQuery = `
+      SELECT institution_domains.institution_id
+      FROM (
+        SELECT LOWER(student_verifications.school_email) AS email, 0 AS priority
+        FROM student_verifications
+        WHERE student_verifications.user_id = users.id
+        AND student_verifications.school_email IS NOT NULL
+        AND student_verifications.expires_at > NOW()
+        UNION ALL
+        SELECT LOWER(accounts.email) AS email, 1 AS priority
+        FROM user_accounts
+        JOIN accounts ON user_accounts.account_provider = accounts.provider AND user_accounts.account_id = accounts.id
+        WHERE user_accounts.user_id = users.id AND accounts.email_verified = TRUE
+      ) AS emails
+      JOIN institution_domains ON SPLIT_PART(emails.email, '@', 2) = institution_domains.domain
+      OR SPLIT_PART(emails.email, '@', 2) LIKE '%.' || institution_domains.domain
+      ORDER BY emails.priority, LENGTH(institution_domains.domain) DESC
+      LIMIT 1
+`
+
+func (pq *PgxQueries) SyncUserInstitutions(ctx context.Context, userId *uuid.UUID) error {
+	_, err := pq.tx.Exec(ctx, `
+    UPDATE users SET
+    institution_id = (`+userInstitutionQuery+`)
+    WHERE users.id = @userId
+    `, pgx.NamedArgs{
+		"userId": userId,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
+
+// Reassigns only the users with a school email or verified account email under one of the domains,
+// subdomains included
+func (pq *PgxQueries) SyncDomainInstitutions(ctx context.Context, domains []string) error {
+	if len(domains) == 0 {
+		return nil
+	}
+
+	_, err := pq.tx.Exec(ctx, `
+    WITH affected_users AS (
+      SELECT student_verifications.user_id
+      FROM student_verifications
+      WHERE student_verifications.school_email IS NOT NULL
+      AND EXISTS (
+        SELECT 1 FROM UNNEST(@domains::TEXT[]) AS domain
+        WHERE SPLIT_PART(LOWER(student_verifications.school_email), '@', 2) = LOWER(domain)
+        OR SPLIT_PART(LOWER(student_verifications.school_email), '@', 2) LIKE '%.' || LOWER(domain)
+      )
+      UNION
+      SELECT user_accounts.user_id
+      FROM user_accounts
+      JOIN accounts ON user_accounts.account_provider = accounts.provider AND user_accounts.account_id = accounts.id
+      WHERE EXISTS (
+        SELECT 1 FROM UNNEST(@domains::TEXT[]) AS domain
+        WHERE SPLIT_PART(LOWER(accounts.email), '@', 2) = LOWER(domain)
+        OR SPLIT_PART(LOWER(accounts.email), '@', 2) LIKE '%.' || LOWER(domain)
+      )
+    )
+    UPDATE users SET
+    institution_id = (`+userInstitutionQuery+`)
+    WHERE users.id IN (SELECT affected_users.user_id FROM affected_users)
+    `, pgx.NamedArgs{
+		"domains": domains,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
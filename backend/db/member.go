@@ -0,0 +1,238 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+)
+
+func (pq *PgxQueries) GetBusinessMembers(ctx context.Context, businessId *uuid.UUID) ([]models.BusinessMemberInfo, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT business_members.*, COALESCE(accounts.name, 'Deleted user') AS name, COALESCE(accounts.email, '') AS email
+    FROM business_members
+    LEFT JOIN user_accounts ON business_members.user_id = user_accounts.user_id AND user_accounts.is_primary = TRUE
+    LEFT JOIN accounts ON user_accounts.account_provider = accounts.provider AND user_accounts.account_id = accounts.id
+    WHERE business_members.business_id = @businessId
+    ORDER BY business_members.created_at
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	members, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.BusinessMemberInfo])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return members, nil
+}
+
+// Adds the user to the business, or changes their role if they are already a member
+func (pq *PgxQueries) AddBusinessMember(ctx context.Context, businessId *uuid.UUID, userId *uuid.UUID, role models.BusinessMemberRole) (*models.BusinessMember, error) {
+	rows, err := pq.tx.Query(ctx, `
+    INSERT INTO business_members
+    (business_id, user_id, role) VALUES (@businessId, @userId, @role)
+    ON CONFLICT (business_id, user_id) DO UPDATE SET role = EXCLUDED.role
+    RETURNING user_id, role, created_at
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"userId":     userId,
+		"role":       role,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	member, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.BusinessMember])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return member, nil
+}
+
+func (pq *PgxQueries) RemoveBusinessMember(ctx context.Context, businessId *uuid.UUID, userId *uuid.UUID) error {
+	res, err := pq.tx.Exec(ctx, `
+    DELETE FROM business_members
+    WHERE business_members.business_id = @businessId AND business_members.user_id = @userId
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"userId":     userId,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) CreateBusinessInvitation(ctx context.Context, businessId *uuid.UUID, invitedBy *uuid.UUID, data *models.BusinessInvitationCreate, expiresAt time.Time) (*models.BusinessInvitation, error) {
+	invitationId, err := uuid.NewRandom()
+	if err != nil {
+		return nil, services.NewInternalServiceError(err)
+	}
+
+	// Expired invitations no longer block a new one to the same address
+	_, err = pq.tx.Exec(ctx, `
+    UPDATE business_invitations SET
+    status = @revoked
+    WHERE business_invitations.business_id = @businessId AND LOWER(business_invitations.email) = LOWER(@email)
+    AND business_invitations.status = @pending AND business_invitations.expires_at <= NOW()
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"email":      data.Email,
+		"pending":    models.BUSINESS_INVITATION_STATUS_PENDING,
+		"revoked":    models.BUSINESS_INVITATION_STATUS_REVOKED,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	rows, err := pq.tx.Query(ctx, `
+    INSERT INTO business_invitations
+    (id, business_id, email, role, invited_by, expires_at) VALUES (@invitationId, @businessId, @email, @role, @invitedBy, @expiresAt)
+    RETURNING business_invitations.*
+    `, pgx.NamedArgs{
+		"invitationId": invitationId,
+		"businessId":   businessId,
+		"email":        data.Email,
+		"role":         data.Role,
+		"invitedBy":    invitedBy,
+		"expiresAt":    expiresAt,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	invitation, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.BusinessInvitation])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return invitation, nil
+}
+
+func (pq *PgxQueries) GetBusinessInvitations(ctx context.Context, businessId *uuid.UUID) ([]models.BusinessInvitation, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT * FROM business_invitations
+    WHERE business_invitations.business_id = @businessId
+    ORDER BY business_invitations.created_at DESC
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	invitations, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.BusinessInvitation])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return invitations, nil
+}
+
+func (pq *PgxQueries) GetBusinessInvitation(ctx context.Context, invitationId *uuid.UUID) (*models.BusinessInvitation, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT * FROM business_invitations
+    WHERE business_invitations.id = @invitationId
+    FOR UPDATE
+    `, pgx.NamedArgs{
+		"invitationId": invitationId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	invitation, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.BusinessInvitation])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return invitation, nil
+}
+
+// Pending invitations addressed to any of the user's verified account emails
+func (pq *PgxQueries) GetInvitationsForUser(ctx context.Context, userId *uuid.UUID) ([]models.UserBusinessInvitation, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT business_invitations.*,
+      jsonb_build_object(
+        'id', businesses.id, 'status', businesses.status, 'created_at', businesses.created_at,
+        'logo_url', businesses.logo_url, 'logo_variants', businesses.logo_variants, 'name', businesses.name
+      ) AS business
+    FROM business_invitations
+    LEFT JOIN businesses ON businesses.id = business_invitations.business_id
+    WHERE business_invitations.status = @pending AND business_invitations.expires_at > NOW()
+    AND LOWER(business_invitations.email) IN (
+      SELECT LOWER(accounts.email)
+      FROM user_accounts
+      LEFT JOIN accounts ON user_accounts.account_provider = accounts.provider AND user_accounts.account_id = accounts.id
+      WHERE user_accounts.user_id = @userId AND accounts.email_verified = TRUE
+    )
+    ORDER BY business_invitations.created_at DESC
+    `, pgx.NamedArgs{
+		"userId":  userId,
+		"pending": models.BUSINESS_INVITATION_STATUS_PENDING,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	invitations, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.UserBusinessInvitation])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return invitations, nil
+}
+
+// Whether the email belongs to one of the user's verified accounts
+func (pq *PgxQueries) HasVerifiedEmail(ctx context.Context, userId *uuid.UUID, email string) (bool, error) {
+	var exists bool
+	err := pq.tx.QueryRow(ctx, `
+    SELECT EXISTS (
+      SELECT 1
+      FROM user_accounts
+      LEFT JOIN accounts ON user_accounts.account_provider = accounts.provider AND user_accounts.account_id = accounts.id
+      WHERE user_accounts.user_id = @userId AND accounts.email_verified = TRUE AND LOWER(accounts.email) = LOWER(@email)
+    )
+    `, pgx.NamedArgs{
+		"userId": userId,
+		"email":  email,
+	}).Scan(&exists)
+	if err != nil {
+		return false, handlePgxError(err)
+	}
+
+	return exists, nil
+}
+
+func (pq *PgxQueries) SetBusinessInvitationStatus(ctx context.Context, invitationId *uuid.UUID, status models.BusinessInvitationStatus) error {
+	res, err := pq.tx.Exec(ctx, `
+    UPDATE business_invitations SET
+    (status, responded_at) = (@status, NOW())
+    WHERE business_invitations.id = @invitationId
+    `, pgx.NamedArgs{
+		"invitationId": invitationId,
+		"status":       status,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
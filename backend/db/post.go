@@ -14,10 +14,12 @@ func (pq *PgxQueries) GetPosts(ctx context.Context, params *models.PostQueryPara
     SELECT posts.*
     FROM posts
     LEFT JOIN businesses ON businesses.id = posts.business_id
-    LEFT JOIN users ON businesses.user_id = users.id
     WHERE (@status::post_status IS NULL OR @status::post_status = posts.status)
     AND (@businessId::UUID IS NULL OR @businessId::UUID = posts.business_id)
-    AND (@userId::UUID IS NULL OR @userId::UUID = users.id)
+    AND (@userId::UUID IS NULL OR EXISTS (
+      SELECT 1 FROM business_members
+      WHERE business_members.business_id = posts.business_id AND business_members.user_id = @userId::UUID
+    ))
     AND businesses.status = @businessActive
     `, pgx.NamedArgs{
 		"status":         params.Status,
@@ -37,6 +39,30 @@ func (pq *PgxQueries) GetPosts(ctx context.Context, params *models.PostQueryPara
 	return posts, nil
 }
 
+// Posts of every business the user is a member of, regardless of status
+func (pq *PgxQueries) GetPostsForMember(ctx context.Context, userId *uuid.UUID) ([]models.Post, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT posts.*
+    FROM posts
+    WHERE EXISTS (
+      SELECT 1 FROM business_members
+      WHERE business_members.business_id = posts.business_id AND business_members.user_id = @userId
+    )
+    `, pgx.NamedArgs{
+		"userId": userId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	posts, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Post])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return posts, nil
+}
+
 func (pq *PgxQueries) GetPostForId(ctx context.Context, businessId *uuid.UUID, postId int) (*models.Post, error) {
 	rows, err := pq.tx.Query(ctx, `
     SELECT posts.*
@@ -132,25 +158,58 @@ func (pq *PgxQueries) SetPostStatus(ctx context.Context, businessId *uuid.UUID,
 	return nil
 }
 
-func (pq *PgxQueries) GetApplicationsForPost(ctx context.Context, businessId *uuid.UUID, postId int) (*models.PostApplications, error) {
+// Disables the active posts of the business, e.g. once the business is suspended
+func (pq *PgxQueries) DisableActivePostsForBusiness(ctx context.Context, businessId *uuid.UUID) error {
+	_, err := pq.tx.Exec(ctx, `
+    UPDATE posts SET
+    status = @disabled
+    WHERE posts.business_id = @businessId AND posts.status = @active
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"active":     models.POST_STATUS_ACTIVE,
+		"disabled":   models.POST_STATUS_DISABLED,
+	})
+
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) GetApplicationsForPost(ctx context.Context, businessId *uuid.UUID, postId int, params *models.PostApplicationQueryParams) (*models.PostApplications, error) {
+	if params == nil {
+		params = &models.PostApplicationQueryParams{}
+	}
+
 	rows, err := pq.tx.Query(ctx, `
     SELECT post_applications.notes, post_applications.status, post_applications.created_at,
       json_build_object(
       'id', users.id,
       'created_at', users.created_at,
       'email', accounts.email,
-      'name', accounts.name,
+      'name', CASE WHEN users.deleted_at IS NULL THEN accounts.name ELSE 'Deleted user' END,
       'email_verified', accounts.email_verified,
-      'status', users.status
+      'status', users.status,
+      'deleted_at', users.deleted_at,
+      'institution_id', users.institution_id,
+      'institution', CASE WHEN institutions.id IS NULL THEN NULL ELSE json_build_object(
+        'id', institutions.id,
+        'name', institutions.name,
+        'country', institutions.country
+      ) END
     ) AS user
     FROM post_applications
     LEFT JOIN users on post_applications.user_id = users.id 
-    LEFT JOIN user_accounts ON users.id = user_accounts.user_id
+    LEFT JOIN user_accounts ON users.id = user_accounts.user_id AND user_accounts.is_primary = TRUE
     LEFT JOIN accounts ON user_accounts.account_provider = accounts.provider AND user_accounts.account_id = accounts.id
-    WHERE post_applications.business_id = @businessId AND post_applications.post_id = @postId AND user_accounts.is_primary = TRUE
+    LEFT JOIN institutions ON users.institution_id = institutions.id
+    WHERE post_applications.business_id = @businessId AND post_applications.post_id = @postId
+    AND (@institutionId::UUID IS NULL OR users.institution_id = @institutionId)
     `, pgx.NamedArgs{
-		"businessId": businessId,
-		"postId":     postId,
+		"businessId":    businessId,
+		"postId":        postId,
+		"institutionId": params.InstitutionId,
 	})
 
 	if err != nil {
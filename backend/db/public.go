@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/john-vh/college_testing/backend/models"
+)
+
+func (pq *PgxQueries) GetPublicBusinesses(ctx context.Context) ([]models.PublicBusiness, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT businesses.id, businesses.name, businesses.logo_url, businesses.logo_variants, businesses.domain_verified,
+      businesses.description, businesses.website, businesses.created_at
+    FROM businesses
+    WHERE businesses.status = @businessActive
+    ORDER BY businesses.name
+    `, pgx.NamedArgs{
+		"businessActive": models.BUSINESS_STATUS_ACTIVE,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	businesses, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.PublicBusiness])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return businesses, nil
+}
+
+// Returns ErrNoRows unless the business is active
+func (pq *PgxQueries) GetPublicBusinessForId(ctx context.Context, businessId *uuid.UUID) (*models.PublicBusiness, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT businesses.id, businesses.name, businesses.logo_url, businesses.logo_variants, businesses.domain_verified,
+      businesses.description, businesses.website, businesses.created_at
+    FROM businesses
+    WHERE businesses.id = @businessId AND businesses.status = @businessActive
+    `, pgx.NamedArgs{
+		"businessId":     businessId,
+		"businessActive": models.BUSINESS_STATUS_ACTIVE,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	business, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.PublicBusiness])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return business, nil
+}
+
+// Active posts of active businesses, newest first
+func (pq *PgxQueries) GetPublicPosts(ctx context.Context, businessId *uuid.UUID) ([]models.PublicPost, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT posts.id, posts.business_id, posts.title, posts.description, posts.pay, posts.time_est,
+      posts.created_at, posts.updated_at,
+      jsonb_build_object(
+        'id', businesses.id,
+        'name', businesses.name,
+        'logo_url', businesses.logo_url,
+        'logo_variants', businesses.logo_variants,
+        'domain_verified', businesses.domain_verified
+      ) AS business
+    FROM posts
+    JOIN businesses ON businesses.id = posts.business_id
+    WHERE posts.status = @postActive AND businesses.status = @businessActive
+    AND (@businessId::UUID IS NULL OR @businessId::UUID = posts.business_id)
+    ORDER BY posts.created_at DESC
+    `, pgx.NamedArgs{
+		"businessId":     businessId,
+		"postActive":     models.POST_STATUS_ACTIVE,
+		"businessActive": models.BUSINESS_STATUS_ACTIVE,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	posts, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.PublicPost])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return posts, nil
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+)
+
+// Files a report against a business, or one of its posts when postId is set
+func (pq *PgxQueries) CreateReport(ctx context.Context, reporterId *uuid.UUID, businessId *uuid.UUID, postId *int, data *models.ReportCreate) (*models.Report, error) {
+	reportId, err := uuid.NewRandom()
+	if err != nil {
+		return nil, services.NewInternalServiceError(err)
+	}
+
+	rows, err := pq.tx.Query(ctx, `
+    INSERT INTO reports
+    (id, reporter_id, business_id, post_id, reason, details)
+    VALUES (@reportId, @reporterId, @businessId, @postId, @reason, @details)
+    RETURNING reports.*
+    `, pgx.NamedArgs{
+		"reportId":   reportId,
+		"reporterId": reporterId,
+		"businessId": businessId,
+		"postId":     postId,
+		"reason":     data.Reason,
+		"details":    data.Details,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	report, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Report])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return report, nil
+}
+
+func (pq *PgxQueries) GetReports(ctx context.Context, params *models.ReportQueryParams) ([]models.Report, error) {
+	if params == nil {
+		params = &models.ReportQueryParams{}
+	}
+
+	rows, err := pq.tx.Query(ctx, `
+    SELECT * FROM reports
+    WHERE (@status::report_status IS NULL OR reports.status = @status::report_status)
+    AND (@businessId::UUID IS NULL OR reports.business_id = @businessId::UUID)
+    AND (@reporterId::UUID IS NULL OR reports.reporter_id = @reporterId::UUID)
+    ORDER BY reports.created_at
+    `, pgx.NamedArgs{
+		"status":     params.Status,
+		"businessId": params.BusinessId,
+		"reporterId": params.ReporterId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	reports, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Report])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return reports, nil
+}
+
+// Closes an open report, returns ErrNoRows when it does not exist or was already reviewed
+func (pq *PgxQueries) ReviewReport(ctx context.Context, reportId *uuid.UUID, reviewerId *uuid.UUID, data *models.ReportReview) (*models.Report, error) {
+	rows, err := pq.tx.Query(ctx, `
+    UPDATE reports SET
+    status = @status,
+    reviewer_id = @reviewerId,
+    review_notes = @notes,
+    reviewed_at = NOW()
+    WHERE reports.id = @reportId AND reports.status = @open
+    RETURNING reports.*
+    `, pgx.NamedArgs{
+		"reportId":   reportId,
+		"reviewerId": reviewerId,
+		"status":     data.Status,
+		"notes":      data.Notes,
+		"open":       models.REPORT_STATUS_OPEN,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	report, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Report])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return report, nil
+}
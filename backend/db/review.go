@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+)
+
+func (pq *PgxQueries) CreateBusinessDocument(ctx context.Context, businessId *uuid.UUID, uploadedBy *uuid.UUID, kind models.BusinessDocumentKind, filename, contentType string, size int64) (*models.BusinessDocument, error) {
+	documentId, err := uuid.NewRandom()
+	if err != nil {
+		return nil, services.NewInternalServiceError(err)
+	}
+
+	rows, err := pq.tx.Query(ctx, `
+    INSERT INTO business_documents
+    (id, business_id, kind, filename, content_type, size, uploaded_by)
+    VALUES (@documentId, @businessId, @kind, @filename, @contentType, @size, @uploadedBy)
+    RETURNING business_documents.*
+    `, pgx.NamedArgs{
+		"documentId":  documentId,
+		"businessId":  businessId,
+		"kind":        kind,
+		"filename":    filename,
+		"contentType": contentType,
+		"size":        size,
+		"uploadedBy":  uploadedBy,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	document, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.BusinessDocument])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return document, nil
+}
+
+func (pq *PgxQueries) GetBusinessDocuments(ctx context.Context, businessId *uuid.UUID) ([]models.BusinessDocument, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT * FROM business_documents
+    WHERE business_documents.business_id = @businessId
+    ORDER BY business_documents.created_at
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	documents, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.BusinessDocument])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return documents, nil
+}
+
+func (pq *PgxQueries) DeleteBusinessDocument(ctx context.Context, businessId *uuid.UUID, documentId *uuid.UUID) (*models.BusinessDocument, error) {
+	rows, err := pq.tx.Query(ctx, `
+    DELETE FROM business_documents
+    WHERE business_documents.business_id = @businessId AND business_documents.id = @documentId
+    RETURNING business_documents.*
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"documentId": documentId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	document, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.BusinessDocument])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return document, nil
+}
+
+// Returns nil when the business has not been reviewed yet
+func (pq *PgxQueries) GetBusinessReview(ctx context.Context, businessId *uuid.UUID) (*models.BusinessReview, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT * FROM business_reviews
+    WHERE business_reviews.business_id = @businessId
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	reviews, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.BusinessReview])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+	if len(reviews) == 0 {
+		return nil, nil
+	}
+
+	return reviews[0], nil
+}
+
+// Creates or replaces the business's review, it is completed once every checklist item is ticked
+func (pq *PgxQueries) SaveBusinessReview(ctx context.Context, businessId *uuid.UUID, reviewerId *uuid.UUID, data *models.BusinessReviewUpdate) (*models.BusinessReview, error) {
+	rows, err := pq.tx.Query(ctx, `
+    INSERT INTO business_reviews
+    (business_id, reviewer_id, checklist, notes, completed_at)
+    VALUES (@businessId, @reviewerId, @checklist, @notes, CASE WHEN @complete::BOOLEAN THEN NOW() END)
+    ON CONFLICT (business_id) DO UPDATE
+    SET (reviewer_id, checklist, notes, completed_at, updated_at) = (
+      excluded.reviewer_id,
+      excluded.checklist,
+      excluded.notes,
+      excluded.completed_at,
+      NOW()
+    )
+    RETURNING business_reviews.*
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"reviewerId": reviewerId,
+		"checklist":  data.Checklist,
+		"notes":      data.Notes,
+		"complete":   data.Checklist.IsComplete(),
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	review, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.BusinessReview])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return review, nil
+}
+
+// Reopens a completed review, e.g. once the evidence it was based on has changed
+func (pq *PgxQueries) ResetBusinessReview(ctx context.Context, businessId *uuid.UUID) error {
+	_, err := pq.tx.Exec(ctx, `
+    UPDATE business_reviews SET
+    checklist = '{}'::JSONB, completed_at = NULL, updated_at = NOW()
+    WHERE business_reviews.business_id = @businessId AND business_reviews.completed_at IS NOT NULL
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/john-vh/college_testing/backend/models"
+)
+
+func (pq *PgxQueries) SetStudentVerification(ctx context.Context, userId *uuid.UUID, data *models.StudentVerification) error {
+	_, err := pq.tx.Exec(ctx, `
+    INSERT INTO student_verifications
+    (user_id, method, school_email, verified_by, verified_at, expires_at) VALUES (@userId, @method, @schoolEmail, @verifiedBy, @verifiedAt, @expiresAt)
+    ON CONFLICT (user_id) DO UPDATE
+    SET (method, school_email, verified_by, verified_at, expires_at) = (excluded.method, excluded.school_email, excluded.verified_by, excluded.verified_at, excluded.expires_at)
+    `, pgx.NamedArgs{
+		"userId":      userId,
+		"method":      data.Method,
+		"schoolEmail": data.SchoolEmail,
+		"verifiedBy":  data.VerifiedBy,
+		"verifiedAt":  data.VerifiedAt,
+		"expiresAt":   data.ExpiresAt,
+	})
+
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) DeleteStudentVerification(ctx context.Context, userId *uuid.UUID) error {
+	res, err := pq.tx.Exec(ctx, `
+    DELETE FROM student_verifications
+    WHERE student_verifications.user_id = @userId
+    `, pgx.NamedArgs{
+		"userId": userId,
+	})
+
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// Reports whether a school email currently verifies a user other than userId
+func (pq *PgxQueries) IsSchoolEmailClaimed(ctx context.Context, userId *uuid.UUID, email string) (bool, error) {
+	var claimed bool
+	err := pq.tx.QueryRow(ctx, `
+    SELECT EXISTS (
+      SELECT 1 FROM student_verifications
+      WHERE LOWER(student_verifications.school_email) = LOWER(@email)
+      AND student_verifications.user_id <> @userId
+      AND student_verifications.expires_at > NOW()
+    )
+    `, pgx.NamedArgs{
+		"userId": userId,
+		"email":  email,
+	}).Scan(&claimed)
+
+	if err != nil {
+		return false, handlePgxError(err)
+	}
+
+	return claimed, nil
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/util"
+)
+
+func (pq *PgxQueries) CreateAPIToken(ctx context.Context, userId *uuid.UUID, data *models.APITokenCreate, tokenHash string, expiresAt time.Time) (*models.APIToken, error) {
+	tokenId, err := uuid.NewRandom()
+	if err != nil {
+		return nil, services.NewInternalServiceError(err)
+	}
+
+	rows, err := pq.tx.Query(ctx, `
+    INSERT INTO api_tokens
+    (id, user_id, name, token_hash, scopes, expires_at) VALUES (@tokenId, @userId, @name, @tokenHash, @scopes, @expiresAt)
+    RETURNING id, user_id, name, to_json(scopes) AS scopes, expires_at, last_used_at, revoked_at, created_at
+    `, pgx.NamedArgs{
+		"tokenId":   tokenId,
+		"userId":    userId,
+		"name":      data.Name,
+		"tokenHash": tokenHash,
+		"scopes":    util.Map(data.Scopes, func(s models.TokenScope) string { return string(s) }),
+		"expiresAt": expiresAt,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	token, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.APIToken])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return token, nil
+}
+
+func (pq *PgxQueries) GetAPITokens(ctx context.Context, userId *uuid.UUID) ([]models.APIToken, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT id, user_id, name, to_json(scopes) AS scopes, expires_at, last_used_at, revoked_at, created_at
+    FROM api_tokens
+    WHERE api_tokens.user_id = @userId
+    ORDER BY api_tokens.created_at DESC
+    `, pgx.NamedArgs{
+		"userId": userId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	tokens, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.APIToken])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return tokens, nil
+}
+
+func (pq *PgxQueries) GetAPITokenForHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT id, user_id, name, to_json(scopes) AS scopes, expires_at, last_used_at, revoked_at, created_at
+    FROM api_tokens
+    WHERE api_tokens.token_hash = @tokenHash
+    `, pgx.NamedArgs{
+		"tokenHash": tokenHash,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	token, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.APIToken])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return token, nil
+}
+
+// Only touches tokens not already marked used after usedBefore, so busy tokens do not write on every request
+func (pq *PgxQueries) SetAPITokenUsed(ctx context.Context, tokenId *uuid.UUID, usedBefore time.Time) error {
+	_, err := pq.tx.Exec(ctx, `
+    UPDATE api_tokens SET
+    last_used_at = NOW()
+    WHERE api_tokens.id = @tokenId
+    AND (api_tokens.last_used_at IS NULL OR api_tokens.last_used_at < @usedBefore)
+    `, pgx.NamedArgs{
+		"tokenId":    tokenId,
+		"usedBefore": usedBefore,
+	})
+
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) RevokeAPIToken(ctx context.Context, userId *uuid.UUID, tokenId *uuid.UUID) error {
+	res, err := pq.tx.Exec(ctx, `
+    UPDATE api_tokens SET
+    revoked_at = NOW()
+    WHERE api_tokens.id = @tokenId AND api_tokens.user_id = @userId AND api_tokens.revoked_at IS NULL
+    `, pgx.NamedArgs{
+		"tokenId": tokenId,
+		"userId":  userId,
+	})
+
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+)
+
+// Records a transfer, forced transfers are recorded as completed immediately
+func (pq *PgxQueries) CreateBusinessTransfer(ctx context.Context, businessId, fromUserId, toUserId, initiatedBy *uuid.UUID, status models.BusinessTransferStatus, reason *string, expiresAt time.Time) (*models.BusinessTransfer, error) {
+	transferId, err := uuid.NewRandom()
+	if err != nil {
+		return nil, services.NewInternalServiceError(err)
+	}
+
+	// Expired transfers no longer block a new one for the business
+	_, err = pq.tx.Exec(ctx, `
+    UPDATE business_transfers SET
+    status = @cancelled
+    WHERE business_transfers.business_id = @businessId
+    AND business_transfers.status = @pending AND business_transfers.expires_at <= NOW()
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"pending":    models.BUSINESS_TRANSFER_STATUS_PENDING,
+		"cancelled":  models.BUSINESS_TRANSFER_STATUS_CANCELLED,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	rows, err := pq.tx.Query(ctx, `
+    INSERT INTO business_transfers
+    (id, business_id, from_user_id, to_user_id, status, initiated_by, reason, expires_at, responded_at)
+    VALUES (@transferId, @businessId, @fromUserId, @toUserId, @status, @initiatedBy, @reason, @expiresAt,
+      CASE WHEN @status::business_transfer_status = 'pending' THEN NULL ELSE NOW() END)
+    RETURNING business_transfers.*
+    `, pgx.NamedArgs{
+		"transferId":  transferId,
+		"businessId":  businessId,
+		"fromUserId":  fromUserId,
+		"toUserId":    toUserId,
+		"status":      status,
+		"initiatedBy": initiatedBy,
+		"reason":      reason,
+		"expiresAt":   expiresAt,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	transfer, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.BusinessTransfer])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return transfer, nil
+}
+
+func (pq *PgxQueries) GetBusinessTransfer(ctx context.Context, transferId *uuid.UUID) (*models.BusinessTransfer, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT * FROM business_transfers
+    WHERE business_transfers.id = @transferId
+    FOR UPDATE
+    `, pgx.NamedArgs{
+		"transferId": transferId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	transfer, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.BusinessTransfer])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return transfer, nil
+}
+
+func (pq *PgxQueries) GetBusinessTransfers(ctx context.Context, businessId *uuid.UUID) ([]models.BusinessTransfer, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT * FROM business_transfers
+    WHERE business_transfers.business_id = @businessId
+    ORDER BY business_transfers.created_at DESC
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	transfers, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.BusinessTransfer])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return transfers, nil
+}
+
+func (pq *PgxQueries) GetPendingTransfersForUser(ctx context.Context, userId *uuid.UUID) ([]models.UserBusinessTransfer, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT business_transfers.*,
+      jsonb_build_object(
+        'id', businesses.id, 'status', businesses.status, 'created_at', businesses.created_at,
+        'logo_url', businesses.logo_url, 'logo_variants', businesses.logo_variants, 'name', businesses.name
+      ) AS business
+    FROM business_transfers
+    LEFT JOIN businesses ON businesses.id = business_transfers.business_id
+    WHERE business_transfers.to_user_id = @userId
+    AND business_transfers.status = @pending AND business_transfers.expires_at > NOW()
+    ORDER BY business_transfers.created_at DESC
+    `, pgx.NamedArgs{
+		"userId":  userId,
+		"pending": models.BUSINESS_TRANSFER_STATUS_PENDING,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	transfers, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.UserBusinessTransfer])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return transfers, nil
+}
+
+func (pq *PgxQueries) SetBusinessTransferStatus(ctx context.Context, transferId *uuid.UUID, status models.BusinessTransferStatus) error {
+	res, err := pq.tx.Exec(ctx, `
+    UPDATE business_transfers SET
+    (status, responded_at) = (@status, NOW())
+    WHERE business_transfers.id = @transferId
+    `, pgx.NamedArgs{
+		"transferId": transferId,
+		"status":     status,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) CancelPendingBusinessTransfers(ctx context.Context, businessId *uuid.UUID) error {
+	_, err := pq.tx.Exec(ctx, `
+    UPDATE business_transfers SET
+    (status, responded_at) = (@cancelled, NOW())
+    WHERE business_transfers.business_id = @businessId AND business_transfers.status = @pending
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"pending":    models.BUSINESS_TRANSFER_STATUS_PENDING,
+		"cancelled":  models.BUSINESS_TRANSFER_STATUS_CANCELLED,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
+
+// Moves ownership to the new user, the previous owner stays on as a manager
+func (pq *PgxQueries) SetBusinessOwner(ctx context.Context, businessId *uuid.UUID, userId *uuid.UUID) error {
+	res, err := pq.tx.Exec(ctx, `
+    UPDATE businesses SET
+    user_id = @userId
+    WHERE businesses.id = @businessId
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"userId":     userId,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	_, err = pq.tx.Exec(ctx, `
+    UPDATE business_members SET
+    role = @manager
+    WHERE business_members.business_id = @businessId AND business_members.role = @owner
+    `, pgx.NamedArgs{
+		"businessId": businessId,
+		"owner":      models.BUSINESS_MEMBER_ROLE_OWNER,
+		"manager":    models.BUSINESS_MEMBER_ROLE_MANAGER,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	_, err = pq.AddBusinessMember(ctx, businessId, userId, models.BUSINESS_MEMBER_ROLE_OWNER)
+	return err
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -62,12 +63,31 @@ func (pq *PgxQueries) UpdateUser(ctx context.Context, id *uuid.UUID, data *model
 	return nil
 }
 
+// Creates or refreshes the account from the latest claims, recording any change of email or its
+// verification. Empty claims keep the stored value since not every provider sends them on each login.
 func (pq *PgxQueries) SaveOpenIDAcct(ctx context.Context, openIDProvider string, data *models.OpenIDClaims) error {
-	res, err := pq.tx.Exec(ctx, `
-    INSERT INTO accounts 
-    (provider, id, name, email, email_verified) VALUES (@provider, @id, @name, @email, @emailVerified)
-    ON CONFLICT (provider, id) DO UPDATE
-    SET (name, email, email_verified, updated_at) = (excluded.name, excluded.email, excluded.email_verified, NOW()) 
+	_, err := pq.tx.Exec(ctx, `
+    WITH prev AS (
+      SELECT accounts.email, accounts.email_verified
+      FROM accounts
+      WHERE accounts.provider = @provider AND accounts.id = @id
+    ), saved AS (
+      INSERT INTO accounts
+      (provider, id, name, email, email_verified) VALUES (@provider, @id, @name, @email, @emailVerified)
+      ON CONFLICT (provider, id) DO UPDATE
+      SET (name, email, email_verified, updated_at) = (
+        COALESCE(NULLIF(excluded.name, ''), accounts.name),
+        COALESCE(NULLIF(excluded.email, ''), accounts.email),
+        excluded.email_verified,
+        NOW()
+      )
+      RETURNING accounts.provider, accounts.id, accounts.email, accounts.email_verified
+    )
+    INSERT INTO account_email_history
+    (account_provider, account_id, old_email, new_email, old_email_verified, new_email_verified)
+    SELECT saved.provider, saved.id, prev.email, saved.email, prev.email_verified, saved.email_verified
+    FROM saved, prev
+    WHERE LOWER(prev.email) <> LOWER(saved.email) OR prev.email_verified <> saved.email_verified
     `, pgx.NamedArgs{
 		"provider":      openIDProvider,
 		"id":            data.Id,
@@ -80,11 +100,30 @@ func (pq *PgxQueries) SaveOpenIDAcct(ctx context.Context, openIDProvider string,
 		return handlePgxError(err)
 	}
 
-	if res.RowsAffected() == 0 {
-		return handlePgxError(ErrNoRows)
+	return nil
+}
+
+func (pq *PgxQueries) GetAccountEmailHistory(ctx context.Context, userId *uuid.UUID) ([]models.AccountEmailChange, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT account_email_history.*
+    FROM account_email_history
+    JOIN user_accounts ON account_email_history.account_provider = user_accounts.account_provider
+      AND account_email_history.account_id = user_accounts.account_id
+    WHERE user_accounts.user_id = @userId
+    ORDER BY account_email_history.changed_at DESC
+    `, pgx.NamedArgs{
+		"userId": userId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
 	}
 
-	return nil
+	history, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.AccountEmailChange])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return history, nil
 }
 
 func (pq *PgxQueries) LinkOpenIDAcct(ctx context.Context, openIDProvider string, acctData *models.OpenIDClaims, userId *uuid.UUID, isPrimary bool) error {
@@ -138,12 +177,21 @@ func (pq *PgxQueries) GetLinkedUserId(ctx context.Context, provider string, acco
 func (pq *PgxQueries) GetUserForId(ctx context.Context, id *uuid.UUID) (*models.User, error) {
 	rows, err := pq.tx.Query(ctx, `
     SELECT 
-      users.*, accounts.email, accounts.email_verified, accounts.name,
-      (SELECT COALESCE(json_agg(accounts.*) FILTER (WHERE accounts.id IS NOT NULL), '[]')
+      users.*, COALESCE(accounts.email, '') AS email, COALESCE(accounts.email_verified, FALSE) AS email_verified,
+      COALESCE(accounts.name, 'Deleted user') AS name,
+      (SELECT COALESCE(json_agg(to_jsonb(accounts.*) || jsonb_build_object('is_primary', COALESCE(user_accounts.is_primary, FALSE))) FILTER (WHERE accounts.id IS NOT NULL), '[]')
        FROM user_accounts
        LEFT JOIN accounts ON user_accounts.account_provider = accounts.provider AND user_accounts.account_id = accounts.id 
         WHERE user_accounts.user_id = @userId
       ) as accounts,
+      (SELECT jsonb_build_object('id', institutions.id, 'name', institutions.name, 'country', institutions.country)
+       FROM institutions
+       WHERE institutions.id = users.institution_id
+      ) as institution,
+      (SELECT to_jsonb(student_verifications.*)
+       FROM student_verifications
+       WHERE student_verifications.user_id = users.id
+      ) as student_verification,
       (SELECT COALESCE(json_agg(user_roles.role) FILTER (WHERE user_roles.user_id IS NOT NULL), '[]')
        FROM user_roles 
        WHERE user_roles.user_id = @userId
@@ -171,26 +219,39 @@ func (pq *PgxQueries) GetUserForId(ctx context.Context, id *uuid.UUID) (*models.
 func (pq *PgxQueries) QueryUsers(ctx context.Context, params *models.UserQueryParams) ([]models.User, error) {
 	rows, err := pq.tx.Query(ctx, `
     SELECT 
-      users.*, accounts.email, accounts.email_verified, accounts.name,
-      (SELECT COALESCE(json_agg(accounts.*) FILTER (WHERE accounts.id IS NOT NULL), '[]')
+      users.*, COALESCE(accounts.email, '') AS email, COALESCE(accounts.email_verified, FALSE) AS email_verified,
+      COALESCE(accounts.name, 'Deleted user') AS name,
+      (SELECT COALESCE(json_agg(to_jsonb(accounts.*) || jsonb_build_object('is_primary', COALESCE(user_accounts.is_primary, FALSE))) FILTER (WHERE accounts.id IS NOT NULL), '[]')
        FROM user_accounts
        LEFT JOIN accounts ON user_accounts.account_provider = accounts.provider AND user_accounts.account_id = accounts.id 
-        WHERE user_accounts.user_id = @userId
+        WHERE user_accounts.user_id = users.id
       ) as accounts,
+      (SELECT jsonb_build_object('id', institutions.id, 'name', institutions.name, 'country', institutions.country)
+       FROM institutions
+       WHERE institutions.id = users.institution_id
+      ) as institution,
+      (SELECT to_jsonb(student_verifications.*)
+       FROM student_verifications
+       WHERE student_verifications.user_id = users.id
+      ) as student_verification,
       (SELECT COALESCE(json_agg(user_roles.role) FILTER (WHERE user_roles.user_id IS NOT NULL), '[]')
        FROM user_roles 
-       WHERE user_roles.user_id = @userId
+       WHERE user_roles.user_id = users.id
       ) as roles 
     FROM users
     LEFT JOIN user_accounts ON users.id = user_accounts.user_id AND user_accounts.is_primary = TRUE
     LEFT JOIN accounts ON user_accounts.account_provider = accounts.provider AND user_accounts.account_id = accounts.id
-    LEFT JOIN user_roles ON users.id = user_roles.user_id
-    WHERE (@role::user_role IS NULL OR @role = user_roles.role)
+    WHERE (@role::user_role IS NULL OR EXISTS (
+      SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = @role
+    ))
     AND (@status::user_status IS NULL OR @status = users.status)
+    AND (@emailVerified::BOOLEAN IS NULL OR accounts.email_verified = @emailVerified)
+    ORDER BY users.created_at
     `,
 		pgx.NamedArgs{
-			"status": params.Status,
-			"role":   params.Role,
+			"status":        params.Status,
+			"role":          params.Role,
+			"emailVerified": params.EmailVerified,
 		})
 	if err != nil {
 		return nil, handlePgxError(err)
@@ -203,3 +264,196 @@ func (pq *PgxQueries) QueryUsers(ctx context.Context, params *models.UserQueryPa
 	}
 	return users, nil
 }
+
+func (pq *PgxQueries) GetUserAccounts(ctx context.Context, userId *uuid.UUID) ([]models.UserAccount, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT accounts.id, accounts.provider, accounts.name, accounts.email, accounts.email_verified, accounts.updated_at,
+      COALESCE(user_accounts.is_primary, FALSE) AS is_primary
+    FROM user_accounts
+    LEFT JOIN accounts ON user_accounts.account_provider = accounts.provider AND user_accounts.account_id = accounts.id
+    WHERE user_accounts.user_id = @userId
+    ORDER BY user_accounts.created_at
+    `, pgx.NamedArgs{
+		"userId": userId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	accounts, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.UserAccount])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return accounts, nil
+}
+
+func (pq *PgxQueries) SetPrimaryAccount(ctx context.Context, userId *uuid.UUID, provider string, accountId string) error {
+	res, err := pq.tx.Exec(ctx, `
+    UPDATE user_accounts SET
+    is_primary = (user_accounts.account_provider = @provider AND user_accounts.account_id = @accountId)
+    WHERE user_accounts.user_id = @userId
+    AND EXISTS (
+      SELECT 1 FROM user_accounts
+      WHERE user_accounts.user_id = @userId AND user_accounts.account_provider = @provider AND user_accounts.account_id = @accountId
+    )
+    `, pgx.NamedArgs{
+		"userId":    userId,
+		"provider":  provider,
+		"accountId": accountId,
+	})
+
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) UnlinkAccount(ctx context.Context, userId *uuid.UUID, provider string, accountId string) error {
+	res, err := pq.tx.Exec(ctx, `
+    DELETE FROM user_accounts
+    WHERE user_accounts.user_id = @userId AND user_accounts.account_provider = @provider AND user_accounts.account_id = @accountId
+    `, pgx.NamedArgs{
+		"userId":    userId,
+		"provider":  provider,
+		"accountId": accountId,
+	})
+
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// Returns the user's effective status, lifting bans and suspensions whose expiry has passed
+// Read only, so it can run on every request. Expired statuses read as active until ClearExpiredUserStatuses resets them.
+func (pq *PgxQueries) GetUserStatus(ctx context.Context, userId *uuid.UUID) (*models.UserStatusInfo, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT
+    CASE WHEN users.status_expires_at <= NOW() THEN 'active' ELSE users.status END AS status,
+    CASE WHEN users.status_expires_at <= NOW() THEN NULL ELSE users.status_reason END AS status_reason,
+    CASE WHEN users.status_expires_at <= NOW() THEN NULL ELSE users.status_expires_at END AS status_expires_at
+    FROM users
+    WHERE users.id = @userId
+    `, pgx.NamedArgs{
+		"userId": userId,
+	})
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	status, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.UserStatusInfo])
+	if err != nil {
+		return nil, handlePgxError(err)
+	}
+
+	return status, nil
+}
+
+// Reinstates users whose temporary status has run out, returning how many were reset
+func (pq *PgxQueries) ClearExpiredUserStatuses(ctx context.Context) (int64, error) {
+	res, err := pq.tx.Exec(ctx, `
+    UPDATE users SET
+    (status, status_reason, status_expires_at) = ('active', NULL, NULL)
+    WHERE users.status <> 'active' AND users.status_expires_at <= NOW()
+    `)
+	if err != nil {
+		return 0, handlePgxError(err)
+	}
+
+	return res.RowsAffected(), nil
+}
+
+func (pq *PgxQueries) SetUserStatus(ctx context.Context, userId *uuid.UUID, status models.UserStatus, reason *string, expiresAt *time.Time, updatedBy *uuid.UUID) error {
+	res, err := pq.tx.Exec(ctx, `
+    UPDATE users SET
+    (status, status_reason, status_expires_at, status_updated_by) = (@status, @reason, @expiresAt, @updatedBy)
+    WHERE users.id = @userId
+    `, pgx.NamedArgs{
+		"userId":    userId,
+		"status":    status,
+		"reason":    reason,
+		"expiresAt": expiresAt,
+		"updatedBy": updatedBy,
+	})
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) GrantUserRole(ctx context.Context, userId *uuid.UUID, role models.UserRole) error {
+	_, err := pq.tx.Exec(ctx, `
+    INSERT INTO user_roles (user_id, role) VALUES (@userId, @role)
+    ON CONFLICT DO NOTHING
+    `, pgx.NamedArgs{
+		"userId": userId,
+		"role":   role,
+	})
+
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	return nil
+}
+
+func (pq *PgxQueries) RevokeUserRole(ctx context.Context, userId *uuid.UUID, role models.UserRole) error {
+	res, err := pq.tx.Exec(ctx, `
+    DELETE FROM user_roles
+    WHERE user_roles.user_id = @userId AND user_roles.role = @role
+    `, pgx.NamedArgs{
+		"userId": userId,
+		"role":   role,
+	})
+
+	if err != nil {
+		return handlePgxError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// Counts only users that can currently sign in, locking their role rows
+func (pq *PgxQueries) CountUsersWithRoleForUpdate(ctx context.Context, role models.UserRole) (int, error) {
+	rows, err := pq.tx.Query(ctx, `
+    SELECT user_roles.user_id
+    FROM user_roles
+    JOIN users ON users.id = user_roles.user_id
+    WHERE user_roles.role = @role
+    AND (users.status = 'active' OR users.status_expires_at <= NOW())
+    AND users.deletion_scheduled_at IS NULL AND users.deleted_at IS NULL
+    FOR UPDATE OF user_roles
+    `, pgx.NamedArgs{
+		"role": role,
+	})
+	if err != nil {
+		return 0, handlePgxError(err)
+	}
+
+	userIds, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return 0, handlePgxError(err)
+	}
+
+	return len(userIds), nil
+}
@@ -13,8 +13,10 @@ import (
 type Config struct {
 	BASE_URI                    string
 	UI_URI                      string
-	OAUTH2_GOOGLE_CLIENT_ID     string
-	OAUTH2_GOOGLE_CLIENT_SECRET string
+	OAUTH2_GOOGLE_CLIENT_ID     string `env:"optional"`
+	OAUTH2_GOOGLE_CLIENT_SECRET string `env:"optional"`
+	AUTH_PROVIDERS_FILE         string `env:"optional"`
+	AUTH_REDIRECT_ALLOWLIST     string `env:"optional"`
 	POSTGRES_USER               string
 	POSTGRES_PASSWORD           string
 	POSTGRES_HOST               string
@@ -24,11 +26,17 @@ type Config struct {
 	AWS_PROFILE                 string
 	AWS_REGION                  string
 	IMAGES_S3_BUCKET            string
+	DOCUMENTS_S3_BUCKET         string
 	MAIL_USER                   string
 	MAIL_PASSWORD               string
 	MAIL_HOST                   string
 	MAIL_PORT                   string
 	TEMPLATES_DIR               string
+	OIDC_FAKE_ENABLED           string `env:"optional"`
+	OIDC_FAKE_IDENTITIES_FILE   string `env:"optional"`
+	STUDENT_EMAIL_DOMAINS       string `env:"optional"`
+	RATE_LIMITS_FILE            string `env:"optional"`
+	CACHE_DRIVER                string `env:"optional"`
 }
 
 const (
@@ -52,7 +60,12 @@ func GetConfig(prefix string) Config {
 	types := configStruct.Type()
 
 	for i := 0; i < configStruct.NumField(); i++ {
-		configStruct.Field(i).SetString(getEnvOrFail(types.Field(i).Name))
+		field := types.Field(i)
+		if field.Tag.Get("env") == "optional" {
+			configStruct.Field(i).SetString(os.Getenv(field.Name))
+			continue
+		}
+		configStruct.Field(i).SetString(getEnvOrFail(field.Name))
 	}
 
 	return configData
@@ -1,10 +1,15 @@
 package filestore
 
-import "io"
+import (
+	"io"
+	"time"
+)
 
 type FileStore interface {
 	UploadObject(key string, f io.ReadSeeker) error
 	DeleteObject(key string) error
 	GetURI(key string) (URI string)
 	GetKey(url string) (key string)
+	// Grants temporary read access to an object that is not publicly readable
+	GetPresignedURI(key string, ttl time.Duration) (URI string, err error)
 }
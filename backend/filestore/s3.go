@@ -1,10 +1,13 @@
 package filestore
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -19,6 +22,11 @@ type S3Store struct {
 }
 
 func NewS3ImageStore(profile string, bucket string, region string) (*S3Store, error) {
+	return NewS3Store(profile, bucket, region)
+}
+
+// Objects are only as public as the bucket's policy makes them, private files need their own bucket
+func NewS3Store(profile string, bucket string, region string) (*S3Store, error) {
 	s, err := session.NewSessionWithOptions(session.Options{
 		Config: aws.Config{
 			Region: aws.String(region),
@@ -36,10 +44,21 @@ func NewS3ImageStore(profile string, bucket string, region string) (*S3Store, er
 }
 
 func (s *S3Store) UploadObject(key string, f io.ReadSeeker) (err error) {
+	// Stored with its content type so browsers display the object instead of downloading it
+	start := make([]byte, 512)
+	n, err := io.ReadFull(f, start)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
 	_, err = s.s3Client.PutObject(&s3.PutObjectInput{
-		Bucket: s.bucket,
-		Key:    aws.String(key),
-		Body:   f,
+		Bucket:      s.bucket,
+		Key:         aws.String(key),
+		Body:        f,
+		ContentType: aws.String(http.DetectContentType(start[:n])),
 	})
 	if err != nil {
 		return err
@@ -59,8 +78,15 @@ func (s *S3Store) GetURI(key string) string {
 	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", *s.bucket, *s.region, key)
 }
 
-func (s *S3Store) GetKey(url string) string {
-	parts := strings.Split(url, "/")
-	return parts[len(parts)-1]
+func (s *S3Store) GetPresignedURI(key string, ttl time.Duration) (string, error) {
+	req, _ := s.s3Client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: s.bucket,
+		Key:    aws.String(key),
+	})
+	return req.Presign(ttl)
+}
 
+// Keys may contain slashes, so the key is everything after the bucket's URI
+func (s *S3Store) GetKey(url string) string {
+	return strings.TrimPrefix(url, s.GetURI(""))
 }
@@ -12,6 +12,7 @@ require (
 	github.com/jackc/pgx/v5 v5.7.1
 	github.com/joho/godotenv v1.5.1
 	github.com/redis/go-redis/v9 v9.6.2
+	golang.org/x/image v0.20.0
 	golang.org/x/oauth2 v0.21.0
 )
 
@@ -0,0 +1,188 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	_ "image/gif"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("Unsupported image format")
+	ErrInvalidDimensions = errors.New("Invalid image dimensions")
+)
+
+type Limits struct {
+	MinWidth  int
+	MinHeight int
+	MaxWidth  int
+	MaxHeight int
+}
+
+// A resized copy of the image, fitted within the bounds without upscaling
+type Variant struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+}
+
+type Encoded struct {
+	Variant     Variant
+	Data        []byte
+	Ext         string
+	ContentType string
+	Width       int
+	Height      int
+}
+
+const jpegQuality = 85
+
+// Decodes the image, checking its dimensions before the pixels are decoded, and re-encodes it
+// for every variant. Only the pixels are kept, so metadata such as EXIF is dropped; the EXIF
+// orientation of JPEGs is applied first so photos keep facing the right way.
+func Process(r io.Reader, limits Limits, variants []Variant) ([]Encoded, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	if cfg.Width < limits.MinWidth || cfg.Height < limits.MinHeight ||
+		cfg.Width > limits.MaxWidth || cfg.Height > limits.MaxHeight {
+		return nil, fmt.Errorf("%w: %vx%v, must be between %vx%v and %vx%v", ErrInvalidDimensions,
+			cfg.Width, cfg.Height, limits.MinWidth, limits.MinHeight, limits.MaxWidth, limits.MaxHeight)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	res := make([]Encoded, 0, len(variants))
+	for _, variant := range variants {
+		// Oriented after resizing so only the small copy is transformed, orientations 5-8 swap the
+		// axes so the bounds are swapped to match
+		maxWidth, maxHeight := variant.MaxWidth, variant.MaxHeight
+		if orientation >= 5 && orientation <= 8 {
+			maxWidth, maxHeight = maxHeight, maxWidth
+		}
+		resized := orient(resize(img, maxWidth, maxHeight), orientation)
+		encoded, err := encode(resized)
+		if err != nil {
+			return nil, err
+		}
+		encoded.Variant = variant
+		res = append(res, *encoded)
+	}
+	return res, nil
+}
+
+func resize(img image.Image, maxWidth, maxHeight int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxWidth {
+		h = max(1, h*maxWidth/w)
+		w = maxWidth
+	}
+	if h > maxHeight {
+		w = max(1, w*maxHeight/h)
+		h = maxHeight
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// Opaque images become JPEGs, PNG is only used where transparency has to be kept
+func encode(img image.Image) (*Encoded, error) {
+	var buf bytes.Buffer
+	encoded := &Encoded{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+
+	if isOpaque(img) {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		encoded.Ext, encoded.ContentType = ".jpg", "image/jpeg"
+	} else {
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := enc.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		encoded.Ext, encoded.ContentType = ".png", "image/png"
+	}
+
+	encoded.Data = buf.Bytes()
+	return encoded, nil
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Applies an EXIF orientation (1-8) by flipping and rotating the image
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	// Maps source to destination coordinates, every pixel lands exactly on another pixel
+	var m f64.Aff3
+	switch orientation {
+	case 2:
+		m = f64.Aff3{-1, 0, w, 0, 1, 0}
+	case 3:
+		m = f64.Aff3{-1, 0, w, 0, -1, h}
+	case 4:
+		m = f64.Aff3{1, 0, 0, 0, -1, h}
+	case 5:
+		m = f64.Aff3{0, 1, 0, 1, 0, 0}
+	case 6:
+		m = f64.Aff3{0, -1, h, 1, 0, 0}
+	case 7:
+		m = f64.Aff3{0, -1, h, -1, 0, w}
+	case 8:
+		m = f64.Aff3{0, 1, 0, -1, 0, w}
+	}
+	minX, minY := float64(b.Min.X), float64(b.Min.Y)
+	m[2] -= m[0]*minX + m[1]*minY
+	m[5] -= m[3]*minX + m[4]*minY
+
+	// Orientations 5-8 swap the axes
+	dw, dh := b.Dx(), b.Dy()
+	if orientation >= 5 {
+		dw, dh = dh, dw
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	draw.NearestNeighbor.Transform(dst, m, img, b, draw.Src, nil)
+	return dst
+}
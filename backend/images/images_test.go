@@ -0,0 +1,286 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"slices"
+	"testing"
+)
+
+func TestOrient(t *testing.T) {
+	const w, h = 3, 2
+	// Offset bounds, as returned by SubImage, must not shift the result
+	src := image.NewNRGBA(image.Rect(5, 7, 5+w, 7+h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			src.SetNRGBA(5+x, 7+y, color.NRGBA{R: uint8(x + y*w), A: 0xff})
+		}
+	}
+
+	// The source pixels are numbered
+	//   0 1 2
+	//   3 4 5
+	// and each orientation lists the rows as they should be displayed
+	tests := map[int][][]uint8{
+		1: {{0, 1, 2}, {3, 4, 5}},
+		2: {{2, 1, 0}, {5, 4, 3}},
+		3: {{5, 4, 3}, {2, 1, 0}},
+		4: {{3, 4, 5}, {0, 1, 2}},
+		5: {{0, 3}, {1, 4}, {2, 5}},
+		6: {{3, 0}, {4, 1}, {5, 2}},
+		7: {{5, 2}, {4, 1}, {3, 0}},
+		8: {{2, 5}, {1, 4}, {0, 3}},
+	}
+	for orientation, want := range tests {
+		dst := orient(src, orientation)
+		b := dst.Bounds()
+		var got [][]uint8
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			var row []uint8
+			for x := b.Min.X; x < b.Max.X; x++ {
+				row = append(row, color.NRGBAModel.Convert(dst.At(x, y)).(color.NRGBA).R)
+			}
+			got = append(got, row)
+		}
+		if !slices.EqualFunc(got, want, slices.Equal[[]uint8]) {
+			t.Errorf("orientation %v: got %v, want %v", orientation, got, want)
+		}
+	}
+}
+
+// A w by h image whose left half is red and right half is blue
+func newTestImage(w, h int, alpha uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: 0xff, A: alpha}
+			if x >= w/2 {
+				c = color.NRGBA{B: 0xff, A: alpha}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeTestPNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeTestJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// A TIFF header with a single IFD entry holding the orientation
+func testTIFF(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], exifOrientationTag)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+	return tiff
+}
+
+// Inserts an APP1 EXIF segment holding the TIFF data right after the start of image marker
+func withTestExif(jpg []byte, tiff []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+
+	res := append([]byte{}, jpg[:2]...)
+	res = append(res, segment...)
+	res = append(res, payload...)
+	return append(res, jpg[2:]...)
+}
+
+var testLimits = Limits{MinWidth: 16, MinHeight: 16, MaxWidth: 512, MaxHeight: 512}
+
+func TestProcessRejects(t *testing.T) {
+	variants := []Variant{{Name: "full", MaxWidth: 512, MaxHeight: 512}}
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not an image", []byte("%PDF-1.7 not an image"), ErrUnsupportedFormat},
+		{"empty", nil, ErrUnsupportedFormat},
+		{"too narrow", encodeTestPNG(t, newTestImage(8, 64, 0xff)), ErrInvalidDimensions},
+		{"too short", encodeTestPNG(t, newTestImage(64, 8, 0xff)), ErrInvalidDimensions},
+		{"too wide", encodeTestPNG(t, newTestImage(600, 64, 0xff)), ErrInvalidDimensions},
+		{"too tall", encodeTestPNG(t, newTestImage(64, 600, 0xff)), ErrInvalidDimensions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(bytes.NewReader(tt.data), testLimits, variants); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessVariants(t *testing.T) {
+	variants := []Variant{
+		{Name: "thumb", MaxWidth: 100, MaxHeight: 100},
+		{Name: "tall", MaxWidth: 300, MaxHeight: 50},
+		{Name: "full", MaxWidth: 512, MaxHeight: 512},
+	}
+	tests := []struct {
+		name            string
+		alpha           uint8
+		wantContentType string
+	}{
+		{"opaque", 0xff, "image/jpeg"},
+		{"transparent", 0x80, "image/png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := Process(bytes.NewReader(encodeTestPNG(t, newTestImage(400, 200, tt.alpha))), testLimits, variants)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Fitted within the bounds keeping the 2:1 aspect ratio, and never upscaled
+			wantSizes := map[string][2]int{"thumb": {100, 50}, "tall": {100, 50}, "full": {400, 200}}
+			for _, e := range encoded {
+				if got := [2]int{e.Width, e.Height}; got != wantSizes[e.Variant.Name] {
+					t.Errorf("%v: got %v, want %v", e.Variant.Name, got, wantSizes[e.Variant.Name])
+				}
+				if e.ContentType != tt.wantContentType {
+					t.Errorf("%v: got %v, want %v", e.Variant.Name, e.ContentType, tt.wantContentType)
+				}
+				cfg, _, err := image.DecodeConfig(bytes.NewReader(e.Data))
+				if err != nil || cfg.Width != e.Width || cfg.Height != e.Height {
+					t.Errorf("%v: encoded data is %vx%v (%v), want %vx%v", e.Variant.Name, cfg.Width, cfg.Height, err, e.Width, e.Height)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessAppliesExifOrientation(t *testing.T) {
+	// Rotating 90 degrees clockwise moves the red left half to the top
+	data := withTestExif(encodeTestJPEG(t, newTestImage(64, 32, 0xff)), testTIFF(binary.LittleEndian, 6))
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("got orientation %v, want 6", got)
+	}
+
+	encoded, err := Process(bytes.NewReader(data), testLimits, []Variant{{Name: "full", MaxWidth: 40, MaxHeight: 512}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := encoded[0]
+	// The bounds apply to the displayed image, which is 32x64
+	if e.Width != 32 || e.Height != 64 {
+		t.Fatalf("got %vx%v, want 32x64", e.Width, e.Height)
+	}
+	if bytes.Contains(e.Data, []byte("Exif")) || jpegOrientation(e.Data) != 1 {
+		t.Fatal("processed image kept its EXIF metadata")
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(e.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	top := color.NRGBAModel.Convert(img.At(16, 8)).(color.NRGBA)
+	bottom := color.NRGBAModel.Convert(img.At(16, 56)).(color.NRGBA)
+	if top.R < 0xc0 || top.B > 0x40 || bottom.B < 0xc0 || bottom.R > 0x40 {
+		t.Fatalf("got top %v and bottom %v, want red above blue", top, bottom)
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	jpg := encodeTestJPEG(t, newTestImage(16, 16, 0xff))
+	valid := withTestExif(jpg, testTIFF(binary.BigEndian, 8))
+
+	// Declares a segment longer than the data that follows
+	overlong := append([]byte{}, valid[:4]...)
+	binary.BigEndian.PutUint16(overlong[2:], 0xFFFF)
+	overlong = append(overlong, valid[4:20]...)
+
+	// Segment lengths include the length field itself, so anything below 2 is invalid
+	undersized := append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01}, valid[6:]...)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"big endian", valid, 8},
+		{"little endian", withTestExif(jpg, testTIFF(binary.LittleEndian, 3)), 3},
+		{"no exif", jpg, 1},
+		{"not a jpeg", encodeTestPNG(t, newTestImage(16, 16, 0xff)), 1},
+		{"empty", nil, 1},
+		{"only start of image", valid[:3], 1},
+		{"truncated segment header", valid[:5], 1},
+		{"truncated segment", valid[:20], 1},
+		{"overlong segment", overlong, 1},
+		{"undersized segment", undersized, 1},
+		{"out of range orientation", withTestExif(jpg, testTIFF(binary.LittleEndian, 9)), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTIFFOrientation(t *testing.T) {
+	valid := testTIFF(binary.LittleEndian, 6)
+
+	badOrder := append([]byte{}, valid...)
+	copy(badOrder, "XX")
+	lowOffset := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(lowOffset[4:], 4)
+	farOffset := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(farOffset[4:], 1<<20)
+	extraEntries := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint16(extraEntries[8:], 2)
+	binary.LittleEndian.PutUint16(extraEntries[10:], 0x010F) // Make, so the orientation would be the missing second entry
+
+	tests := []struct {
+		name string
+		tiff []byte
+		want int
+	}{
+		{"valid", valid, 6},
+		{"truncated header", valid[:7], 1},
+		{"truncated entry count", valid[:9], 1},
+		{"truncated entry", valid[:21], 1},
+		{"unknown byte order", badOrder, 1},
+		{"offset into the header", lowOffset, 1},
+		{"offset past the end", farOffset, 1},
+		{"entries past the end", extraEntries, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tiffOrientation(tt.tiff); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"fmt"
+	"net"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,6 +16,8 @@ const (
 	BUSINESS_STATUS_PENDING  BusinessStatus = "pending"
 	BUSINESS_STATUS_ACTIVE   BusinessStatus = "active"
 	BUSINESS_STATUS_DISABLED BusinessStatus = "disabled"
+	// A pending request that was turned down, it can still be approved later
+	BUSINESS_STATUS_REJECTED BusinessStatus = "rejected"
 )
 
 type businessMeta struct {
@@ -20,6 +25,18 @@ type businessMeta struct {
 	Status    BusinessStatus `json:"status" db:"status"`
 	CreatedAt time.Time      `json:"created_at" db:"created_at"`
 	LogoUrl   *string        `json:"logo_url" db:"logo_url"`
+	// Resized copies of the logo, LogoUrl points to the full variant
+	LogoVariants *BusinessLogoVariants `json:"logo_variants" db:"logo_variants"`
+}
+
+type BusinessLogoVariants struct {
+	Thumb string `json:"thumb"`
+	Card  string `json:"card"`
+	Full  string `json:"full"`
+}
+
+func (v *BusinessLogoVariants) URLs() []string {
+	return []string{v.Thumb, v.Card, v.Full}
 }
 
 type BusinessUpdate struct {
@@ -28,6 +45,19 @@ type BusinessUpdate struct {
 	Website string `json:"website" db:"website" validate:"required,http_url"`
 }
 
+// Returns the lower case host name of the website, ownership of which can be verified
+func (b *BusinessUpdate) Domain() (string, error) {
+	u, err := url.Parse(b.Website)
+	if err != nil {
+		return "", err
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" || net.ParseIP(host) != nil || !strings.Contains(host, ".") {
+		return "", fmt.Errorf("Website %q does not have a domain name", b.Website)
+	}
+	return host, nil
+}
+
 type BusinessCreate struct {
 	BusinessUpdate
 }
@@ -41,6 +71,25 @@ type Business struct {
 	businessMeta
 	BusinessCreate
 	UserId uuid.UUID `json:"user_id" db:"user_id"`
+	// Set once ownership of the website's domain has been confirmed
+	DomainVerified bool `json:"domain_verified" db:"domain_verified"`
+	// Loaded for authorization, listed through the members endpoint
+	Members []BusinessMember `json:"-" db:"members"`
+}
+
+type BusinessStatusUpdate struct {
+	Reason string `json:"reason" validate:"required,min=3,max=512"`
+}
+
+type BusinessStatusChange struct {
+	Id         int            `json:"id" db:"id"`
+	BusinessId uuid.UUID      `json:"business_id" db:"business_id"`
+	FromStatus BusinessStatus `json:"from_status" db:"from_status"`
+	ToStatus   BusinessStatus `json:"to_status" db:"to_status"`
+	Reason     *string        `json:"reason" db:"reason"`
+	// Nil when the change was not made by a person
+	ChangedBy *uuid.UUID `json:"changed_by" db:"changed_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 }
 
 type BusinessQueryParams struct {
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DomainVerificationMethod string
+
+const (
+	// The token is served at a well-known path of the website
+	DOMAIN_VERIFICATION_METHOD_HTTP DomainVerificationMethod = "http"
+	// The token is published in a TXT record of the domain
+	DOMAIN_VERIFICATION_METHOD_DNS DomainVerificationMethod = "dns"
+)
+
+type DomainVerification struct {
+	BusinessId    uuid.UUID  `json:"business_id" db:"business_id"`
+	Domain        string     `json:"domain" db:"domain"`
+	Token         string     `json:"token" db:"token"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastCheckedAt *time.Time `json:"last_checked_at" db:"last_checked_at"`
+	// Why the last check failed
+	LastError  *string                   `json:"last_error" db:"last_error"`
+	Method     *DomainVerificationMethod `json:"method" db:"method"`
+	VerifiedAt *time.Time                `json:"verified_at" db:"verified_at"`
+	// The token is no longer checked afterwards, a new one has to be requested
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// Where the token can be published, filled in by the service
+	HTTPURL  string `json:"http_url" db:"-"`
+	DNSName  string `json:"dns_name" db:"-"`
+	DNSValue string `json:"dns_value" db:"-"`
+}
+
+func (v *DomainVerification) IsVerified() bool {
+	return v.VerifiedAt != nil
+}
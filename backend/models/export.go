@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type UserExport struct {
+	ExportedAt   time.Time         `json:"exported_at"`
+	User         *User             `json:"user"`
+	Businesses   []Business        `json:"businesses"`
+	Posts        []Post            `json:"posts"`
+	Applications []UserApplication `json:"applications"`
+	APITokens    []APIToken        `json:"api_tokens"`
+	Reports      []Report          `json:"reports"`
+}
+
+type UserDeletion struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
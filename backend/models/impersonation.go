@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ImpersonationAction string
+
+const (
+	IMPERSONATION_ACTION_START   ImpersonationAction = "start"
+	IMPERSONATION_ACTION_REQUEST ImpersonationAction = "request"
+	IMPERSONATION_ACTION_END     ImpersonationAction = "end"
+)
+
+type ImpersonationCreate struct {
+	Reason string `json:"reason" validate:"required,min=3,max=512"`
+}
+
+type Impersonation struct {
+	UserId         uuid.UUID `json:"user_id"`
+	ImpersonatorId uuid.UUID `json:"impersonator_id"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type ImpersonationLogEntry struct {
+	Id             int                 `json:"id" db:"id"`
+	ImpersonatorId uuid.UUID           `json:"impersonator_id" db:"impersonator_id"`
+	UserId         uuid.UUID           `json:"user_id" db:"user_id"`
+	SessionId      string              `json:"session_id" db:"session_id"`
+	Action         ImpersonationAction `json:"action" db:"action"`
+	Method         string              `json:"method" db:"method"`
+	Path           string              `json:"path" db:"path"`
+	// Only set for requests made while impersonating
+	StatusCode *int `json:"status_code" db:"status_code"`
+	// Only set when the impersonation is started
+	Reason    *string   `json:"reason" db:"reason"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type ImpersonationLogQueryParams struct {
+	UserId         *uuid.UUID
+	ImpersonatorId *uuid.UUID
+}
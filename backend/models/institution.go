@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InstitutionOverview struct {
+	Id      uuid.UUID `json:"id" db:"id"`
+	Name    string    `json:"name" db:"name"`
+	Country string    `json:"country" db:"country"`
+}
+
+type Institution struct {
+	InstitutionOverview
+	Domains   []string  `json:"domains" db:"domains"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type InstitutionCreate struct {
+	Name    string   `json:"name" db:"name" validate:"required,max=255"`
+	Country string   `json:"country" db:"country" validate:"required,iso3166_1_alpha2"`
+	Domains []string `json:"domains" db:"domains" validate:"required,min=1,dive,fqdn,max=255"`
+}
+
+type InstitutionUpdate InstitutionCreate
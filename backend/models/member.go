@@ -0,0 +1,126 @@
+package models
+
+import (
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BusinessMemberRole string
+
+const (
+	BUSINESS_MEMBER_ROLE_OWNER    BusinessMemberRole = "owner"
+	BUSINESS_MEMBER_ROLE_MANAGER  BusinessMemberRole = "manager"
+	BUSINESS_MEMBER_ROLE_REVIEWER BusinessMemberRole = "reviewer"
+	BUSINESS_MEMBER_ROLE_VIEWER   BusinessMemberRole = "viewer"
+)
+
+type BusinessPermission string
+
+const (
+	// Edit the business profile
+	BUSINESS_PERMISSION_UPDATE BusinessPermission = "update"
+	// Invite and remove members
+	BUSINESS_PERMISSION_MANAGE_MEMBERS BusinessPermission = "manage_members"
+	// Create and edit posts
+	BUSINESS_PERMISSION_MANAGE_POSTS BusinessPermission = "manage_posts"
+	// Accept, reject and complete applications
+	BUSINESS_PERMISSION_REVIEW_APPLICATIONS BusinessPermission = "review_applications"
+	// See the business, its posts and applicants regardless of status
+	BUSINESS_PERMISSION_VIEW BusinessPermission = "view"
+)
+
+var businessMemberPermissions = map[BusinessMemberRole][]BusinessPermission{
+	BUSINESS_MEMBER_ROLE_OWNER: {
+		BUSINESS_PERMISSION_UPDATE,
+		BUSINESS_PERMISSION_MANAGE_MEMBERS,
+		BUSINESS_PERMISSION_MANAGE_POSTS,
+		BUSINESS_PERMISSION_REVIEW_APPLICATIONS,
+		BUSINESS_PERMISSION_VIEW,
+	},
+	BUSINESS_MEMBER_ROLE_MANAGER: {
+		BUSINESS_PERMISSION_UPDATE,
+		BUSINESS_PERMISSION_MANAGE_MEMBERS,
+		BUSINESS_PERMISSION_MANAGE_POSTS,
+		BUSINESS_PERMISSION_REVIEW_APPLICATIONS,
+		BUSINESS_PERMISSION_VIEW,
+	},
+	BUSINESS_MEMBER_ROLE_REVIEWER: {
+		BUSINESS_PERMISSION_REVIEW_APPLICATIONS,
+		BUSINESS_PERMISSION_VIEW,
+	},
+	BUSINESS_MEMBER_ROLE_VIEWER: {
+		BUSINESS_PERMISSION_VIEW,
+	},
+}
+
+type BusinessMember struct {
+	UserId    uuid.UUID          `json:"user_id" db:"user_id"`
+	Role      BusinessMemberRole `json:"role" db:"role"`
+	CreatedAt time.Time          `json:"created_at" db:"created_at"`
+}
+
+type BusinessMemberInfo struct {
+	BusinessMember
+	Name  string `json:"name" db:"name"`
+	Email string `json:"email" db:"email"`
+}
+
+type BusinessInvitationStatus string
+
+const (
+	BUSINESS_INVITATION_STATUS_PENDING  BusinessInvitationStatus = "pending"
+	BUSINESS_INVITATION_STATUS_ACCEPTED BusinessInvitationStatus = "accepted"
+	BUSINESS_INVITATION_STATUS_DECLINED BusinessInvitationStatus = "declined"
+	BUSINESS_INVITATION_STATUS_REVOKED  BusinessInvitationStatus = "revoked"
+)
+
+type BusinessInvitationCreate struct {
+	Email string             `json:"email" db:"email" validate:"required,email,max=255"`
+	Role  BusinessMemberRole `json:"role" db:"role" validate:"required,oneof=manager reviewer viewer"`
+}
+
+type BusinessInvitation struct {
+	BusinessInvitationCreate
+	Id          uuid.UUID                `json:"id" db:"id"`
+	BusinessId  uuid.UUID                `json:"business_id" db:"business_id"`
+	Status      BusinessInvitationStatus `json:"status" db:"status"`
+	InvitedBy   uuid.UUID                `json:"invited_by" db:"invited_by"`
+	CreatedAt   time.Time                `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time                `json:"expires_at" db:"expires_at"`
+	RespondedAt *time.Time               `json:"responded_at" db:"responded_at"`
+}
+
+// Invitation as seen by the invited user
+type UserBusinessInvitation struct {
+	BusinessInvitation
+	Business BusinessOverview `json:"business" db:"business"`
+}
+
+func (i *BusinessInvitation) IsPending() bool {
+	return i.Status == BUSINESS_INVITATION_STATUS_PENDING && time.Now().Before(i.ExpiresAt)
+}
+
+func (i *BusinessInvitation) URI(baseURL string) (string, error) {
+	return url.JoinPath(baseURL, "account", "invitations")
+}
+
+func (r BusinessMemberRole) HasPermission(permission BusinessPermission) bool {
+	return slices.Contains(businessMemberPermissions[r], permission)
+}
+
+func (b *Business) MemberRole(userId uuid.UUID) (BusinessMemberRole, bool) {
+	for _, member := range b.Members {
+		if member.UserId == userId {
+			return member.Role, true
+		}
+	}
+	return "", false
+}
+
+func (b *Business) HasMemberPermission(userId uuid.UUID, permission BusinessPermission) bool {
+	role, ok := b.MemberRole(userId)
+	return ok && role.HasPermission(permission)
+}
@@ -8,6 +8,7 @@ import (
 	"math"
 	"net/http"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -29,6 +30,7 @@ func init() {
 	})
 
 	Validate.RegisterValidation("usd", validateUSD)
+	Validate.RegisterValidation("token_scope", validateTokenScope)
 }
 
 func ValidateData(data interface{}) error {
@@ -58,6 +60,11 @@ func validateUSD(fl validator.FieldLevel) bool {
 	return true
 }
 
+func validateTokenScope(fl validator.FieldLevel) bool {
+	scope, ok := fl.Field().Interface().(TokenScope)
+	return ok && slices.Contains(TokenScopes, scope)
+}
+
 func ReadRequestJson(r *http.Request, dest interface{}) error {
 	mediaType := getMediaType(r)
 	if mediaType != "application/json" {
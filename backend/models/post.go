@@ -93,6 +93,10 @@ type UserApplicationQueryParams struct {
 	PostStatus        *PostStatus
 }
 
+type PostApplicationQueryParams struct {
+	InstitutionId *uuid.UUID
+}
+
 type ApplicationNoteUpdate struct {
 	Data string `json:"data" db:"data"`
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The parts of an active business that are shown without signing in. Owner and member details
+// are left out on purpose.
+type PublicBusinessOverview struct {
+	Id             uuid.UUID             `json:"id" db:"id"`
+	Name           string                `json:"name" db:"name"`
+	LogoUrl        *string               `json:"logo_url" db:"logo_url"`
+	LogoVariants   *BusinessLogoVariants `json:"logo_variants" db:"logo_variants"`
+	DomainVerified bool                  `json:"domain_verified" db:"domain_verified"`
+}
+
+type PublicBusiness struct {
+	PublicBusinessOverview
+	Desc      string    `json:"desc" db:"description"`
+	Website   string    `json:"website" db:"website"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type PublicPost struct {
+	Id         int                    `json:"id" db:"id"`
+	BusinessId uuid.UUID              `json:"business_id" db:"business_id"`
+	Title      string                 `json:"title" db:"title"`
+	Desc       string                 `json:"desc" db:"description"`
+	Pay        float32                `json:"pay" db:"pay"`
+	TimeEst    int                    `json:"time_est" db:"time_est"`
+	CreatedAt  time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at" db:"updated_at"`
+	Business   PublicBusinessOverview `json:"business" db:"business"`
+}
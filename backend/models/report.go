@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReportReason string
+
+const (
+	REPORT_REASON_SPAM          ReportReason = "spam"
+	REPORT_REASON_INAPPROPRIATE ReportReason = "inappropriate"
+	REPORT_REASON_MISLEADING    ReportReason = "misleading"
+	REPORT_REASON_OTHER         ReportReason = "other"
+)
+
+type ReportStatus string
+
+const (
+	REPORT_STATUS_OPEN ReportStatus = "open"
+	// The reviewer acted on the report, e.g. by suspending the business or disabling the post
+	REPORT_STATUS_RESOLVED  ReportStatus = "resolved"
+	REPORT_STATUS_DISMISSED ReportStatus = "dismissed"
+)
+
+type ReportCreate struct {
+	Reason  ReportReason `json:"reason" validate:"required,oneof=spam inappropriate misleading other"`
+	Details string       `json:"details" validate:"max=2048"`
+}
+
+type ReportReview struct {
+	Status ReportStatus `json:"status" validate:"required,oneof=resolved dismissed"`
+	Notes  string       `json:"notes" validate:"max=4096"`
+}
+
+type Report struct {
+	Id          uuid.UUID    `json:"id" db:"id"`
+	ReporterId  uuid.UUID    `json:"reporter_id" db:"reporter_id"`
+	BusinessId  uuid.UUID    `json:"business_id" db:"business_id"`
+	PostId      *int         `json:"post_id" db:"post_id"`
+	Reason      ReportReason `json:"reason" db:"reason"`
+	Details     string       `json:"details" db:"details"`
+	Status      ReportStatus `json:"status" db:"status"`
+	ReviewerId  *uuid.UUID   `json:"reviewer_id" db:"reviewer_id"`
+	ReviewNotes string       `json:"review_notes" db:"review_notes"`
+	ReviewedAt  *time.Time   `json:"reviewed_at" db:"reviewed_at"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+}
+
+type ReportQueryParams struct {
+	Status     *ReportStatus
+	BusinessId *uuid.UUID
+	ReporterId *uuid.UUID
+}
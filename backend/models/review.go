@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BusinessDocumentKind string
+
+const (
+	BUSINESS_DOCUMENT_KIND_REGISTRATION     BusinessDocumentKind = "registration"
+	BUSINESS_DOCUMENT_KIND_DOMAIN_OWNERSHIP BusinessDocumentKind = "domain_ownership"
+	BUSINESS_DOCUMENT_KIND_OTHER            BusinessDocumentKind = "other"
+)
+
+type BusinessDocumentCreate struct {
+	Kind BusinessDocumentKind `json:"kind" validate:"required,oneof=registration domain_ownership other"`
+}
+
+type BusinessDocument struct {
+	Id          uuid.UUID            `json:"id" db:"id"`
+	BusinessId  uuid.UUID            `json:"business_id" db:"business_id"`
+	Kind        BusinessDocumentKind `json:"kind" db:"kind"`
+	Filename    string               `json:"filename" db:"filename"`
+	ContentType string               `json:"content_type" db:"content_type"`
+	Size        int64                `json:"size" db:"size"`
+	UploadedBy  uuid.UUID            `json:"uploaded_by" db:"uploaded_by"`
+	CreatedAt   time.Time            `json:"created_at" db:"created_at"`
+	// Short lived link to the private object, set when the document is listed
+	PreviewURL string `json:"preview_url" db:"-"`
+}
+
+type BusinessReviewChecklist struct {
+	// Registration documents match the business name and are current
+	RegistrationVerified bool `json:"registration_verified"`
+	// The website is owned by the business
+	WebsiteVerified bool `json:"website_verified"`
+	// The description is accurate and suitable for students
+	DescriptionAccurate bool `json:"description_accurate"`
+}
+
+func (c *BusinessReviewChecklist) IsComplete() bool {
+	return c.RegistrationVerified && c.WebsiteVerified && c.DescriptionAccurate
+}
+
+type BusinessReviewUpdate struct {
+	Checklist BusinessReviewChecklist `json:"checklist"`
+	Notes     string                  `json:"notes" validate:"max=4096"`
+}
+
+type BusinessReview struct {
+	BusinessId uuid.UUID               `json:"business_id" db:"business_id"`
+	ReviewerId uuid.UUID               `json:"reviewer_id" db:"reviewer_id"`
+	Checklist  BusinessReviewChecklist `json:"checklist" db:"checklist"`
+	Notes      string                  `json:"notes" db:"notes"`
+	// Set while every checklist item is ticked, cleared when the documents change
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+func (r *BusinessReview) IsComplete() bool {
+	return r != nil && r.CompletedAt != nil
+}
+
+type BusinessReviewQueueItem struct {
+	Business  Business           `json:"business"`
+	Documents []BusinessDocument `json:"documents"`
+	Review    *BusinessReview    `json:"review"`
+}
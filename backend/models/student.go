@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type StudentVerificationMethod string
+
+const (
+	STUDENT_VERIFICATION_METHOD_EMAIL StudentVerificationMethod = "email"
+	STUDENT_VERIFICATION_METHOD_ADMIN StudentVerificationMethod = "admin"
+)
+
+type StudentVerification struct {
+	Method      StudentVerificationMethod `json:"method" db:"method"`
+	SchoolEmail *string                   `json:"school_email" db:"school_email"`
+	VerifiedBy  *uuid.UUID                `json:"verified_by" db:"verified_by"`
+	VerifiedAt  time.Time                 `json:"verified_at" db:"verified_at"`
+	ExpiresAt   time.Time                 `json:"expires_at" db:"expires_at"`
+}
+
+type StudentVerificationRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type StudentVerificationConfirm struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type StudentVerificationOverride struct {
+	ExpiresAt time.Time `json:"expires_at" validate:"required"`
+}
+
+func (v *StudentVerification) IsValid() bool {
+	return v != nil && time.Now().Before(v.ExpiresAt)
+}
+
+// Reports whether the address belongs to one of the given domain suffixes, e.g. ".edu" or "ac.uk"
+func IsSchoolEmail(email string, domains []string) bool {
+	_, domain, ok := strings.Cut(strings.ToLower(email), "@")
+	if !ok {
+		return false
+	}
+	for _, suffix := range domains {
+		suffix = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(suffix)), ".")
+		if domain == suffix || strings.HasSuffix(domain, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TokenScope string
+
+const (
+	TOKEN_SCOPE_USER_READ          TokenScope = "user:read"
+	TOKEN_SCOPE_BUSINESSES_READ    TokenScope = "businesses:read"
+	TOKEN_SCOPE_BUSINESSES_WRITE   TokenScope = "businesses:write"
+	TOKEN_SCOPE_POSTS_READ         TokenScope = "posts:read"
+	TOKEN_SCOPE_POSTS_WRITE        TokenScope = "posts:write"
+	TOKEN_SCOPE_APPLICATIONS_READ  TokenScope = "applications:read"
+	TOKEN_SCOPE_APPLICATIONS_WRITE TokenScope = "applications:write"
+)
+
+var TokenScopes = []TokenScope{
+	TOKEN_SCOPE_USER_READ,
+	TOKEN_SCOPE_BUSINESSES_READ,
+	TOKEN_SCOPE_BUSINESSES_WRITE,
+	TOKEN_SCOPE_POSTS_READ,
+	TOKEN_SCOPE_POSTS_WRITE,
+	TOKEN_SCOPE_APPLICATIONS_READ,
+	TOKEN_SCOPE_APPLICATIONS_WRITE,
+}
+
+type APITokenCreate struct {
+	Name          string       `json:"name" db:"name" validate:"required,min=3,max=64"`
+	Scopes        []TokenScope `json:"scopes" db:"scopes" validate:"required,min=1,dive,token_scope"`
+	ExpiresInDays int          `json:"expires_in_days" validate:"required,gt=0,lte=365"`
+}
+
+type APIToken struct {
+	Id         uuid.UUID    `json:"id" db:"id"`
+	UserId     uuid.UUID    `json:"user_id" db:"user_id"`
+	Name       string       `json:"name" db:"name"`
+	Scopes     []TokenScope `json:"scopes" db:"scopes"`
+	ExpiresAt  time.Time    `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time   `json:"revoked_at" db:"revoked_at"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+}
+
+// Returned once on creation, the plaintext token is never stored
+type APITokenCreated struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+func (t *APIToken) IsActive() bool {
+	return t.RevokedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+func (t *APIToken) HasScope(scope TokenScope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
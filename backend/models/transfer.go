@@ -0,0 +1,55 @@
+package models
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BusinessTransferStatus string
+
+const (
+	BUSINESS_TRANSFER_STATUS_PENDING   BusinessTransferStatus = "pending"
+	BUSINESS_TRANSFER_STATUS_ACCEPTED  BusinessTransferStatus = "accepted"
+	BUSINESS_TRANSFER_STATUS_DECLINED  BusinessTransferStatus = "declined"
+	BUSINESS_TRANSFER_STATUS_CANCELLED BusinessTransferStatus = "cancelled"
+	// Completed by an admin without the recipient's acceptance
+	BUSINESS_TRANSFER_STATUS_FORCED BusinessTransferStatus = "forced"
+)
+
+type BusinessTransferCreate struct {
+	UserId uuid.UUID `json:"user_id" validate:"required"`
+}
+
+type BusinessTransferForce struct {
+	UserId uuid.UUID `json:"user_id" validate:"required"`
+	Reason string    `json:"reason" validate:"required,min=3,max=512"`
+}
+
+type BusinessTransfer struct {
+	Id          uuid.UUID              `json:"id" db:"id"`
+	BusinessId  uuid.UUID              `json:"business_id" db:"business_id"`
+	FromUserId  uuid.UUID              `json:"from_user_id" db:"from_user_id"`
+	ToUserId    uuid.UUID              `json:"to_user_id" db:"to_user_id"`
+	Status      BusinessTransferStatus `json:"status" db:"status"`
+	InitiatedBy uuid.UUID              `json:"initiated_by" db:"initiated_by"`
+	Reason      *string                `json:"reason" db:"reason"`
+	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time              `json:"expires_at" db:"expires_at"`
+	RespondedAt *time.Time             `json:"responded_at" db:"responded_at"`
+}
+
+// Transfer as seen by the recipient
+type UserBusinessTransfer struct {
+	BusinessTransfer
+	Business BusinessOverview `json:"business" db:"business"`
+}
+
+func (t *BusinessTransfer) IsPending() bool {
+	return t.Status == BUSINESS_TRANSFER_STATUS_PENDING && time.Now().Before(t.ExpiresAt)
+}
+
+func (t *BusinessTransfer) URI(baseURL string) (string, error) {
+	return url.JoinPath(baseURL, "account", "transfers")
+}
@@ -2,7 +2,6 @@ package models
 
 import (
 	"slices"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,8 +10,9 @@ import (
 type UserStatus string
 
 const (
-	USER_STATUS_ACTIVE   UserStatus = "active"
-	USER_STATUS_BANNED   UserStatus = "banned"
+	USER_STATUS_ACTIVE UserStatus = "active"
+	USER_STATUS_BANNED UserStatus = "banned"
+	// Temporarily suspended by an admin
 	USER_STATUS_DISABLED UserStatus = "disabled"
 )
 
@@ -21,8 +21,16 @@ type UserRole string
 const (
 	USER_ROLE_ADMIN UserRole = "admin"
 	USER_ROLE_USER  UserRole = "user"
+	// Reviews businesses and content without full admin access
+	USER_ROLE_MODERATOR UserRole = "moderator"
 )
 
+var UserRoles = []UserRole{
+	USER_ROLE_ADMIN,
+	USER_ROLE_USER,
+	USER_ROLE_MODERATOR,
+}
+
 type acctInfo struct {
 	Email         string `json:"email" db:"email"`
 	Name          string `json:"name" db:"name"`
@@ -42,37 +50,78 @@ type UserUpdate struct {
 
 type UserOverview struct {
 	UserUpdate
-	Id        uuid.UUID  `json:"id" db:"id"`
-	CreatedAt time.Time  `json:"created_at" db:"created_at"`
-	Status    UserStatus `json:"status" db:"status"`
+	Id              uuid.UUID            `json:"id" db:"id"`
+	CreatedAt       time.Time            `json:"created_at" db:"created_at"`
+	Status          UserStatus           `json:"status" db:"status"`
+	StatusReason    *string              `json:"status_reason" db:"status_reason"`
+	StatusExpiresAt *time.Time           `json:"status_expires_at" db:"status_expires_at"`
+	StatusUpdatedBy *uuid.UUID           `json:"status_updated_by" db:"status_updated_by"`
+	InstitutionId   *uuid.UUID           `json:"institution_id" db:"institution_id"`
+	Institution     *InstitutionOverview `json:"institution" db:"institution"`
+	// Set while a requested deletion is within its grace period
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at" db:"deletion_scheduled_at"`
+	DeletedAt           *time.Time `json:"deleted_at" db:"deleted_at"`
 	acctInfo
 }
 
 type UserAccount struct {
 	acctInfo
-	Provider string `json:"provider" db:"provider"`
-	// IsPrimary bool      `json:"is_primary" db:"is_primary"`
+	Id        string    `json:"id" db:"id"`
+	Provider  string    `json:"provider" db:"provider"`
+	IsPrimary bool      `json:"is_primary" db:"is_primary"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type User struct {
 	UserOverview
-	Roles    []UserRole    `json:"roles" db:"roles" validate:"required,dive"`
-	Accounts []UserAccount `json:"accounts" db:"accounts"`
+	Roles               []UserRole           `json:"roles" db:"roles" validate:"required,dive"`
+	Accounts            []UserAccount        `json:"accounts" db:"accounts"`
+	StudentVerification *StudentVerification `json:"student_verification" db:"student_verification"`
+}
+
+type UserModeration struct {
+	Reason string `json:"reason" validate:"required,max=512"`
+	// Leave empty for a permanent change
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type UserStatusInfo struct {
+	Status    UserStatus `json:"status" db:"status"`
+	Reason    *string    `json:"reason" db:"status_reason"`
+	ExpiresAt *time.Time `json:"expires_at" db:"status_expires_at"`
+}
+
+func (s *UserStatusInfo) IsBlocked() bool {
+	return s.Status != USER_STATUS_ACTIVE
 }
 
 type UserQueryParams struct {
-	Role   *UserRole
-	Status *UserStatus
+	Role          *UserRole
+	Status        *UserStatus
+	EmailVerified *bool
+}
+
+// Recorded when a login reports a different email or verification for an account
+type AccountEmailChange struct {
+	AccountProvider  string    `json:"account_provider" db:"account_provider"`
+	AccountId        string    `json:"account_id" db:"account_id"`
+	OldEmail         string    `json:"old_email" db:"old_email"`
+	NewEmail         string    `json:"new_email" db:"new_email"`
+	OldEmailVerified bool      `json:"old_email_verified" db:"old_email_verified"`
+	NewEmailVerified bool      `json:"new_email_verified" db:"new_email_verified"`
+	ChangedAt        time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// Deleted users keep their id but lose their accounts, so there is no address to mail
+func (u *UserOverview) CanBeNotified() bool {
+	return u.DeletedAt == nil && u.Email != ""
 }
 
 func (u *User) HasRole(role UserRole) bool {
 	return slices.Contains(u.Roles, role)
 }
 
+// Students must also keep the email of their primary account verified by its provider
 func (u *User) IsStudent() bool {
-	// HACK: Need to improve student verification
-	return slices.ContainsFunc(u.Accounts, func(ua UserAccount) bool {
-		return strings.HasSuffix(ua.Email, ".edu")
-	})
+	return u.EmailVerified && u.StudentVerification.IsValid()
 }
@@ -3,18 +3,14 @@ package auth
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log/slog"
-	"net/http"
-	"time"
 
-	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/cache"
 	"github.com/john-vh/college_testing/backend/db"
 	"github.com/john-vh/college_testing/backend/models"
 	"github.com/john-vh/college_testing/backend/services"
 	"github.com/john-vh/college_testing/backend/services/sessions"
-	"golang.org/x/oauth2"
 )
 
 type AuthHandler struct {
@@ -23,45 +19,38 @@ type AuthHandler struct {
 	store           *db.PgxStore
 	providers       map[string]providerConfig
 	handleErr       services.ServicesHTTPErrorHandler
+	baseURI         string
 	redirectBaseURI string
+	// Frontend path prefixes that logins may redirect to
+	redirectAllowlist []string
+	stateStore        cache.Cache
 }
 
-type providerConfig struct {
-	config   oauth2.Config
-	provider *oidc.Provider
-}
-
-type ProviderConfig struct {
-	Issuer       string
-	ClientID     string
-	ClientSecret string
-	Scopes       []string
-}
+func NewAuthHandler(logger *slog.Logger, errHandler services.ServicesHTTPErrorHandler, sessions *sessions.SessionsHandler, store *db.PgxStore, stateStore cache.Cache, baseURL string, redirectBaseURL string, redirectAllowlist []string, providerConfigs map[string]ProviderConfig) (*AuthHandler, error) {
+	if len(redirectAllowlist) == 0 {
+		redirectAllowlist = []string{"/"}
+	}
 
-func NewAuthHandler(logger *slog.Logger, errHandler services.ServicesHTTPErrorHandler, sessions *sessions.SessionsHandler, store *db.PgxStore, baseURL string, redirectBaseURL string, providerConfigs map[string]ProviderConfig) (*AuthHandler, error) {
 	providers := make(map[string]providerConfig)
 	for provider, config := range providerConfigs {
-		providerClient, err := oidc.NewProvider(context.TODO(), config.Issuer)
+		p, err := newProviderConfig(baseURL, provider, config)
 		if err != nil {
 			return nil, err
 		}
-		config := oauth2.Config{
-			ClientID:     config.ClientID,
-			ClientSecret: config.ClientSecret,
-			Endpoint:     providerClient.Endpoint(),
-			RedirectURL:  fmt.Sprintf("%v/auth/%v/callback", baseURL, provider),
-			Scopes:       config.Scopes,
-		}
-		providers[provider] = providerConfig{provider: providerClient, config: config}
+		logger.Info("Registered auth provider", "provider", provider, "issuer", config.Issuer)
+		providers[provider] = *p
 	}
 
 	return &AuthHandler{
-		logger:          logger,
-		handleErr:       errHandler,
-		sessions:        sessions,
-		store:           store,
-		providers:       providers,
-		redirectBaseURI: redirectBaseURL,
+		logger:            logger,
+		handleErr:         errHandler,
+		sessions:          sessions,
+		store:             store,
+		providers:         providers,
+		baseURI:           baseURL,
+		redirectBaseURI:   redirectBaseURL,
+		redirectAllowlist: redirectAllowlist,
+		stateStore:        stateStore,
 	}, nil
 }
 
@@ -83,6 +72,10 @@ func (auth *AuthHandler) SaveAccount(ctx context.Context, openidProvider string,
 		// Return the already associated user, or do not create a user if no data is given
 		if linkedUserId != nil {
 			auth.logger.Debug("Skipping user create", "linked_id", linkedUserId)
+			// Email verification may have changed since the last login
+			if err := pq.SyncUserInstitutions(ctx, linkedUserId); err != nil {
+				return nil, err
+			}
 			return linkedUserId, nil
 		}
 
@@ -96,6 +89,9 @@ func (auth *AuthHandler) SaveAccount(ctx context.Context, openidProvider string,
 			auth.logger.Debug("Failed to link account to user")
 			return nil, err
 		}
+		if err := pq.SyncUserInstitutions(ctx, userId); err != nil {
+			return nil, err
+		}
 
 		return userId, nil
 	})
@@ -128,7 +124,7 @@ func (auth *AuthHandler) LinkAccount(ctx context.Context, userId *uuid.UUID, ope
 		}
 
 		if linkedUserId != nil && (*linkedUserId) == (*userId) {
-			return nil
+			return pq.SyncUserInstitutions(ctx, userId)
 		} else if linkedUserId != nil {
 			return services.NewDataConflictServiceError(nil, "Account is already linked to another user")
 		}
@@ -137,17 +133,6 @@ func (auth *AuthHandler) LinkAccount(ctx context.Context, userId *uuid.UUID, ope
 		if err != nil {
 			return err
 		}
-		return nil
+		return pq.SyncUserInstitutions(ctx, userId)
 	})
 }
-
-func setCallbackCookie(w http.ResponseWriter, name string, value string) {
-	c := &http.Cookie{
-		Name:     name,
-		Value:    value,
-		MaxAge:   int(time.Hour.Seconds()),
-		Secure:   true,
-		HttpOnly: true,
-	}
-	http.SetCookie(w, c)
-}
@@ -0,0 +1,401 @@
+package fakeoidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/john-vh/college_testing/backend/util"
+)
+
+const (
+	tokenTTL = time.Hour
+	codeTTL  = time.Minute * 5
+)
+
+type Identity struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+var DefaultIdentities = []Identity{
+	{Subject: "student", Email: "student@example.edu", Name: "Test Student", EmailVerified: true},
+	{Subject: "unverified-student", Email: "unverified@example.edu", Name: "Unverified Student", EmailVerified: false},
+	{Subject: "business", Email: "owner@example.com", Name: "Test Business Owner", EmailVerified: true},
+}
+
+type grant struct {
+	identity    Identity
+	clientId    string
+	redirectURI string
+	nonce       string
+	// PKCE challenge sent with the authorization request, if any
+	codeChallenge string
+	expires       time.Time
+}
+
+// Provider is a minimal OpenID Connect issuer that signs in configured identities without
+// prompting for credentials. It is intended for local development and tests only.
+type Provider struct {
+	logger     *slog.Logger
+	issuer     string
+	issuerPath string
+	key        *rsa.PrivateKey
+	keyId      string
+	identities []Identity
+	mux        *http.ServeMux
+
+	mu     sync.Mutex
+	codes  map[string]grant
+	tokens map[string]grant
+}
+
+func NewProvider(logger *slog.Logger, issuer string, identities []Identity) (*Provider, error) {
+	issuerURL, err := url.Parse(issuer)
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		identities = DefaultIdentities
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	keyId, err := util.RandString(8)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		logger:     logger,
+		issuer:     strings.TrimSuffix(issuer, "/"),
+		issuerPath: strings.TrimSuffix(issuerURL.Path, "/"),
+		key:        key,
+		keyId:      keyId,
+		identities: identities,
+		mux:        http.NewServeMux(),
+		codes:      make(map[string]grant),
+		tokens:     make(map[string]grant),
+	}
+
+	p.mux.HandleFunc("GET /.well-known/openid-configuration", p.handleDiscovery)
+	p.mux.HandleFunc("GET /jwks", p.handleJWKS)
+	p.mux.HandleFunc("GET /authorize", p.handleAuthorize)
+	p.mux.HandleFunc("POST /token", p.handleToken)
+	p.mux.HandleFunc("GET /userinfo", p.handleUserInfo)
+
+	return p, nil
+}
+
+func LoadIdentities(path string) ([]Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var identities []Identity
+	if err := json.Unmarshal(data, &identities); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (p *Provider) Issuer() string {
+	return p.issuer
+}
+
+// Path of the issuer URL, the provider has to be mounted under it
+func (p *Provider) Path() string {
+	return p.issuerPath
+}
+
+// Serves requests with paths relative to the issuer, mount with http.StripPrefix
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+// Returns a client that reaches the provider in-process, so back-channel requests do not
+// depend on the server already listening
+func (p *Provider) Client() *http.Client {
+	return &http.Client{Transport: p}
+}
+
+func (p *Provider) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !strings.HasPrefix(r.URL.String(), p.issuer) {
+		return http.DefaultTransport.RoundTrip(r)
+	}
+
+	r = r.Clone(r.Context())
+	r.URL.Path = strings.TrimPrefix(r.URL.Path, p.issuerPath)
+	r.RequestURI = r.URL.RequestURI()
+
+	rec := httptest.NewRecorder()
+	p.mux.ServeHTTP(rec, r)
+	res := rec.Result()
+	res.Request = r
+	return res, nil
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"userinfo_endpoint":                     p.issuer + "/userinfo",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"claims_supported":                      []string{"sub", "email", "email_verified", "name"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": p.keyId,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+var chooserTemplate = template.Must(template.New("chooser").Parse(`<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="UTF-8">
+    <title>Fake OpenID Provider</title>
+  </head>
+  <body>
+    <h1>Sign in as</h1>
+    <ul>
+      {{range .}}<li><a href="{{.Link}}">{{.Name}} &lt;{{.Email}}&gt;</a>{{if not .EmailVerified}} (unverified){{end}}</li>
+      {{end}}
+    </ul>
+  </body>
+</html>`))
+
+func (p *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" {
+		http.Error(w, "unsupported response_type", http.StatusBadRequest)
+		return
+	}
+
+	challenge := query.Get("code_challenge")
+	if challenge != "" && query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "unsupported code_challenge_method", http.StatusBadRequest)
+		return
+	}
+
+	hint := query.Get("login_hint")
+	identity, ok := p.findIdentity(hint)
+	if !ok {
+		type choice struct {
+			Identity
+			Link string
+		}
+		choices := make([]choice, 0, len(p.identities))
+		for _, identity := range p.identities {
+			q := r.URL.Query()
+			q.Set("login_hint", identity.Subject)
+			choices = append(choices, choice{Identity: identity, Link: p.issuer + "/authorize?" + q.Encode()})
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		chooserTemplate.Execute(w, choices)
+		return
+	}
+
+	code, err := util.RandString(16)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p.mu.Lock()
+	p.codes[code] = grant{
+		identity:      identity,
+		clientId:      query.Get("client_id"),
+		redirectURI:   redirectURI.String(),
+		nonce:         query.Get("nonce"),
+		codeChallenge: challenge,
+		expires:       time.Now().Add(codeTTL),
+	}
+	p.mu.Unlock()
+	p.logger.Debug("Fake OIDC issued authorization code", "sub", identity.Subject)
+
+	q := redirectURI.Query()
+	q.Set("code", code)
+	if state := query.Get("state"); state != "" {
+		q.Set("state", state)
+	}
+	redirectURI.RawQuery = q.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeTokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	clientId, _, ok := r.BasicAuth()
+	if !ok {
+		clientId = r.PostForm.Get("client_id")
+	}
+
+	p.mu.Lock()
+	code := r.PostForm.Get("code")
+	g, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(g.expires) || g.clientId != clientId || g.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeTokenError(w, "invalid_grant")
+		return
+	}
+	if g.codeChallenge != "" && !verifyCodeChallenge(g.codeChallenge, r.PostForm.Get("code_verifier")) {
+		writeTokenError(w, "invalid_grant")
+		return
+	}
+
+	idToken, err := p.signIdToken(g)
+	if err != nil {
+		writeTokenError(w, "server_error")
+		return
+	}
+	accessToken, err := util.RandString(32)
+	if err != nil {
+		writeTokenError(w, "server_error")
+		return
+	}
+
+	g.expires = time.Now().Add(tokenTTL)
+	p.mu.Lock()
+	p.tokens[accessToken] = g
+	p.mu.Unlock()
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(tokenTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+func (p *Provider) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	p.mu.Lock()
+	g, ok := p.tokens[accessToken]
+	p.mu.Unlock()
+	if !ok || time.Now().After(g.expires) {
+		http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, g.identity)
+}
+
+func (p *Provider) findIdentity(hint string) (Identity, bool) {
+	if hint == "" {
+		return Identity{}, false
+	}
+	for _, identity := range p.identities {
+		if identity.Subject == hint || strings.EqualFold(identity.Email, hint) {
+			return identity, true
+		}
+	}
+	return Identity{}, false
+}
+
+func (p *Provider) signIdToken(g grant) (string, error) {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":            p.issuer,
+		"sub":            g.identity.Subject,
+		"aud":            g.clientId,
+		"iat":            now.Unix(),
+		"exp":            now.Add(tokenTTL).Unix(),
+		"email":          g.identity.Email,
+		"email_verified": g.identity.EmailVerified,
+		"name":           g.identity.Name,
+	}
+	if g.nonce != "" {
+		claims["nonce"] = g.nonce
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": p.keyId})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeTokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+// Checks an S256 code verifier against the challenge from the authorization request
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if verifier == "" {
+		return false
+	}
+	digest := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(digest[:]) == challenge
+}
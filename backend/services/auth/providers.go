@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/john-vh/college_testing/backend/models"
+	"golang.org/x/oauth2"
+)
+
+// Names of the claims holding each account field, for issuers that do not use the standard names
+type ClaimMappings struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified string `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+type ProviderConfig struct {
+	DisplayName  string        `json:"display_name"`
+	Issuer       string        `json:"issuer"`
+	ClientID     string        `json:"client_id"`
+	ClientSecret string        `json:"client_secret"`
+	Scopes       []string      `json:"scopes"`
+	Claims       ClaimMappings `json:"claims"`
+	// Treat emails as verified when the issuer does not assert email_verified, e.g. campus SSO
+	AssumeEmailVerified bool `json:"assume_email_verified"`
+	// Send an S256 code challenge with each login, required by some providers for public clients
+	PKCE bool `json:"pkce"`
+	// Optional client used to reach the issuer, defaults to http.DefaultClient
+	HTTPClient *http.Client `json:"-"`
+}
+
+type ProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+	LinkURL     string `json:"link_url"`
+}
+
+type providerConfig struct {
+	name                string
+	displayName         string
+	config              oauth2.Config
+	provider            *oidc.Provider
+	httpClient          *http.Client
+	claims              ClaimMappings
+	assumeEmailVerified bool
+	pkce                bool
+}
+
+// Reads a JSON object of provider name to ProviderConfig
+func LoadProviderConfigs(path string) (map[string]ProviderConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make(map[string]ProviderConfig)
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("Invalid auth providers file %v: %w", path, err)
+	}
+	return configs, nil
+}
+
+func newProviderConfig(baseURL string, name string, config ProviderConfig) (*providerConfig, error) {
+	if config.DisplayName == "" {
+		config.DisplayName = name
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	} else if !slices.Contains(config.Scopes, oidc.ScopeOpenID) {
+		config.Scopes = append([]string{oidc.ScopeOpenID}, config.Scopes...)
+	}
+	if config.Claims.Subject == "" {
+		config.Claims.Subject = "sub"
+	}
+	if config.Claims.Email == "" {
+		config.Claims.Email = "email"
+	}
+	if config.Claims.EmailVerified == "" {
+		config.Claims.EmailVerified = "email_verified"
+	}
+	if config.Claims.Name == "" {
+		config.Claims.Name = "name"
+	}
+
+	p := &providerConfig{
+		name:                name,
+		displayName:         config.DisplayName,
+		httpClient:          config.HTTPClient,
+		claims:              config.Claims,
+		assumeEmailVerified: config.AssumeEmailVerified,
+		pkce:                config.PKCE,
+	}
+	providerClient, err := oidc.NewProvider(p.context(context.TODO()), config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to discover auth provider %v: %w", name, err)
+	}
+	p.provider = providerClient
+	p.config = oauth2.Config{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		Endpoint:     providerClient.Endpoint(),
+		RedirectURL:  fmt.Sprintf("%v/auth/%v/callback", baseURL, name),
+		Scopes:       config.Scopes,
+	}
+
+	return p, nil
+}
+
+// Attaches the provider's HTTP client, if any, for back-channel requests
+func (p *providerConfig) context(ctx context.Context) context.Context {
+	if p.httpClient == nil {
+		return ctx
+	}
+	return oidc.ClientContext(ctx, p.httpClient)
+}
+
+func (p *providerConfig) mapClaims(raw map[string]interface{}) (*models.OpenIDClaims, error) {
+	var claims models.OpenIDClaims
+
+	sub, ok := raw[p.claims.Subject].(string)
+	if !ok || sub == "" {
+		return nil, fmt.Errorf("Provider %v did not return a %q claim", p.name, p.claims.Subject)
+	}
+	claims.Id = sub
+	claims.Email, _ = raw[p.claims.Email].(string)
+	claims.Name, _ = raw[p.claims.Name].(string)
+
+	switch verified := raw[p.claims.EmailVerified].(type) {
+	case bool:
+		claims.EmailVerified = verified
+	case string:
+		claims.EmailVerified, _ = strconv.ParseBool(verified)
+	default:
+		claims.EmailVerified = p.assumeEmailVerified
+	}
+
+	return &claims, nil
+}
+
+func (p *providerConfig) info(baseURL string) ProviderInfo {
+	return ProviderInfo{
+		Name:        p.name,
+		DisplayName: p.displayName,
+		LoginURL:    fmt.Sprintf("%v/auth/%v", baseURL, p.name),
+		LinkURL:     fmt.Sprintf("%v/auth/%v/link", baseURL, p.name),
+	}
+}
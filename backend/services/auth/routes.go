@@ -2,32 +2,76 @@ package auth
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"net/http"
-	"net/url"
+	"slices"
+	"strings"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/google/uuid"
-	"github.com/john-vh/college_testing/backend/models"
 	"github.com/john-vh/college_testing/backend/services"
 	"github.com/john-vh/college_testing/backend/util"
 	"golang.org/x/oauth2"
 )
 
 func (auth *AuthHandler) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /auth/providers", auth.handleErr(auth.handleGetProviders))
 	router.HandleFunc("GET /auth/{provider}", auth.handleErr(auth.handleLogin))
+	router.HandleFunc("GET /auth/{provider}/link", auth.handleErr(auth.handleLink))
 	router.HandleFunc("GET /auth/{provider}/callback", auth.handleErr(auth.handleLoginCallback))
 	router.HandleFunc("POST /auth/logout", auth.handleErr(auth.handleLogout))
 
 	auth.logger.Info("Registered auth routes")
 }
 
+func (auth *AuthHandler) handleGetProviders(w http.ResponseWriter, r *http.Request) error {
+	providers := make([]ProviderInfo, 0, len(auth.providers))
+	for _, provider := range auth.providers {
+		providers = append(providers, provider.info(auth.baseURI))
+	}
+	slices.SortFunc(providers, func(a, b ProviderInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(providers)
+	return nil
+}
+
 func (auth *AuthHandler) handleLogin(w http.ResponseWriter, r *http.Request) error {
+	return auth.startLogin(w, r, false)
+}
+
+func (auth *AuthHandler) handleLink(w http.ResponseWriter, r *http.Request) error {
+	session, err := auth.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+	if session.GetUserId() == nil {
+		return services.NewUnauthenticatedServiceError(nil)
+	}
+	if err := session.RequireInteractive(); err != nil {
+		return err
+	}
+
+	return auth.startLogin(w, r, true)
+}
+
+func (auth *AuthHandler) startLogin(w http.ResponseWriter, r *http.Request, link bool) error {
 	provider := r.PathValue("provider")
 	client, ok := auth.providers[provider]
 	if !ok {
 		return services.NewNotFoundServiceError(nil)
 	}
 
+	redirect, ok := auth.validateRedirect(r.URL.Query().Get("redirect"))
+	if !ok {
+		auth.logger.Debug("Rejected login redirect", "redirect", r.URL.Query().Get("redirect"))
+		return services.NewBadRequestServiceError(nil)
+	}
+
 	state, err := util.RandString(16)
 	if err != nil {
 		return err
@@ -37,11 +81,29 @@ func (auth *AuthHandler) handleLogin(w http.ResponseWriter, r *http.Request) err
 		return err
 	}
 
-	redirect := r.URL.Query().Get("redirect")
-	setCallbackCookie(w, "state", state)
-	setCallbackCookie(w, "nonce", nonce)
-	setCallbackCookie(w, "redirect", redirect)
-	http.Redirect(w, r, client.config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.ApprovalForce), http.StatusFound)
+	data := &loginState{
+		Provider: provider,
+		Nonce:    nonce,
+		Redirect: redirect,
+	}
+	if link {
+		session, err := auth.sessions.GetSession(r)
+		if err != nil {
+			return err
+		}
+		data.LinkUserId = session.GetUserId()
+	}
+	opts := []oauth2.AuthCodeOption{oidc.Nonce(nonce), oauth2.ApprovalForce}
+	if client.pkce {
+		// The verifier stays server side with the rest of the login state
+		data.Verifier = oauth2.GenerateVerifier()
+		opts = append(opts, oauth2.S256ChallengeOption(data.Verifier))
+	}
+	if err := auth.saveLoginState(r.Context(), w, state, data); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, client.config.AuthCodeURL(state, opts...), http.StatusFound)
 
 	return nil
 }
@@ -53,15 +115,30 @@ func (auth *AuthHandler) handleLoginCallback(w http.ResponseWriter, r *http.Requ
 		return services.NewNotFoundServiceError(nil)
 	}
 
-	state, err := r.Cookie("state")
-	if err != nil || r.URL.Query().Get("state") != state.Value {
+	state, err := auth.takeLoginState(r.Context(), w, r)
+	if err != nil {
 		auth.logger.Debug("State did not match")
-		return services.NewBadRequestServiceError(err)
+		return err
+	}
+	if state.Provider != provider {
+		auth.logger.Debug("State was issued for a different provider", "provider", provider, "state_provider", state.Provider)
+		return services.NewBadRequestServiceError(nil)
+	}
+
+	opts := []oauth2.AuthCodeOption{}
+	if state.Verifier != "" {
+		opts = append(opts, oauth2.VerifierOption(state.Verifier))
 	}
 
-	oauth2Token, err := client.config.Exchange(r.Context(), r.URL.Query().Get("code"))
+	ctx := client.context(r.Context())
+	oauth2Token, err := client.config.Exchange(ctx, r.URL.Query().Get("code"), opts...)
 	if err != nil {
-		auth.logger.Debug("Failed to exchange for token")
+		auth.logger.Debug("Failed to exchange for token", "err", err)
+		// The issuer refused the code, e.g. because it was already used or issued for another login
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			return services.NewBadRequestServiceError(err)
+		}
 		return services.NewInternalServiceError(err)
 	}
 
@@ -73,55 +150,77 @@ func (auth *AuthHandler) handleLoginCallback(w http.ResponseWriter, r *http.Requ
 
 	verifier := client.provider.Verifier(&oidc.Config{ClientID: client.config.ClientID})
 
-	idToken, err := verifier.Verify(context.TODO(), rawIdToken)
+	idToken, err := verifier.Verify(ctx, rawIdToken)
 	if err != nil {
 		auth.logger.Debug("Failed to verify raw token")
 		return services.NewInternalServiceError(err)
 	}
 
-	nonce, err := r.Cookie("nonce")
-	if err != nil || idToken.Nonce != nonce.Value {
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(state.Nonce)) != 1 {
 		auth.logger.Debug("Nonce did not match")
+		return services.NewBadRequestServiceError(nil)
+	}
+
+	rawClaims := make(map[string]interface{})
+	if err := idToken.Claims(&rawClaims); err != nil {
+		auth.logger.Debug("Failed to retreive id token claims")
 		return services.NewInternalServiceError(err)
 	}
 
-	var claims models.OpenIDClaims
+	if client.provider.UserInfoEndpoint() != "" {
+		userInfo, err := client.provider.UserInfo(ctx, oauth2.StaticTokenSource(oauth2Token))
+		if err != nil {
+			auth.logger.Debug("Failed to retreive user info")
+			return services.NewInternalServiceError(err)
+		}
 
-	userInfo, err := client.provider.UserInfo(context.TODO(), oauth2.StaticTokenSource(oauth2Token))
-	if err != nil {
-		auth.logger.Debug("Failed to retreive user info")
-		return services.NewInternalServiceError(err)
+		if err := userInfo.Claims(&rawClaims); err != nil {
+			auth.logger.Debug("Failed to retreive user info claims")
+			return services.NewInternalServiceError(err)
+		}
 	}
 
-	if err := userInfo.Claims(&claims); err != nil {
-		auth.logger.Debug("Failed to retreive user info claims")
+	claims, err := client.mapClaims(rawClaims)
+	if err != nil {
+		auth.logger.Debug("Failed to map claims", "err", err)
 		return services.NewInternalServiceError(err)
 	}
 
+	link := state.LinkUserId != nil
+
 	var userId *uuid.UUID
 	session, err := auth.sessions.GetSession(r)
-	if err == nil {
+	// Accounts are never linked to an impersonated user, signing in replaces the session instead
+	if err == nil && !session.IsImpersonating() {
 		userId = session.GetUserId()
 	}
+	if link && (userId == nil || *userId != *state.LinkUserId) {
+		auth.logger.Debug("Link was started by a different user")
+		return services.NewUnauthenticatedServiceError(nil)
+	}
 
-	linkedUser, err := auth.GetLinkedUser(r.Context(), provider, &claims)
+	linkedUser, err := auth.GetLinkedUser(r.Context(), provider, claims)
 	if err != nil {
 		auth.logger.Error("Error checking for linked user", "err", err)
 		return err
 	}
-	if userId != nil && (linkedUser == nil || *userId == *linkedUser) {
+	if userId != nil && (link || linkedUser == nil || *userId == *linkedUser) {
 		auth.logger.Debug("UserId is not nil", "user_id", userId)
-		err := auth.LinkAccount(context.TODO(), userId, provider, &claims)
+		err := auth.LinkAccount(context.TODO(), userId, provider, claims)
 		if err != nil {
 			auth.logger.Debug("Failed to link account")
 			return err
 		}
 	} else {
-		userId, err := auth.SaveAccount(context.TODO(), provider, &claims)
+		userId, err := auth.SaveAccount(context.TODO(), provider, claims)
 		if err != nil {
 			auth.logger.Debug("Failed to create account")
 			return err
 		}
+		if err := auth.sessions.ValidateUser(r.Context(), userId); err != nil {
+			auth.logger.Info("Rejected login for blocked user", "user_id", userId)
+			return err
+		}
 		_, err = auth.sessions.SetNewSession(w, r, userId)
 		if err != nil {
 			auth.logger.Debug("Failed to set session")
@@ -129,12 +228,7 @@ func (auth *AuthHandler) handleLoginCallback(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
-	redirect := ""
-	if c, err := r.Cookie("redirect"); err == nil {
-		redirect = c.Value
-	}
-
-	fullRedirect, err := url.JoinPath(auth.redirectBaseURI, redirect)
+	fullRedirect, err := auth.redirectURL(state.Redirect)
 	if err != nil {
 		return services.NewInternalServiceError(err)
 	}
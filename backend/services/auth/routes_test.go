@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/john-vh/college_testing/backend/cache"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/auth/fakeoidc"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+const (
+	testBaseURI = "http://localhost/api"
+	testUIURI   = "http://localhost:3000"
+)
+
+type testAuth struct {
+	router   *http.ServeMux
+	provider *fakeoidc.Provider
+}
+
+// Wires the auth routes to an in-process fake issuer, store may be nil for flows that fail before
+// any account is saved
+func newTestAuth(t *testing.T, store *db.PgxStore) *testAuth {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	provider, err := fakeoidc.NewProvider(logger, testBaseURI+"/fake-oidc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memoryCache := cache.NewMemoryCache(0)
+	sessionsHandler := sessions.NewSessionHandler(logger, cache.NewPrefixCache(memoryCache, "sessions:"), sessions.SessionsConfig{
+		IdleTTL:          time.Hour,
+		MaxLifetime:      time.Hour * 24,
+		UnauthorizedTTL:  time.Hour,
+		ImpersonationTTL: time.Hour,
+	})
+	handler, err := NewAuthHandler(logger, services.HandleHTTPError, sessionsHandler, store, cache.NewPrefixCache(memoryCache, "auth:"), testBaseURI, testUIURI, nil, map[string]ProviderConfig{
+		"fake": {
+			Issuer:       provider.Issuer(),
+			ClientID:     "fake-client",
+			ClientSecret: "fake-secret",
+			PKCE:         true,
+			HTTPClient:   provider.Client(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := http.NewServeMux()
+	handler.RegisterRoutes(router)
+	return &testAuth{router: router, provider: provider}
+}
+
+// Starts a login and follows the fake issuer's authorization redirect, returning the callback
+// URL and the state cookie the browser would send with it
+func (ta *testAuth) authorize(t *testing.T, loginHint string) (*url.URL, *http.Cookie) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	ta.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/fake?redirect=/dashboard", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login returned %v, want %v", rec.Code, http.StatusFound)
+	}
+	var stateCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == stateCookieName {
+			stateCookie = c
+		}
+	}
+	if stateCookie == nil {
+		t.Fatal("login did not set the state cookie")
+	}
+
+	authorizeURL, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := authorizeURL.Query()
+	q.Set("login_hint", loginHint)
+	authorizeURL.RawQuery = q.Encode()
+
+	rec = httptest.NewRecorder()
+	ta.provider.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, strings.TrimPrefix(authorizeURL.RequestURI(), ta.provider.Path()), nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("authorize returned %v, want %v", rec.Code, http.StatusFound)
+	}
+	callbackURL, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The auth routes are mounted below the base path
+	callbackURL.Path = strings.TrimPrefix(callbackURL.Path, "/api")
+	return callbackURL, stateCookie
+}
+
+func (ta *testAuth) callback(callbackURL *url.URL, stateCookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, callbackURL.RequestURI(), nil)
+	if stateCookie != nil {
+		req.AddCookie(stateCookie)
+	}
+	rec := httptest.NewRecorder()
+	ta.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLoginCallbackRejectsMissingState(t *testing.T) {
+	ta := newTestAuth(t, nil)
+	callbackURL, _ := ta.authorize(t, "student")
+
+	rec := ta.callback(callbackURL, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("callback returned %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestLoginCallbackRejectsCodeFromAnotherLogin(t *testing.T) {
+	ta := newTestAuth(t, nil)
+	callbackURL, stateCookie := ta.authorize(t, "student")
+	otherURL, _ := ta.authorize(t, "student")
+
+	// The other login's code was issued for a different PKCE challenge, so the issuer refuses it
+	q := callbackURL.Query()
+	q.Set("code", otherURL.Query().Get("code"))
+	callbackURL.RawQuery = q.Encode()
+
+	rec := ta.callback(callbackURL, stateCookie)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("callback returned %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session" {
+			t.Fatal("callback set a session for a rejected login")
+		}
+	}
+}
+
+// Runs against a migrated database given by TEST_DATABASE_URL
+func TestLoginCallbackCreatesSession(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	pgConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := db.NewPgxStorage(context.Background(), pgConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta := newTestAuth(t, store)
+	callbackURL, stateCookie := ta.authorize(t, "student")
+
+	rec := ta.callback(callbackURL, stateCookie)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("callback returned %v, want %v: %v", rec.Code, http.StatusFound, rec.Body.String())
+	}
+	if location := rec.Header().Get("Location"); location != testUIURI+"/dashboard" {
+		t.Fatalf("callback redirected to %v, want %v", location, testUIURI+"/dashboard")
+	}
+	hasSession := false
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session" && c.Value != "" {
+			hasSession = true
+		}
+	}
+	if !hasSession {
+		t.Fatal("callback did not set a session")
+	}
+
+	// States are single use
+	rec = ta.callback(callbackURL, stateCookie)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("replayed callback returned %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/cache"
+	"github.com/john-vh/college_testing/backend/services"
+)
+
+const (
+	stateCookieName = "oidc_state"
+	stateTTL        = time.Minute * 10
+)
+
+// Everything needed to finish an authorization code flow, kept server side so the browser only
+// holds the opaque state value
+type loginState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Redirect string `json:"redirect"`
+	// PKCE code verifier, empty when the provider does not use PKCE
+	Verifier string `json:"verifier,omitempty"`
+	// Set when linking, the account is only linked to the user that started the flow
+	LinkUserId *uuid.UUID `json:"link_user_id,omitempty"`
+}
+
+func loginStateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+func (auth *AuthHandler) saveLoginState(ctx context.Context, w http.ResponseWriter, state string, data *loginState) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err := auth.stateStore.Set(ctx, loginStateKey(state), b, stateTTL); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(stateTTL.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		// Lax so the cookie is sent on the top-level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// Loads and consumes the state for the callback request, states can only be used once
+func (auth *AuthHandler) takeLoginState(ctx context.Context, w http.ResponseWriter, r *http.Request) (*loginState, error) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	state := r.URL.Query().Get("state")
+	c, err := r.Cookie(stateCookieName)
+	if err != nil || state == "" || c.Value != state {
+		return nil, services.NewBadRequestServiceError(err)
+	}
+
+	key := loginStateKey(state)
+	b, err := auth.stateStore.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil, services.NewBadRequestServiceError(err)
+		}
+		return nil, err
+	}
+	if err := auth.stateStore.Delete(ctx, key); err != nil {
+		return nil, err
+	}
+
+	data := loginState{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, services.NewBadRequestServiceError(err)
+	}
+	return &data, nil
+}
+
+// Accepts only same-origin paths that fall under one of the allowed prefixes, so the redirect
+// can not leave the frontend or reach unexpected pages through path tricks
+func (auth *AuthHandler) validateRedirect(redirect string) (string, bool) {
+	if redirect == "" {
+		return "/", true
+	}
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.ContainsAny(redirect, "\\\r\n\t") {
+		return "", false
+	}
+
+	u, err := url.Parse(redirect)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return "", false
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == "." || segment == ".." {
+			return "", false
+		}
+	}
+
+	for _, prefix := range auth.redirectAllowlist {
+		if u.Path == prefix || strings.HasPrefix(u.Path, strings.TrimSuffix(prefix, "/")+"/") {
+			return u.String(), true
+		}
+	}
+	return "", false
+}
+
+func (auth *AuthHandler) redirectURL(redirect string) (string, error) {
+	base, err := url.Parse(auth.redirectBaseURI)
+	if err != nil {
+		return "", err
+	}
+	target, err := url.Parse(redirect)
+	if err != nil {
+		return "", err
+	}
+
+	base.Path = strings.TrimSuffix(base.Path, "/") + target.Path
+	base.RawPath = ""
+	base.RawQuery = target.RawQuery
+	base.Fragment = target.Fragment
+	return base.String(), nil
+}
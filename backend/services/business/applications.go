@@ -54,7 +54,7 @@ func (h *BusinessHandler) CreateApplication(ctx context.Context, session *sessio
 			return nil
 		}
 
-		if err := AuthorizeApplicationAction(sessionUser, APPLICATION_ACTION_CREATE, business, targetUser, nil, nil); err != nil {
+		if err := authorizeApplicationAction(session, sessionUser, APPLICATION_ACTION_CREATE, business, targetUser, nil, nil); err != nil {
 			return err
 		}
 
@@ -81,6 +81,9 @@ func (h *BusinessHandler) CreateApplication(ctx context.Context, session *sessio
 				h.logger.Debug("Failed to get post owner while sending email")
 				return
 			}
+			if !owner.CanBeNotified() {
+				return
+			}
 			err = h.notifications.EnqueueWithTimeout(context.Background(), h.NewApplicationReceivedNotification(owner, targetUser, post))
 			if err != nil {
 				h.logger.Debug("Failed to send application confirmation email", "err", err)
@@ -155,7 +158,7 @@ func (h *BusinessHandler) SetApplicationStatus(ctx context.Context, session *ses
 			return services.NewBadRequestServiceError(fmt.Errorf("Invalid application status"))
 		}
 
-		if err := AuthorizeApplicationAction(sessionUser, action, business, targetUser, application, nil); err != nil {
+		if err := authorizeApplicationAction(session, sessionUser, action, business, targetUser, application, nil); err != nil {
 			return err
 		}
 
@@ -176,7 +179,7 @@ func (h *BusinessHandler) SetApplicationStatus(ctx context.Context, session *ses
 	return nil
 }
 
-func (h *BusinessHandler) GetPostApplications(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, postId int) (*models.PostApplications, error) {
+func (h *BusinessHandler) GetPostApplications(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, postId int, params *models.PostApplicationQueryParams) (*models.PostApplications, error) {
 	h.logger.Debug("Retrieving post applications", "Business Id", businessId, "Post Id", postId)
 	userId := session.GetUserId()
 	if userId == nil {
@@ -192,11 +195,11 @@ func (h *BusinessHandler) GetPostApplications(ctx context.Context, session *sess
 			return nil, services.NewUnauthorizedServiceError(err)
 		}
 
-		if err := AuthorizeApplicationAction(user, APPLICATION_ACTION_READ, business, nil, nil, nil); err != nil {
+		if err := authorizeApplicationAction(session, user, APPLICATION_ACTION_READ, business, nil, nil, nil); err != nil {
 			return nil, err
 		}
 
-		applications, err := pq.GetApplicationsForPost(ctx, businessId, postId)
+		applications, err := pq.GetApplicationsForPost(ctx, businessId, postId, params)
 		if err != nil {
 			if errors.Is(err, db.ErrNoRows) {
 				return nil, services.NewNotFoundServiceError(err)
@@ -223,7 +226,7 @@ func (h *BusinessHandler) GetUserApplications(ctx context.Context, session *sess
 		if err != nil {
 			return nil, services.NewUnauthorizedServiceError(err)
 		}
-		if err := AuthorizeApplicationAction(user, APPLICATION_ACTION_READ_USER, nil, nil, nil, params); err != nil {
+		if err := authorizeApplicationAction(session, user, APPLICATION_ACTION_READ_USER, nil, nil, nil, params); err != nil {
 			return nil, err
 		}
 
@@ -248,6 +251,25 @@ const (
 	APPLICATION_ACTION_WITHDRAW   ApplicationAction = "application:withdraw"
 )
 
+var applicationActionScopes = map[ApplicationAction]models.TokenScope{
+	APPLICATION_ACTION_CREATE:     models.TOKEN_SCOPE_APPLICATIONS_WRITE,
+	APPLICATION_ACTION_READ_USER:  models.TOKEN_SCOPE_APPLICATIONS_READ,
+	APPLICATION_ACTION_READ:       models.TOKEN_SCOPE_APPLICATIONS_READ,
+	APPLICATION_ACTION_REJECT:     models.TOKEN_SCOPE_APPLICATIONS_WRITE,
+	APPLICATION_ACTION_ACCEPT:     models.TOKEN_SCOPE_APPLICATIONS_WRITE,
+	APPLICATION_ACTION_COMPLETE:   models.TOKEN_SCOPE_APPLICATIONS_WRITE,
+	APPLICATION_ACTION_INCOMPLETE: models.TOKEN_SCOPE_APPLICATIONS_WRITE,
+	APPLICATION_ACTION_WITHDRAW:   models.TOKEN_SCOPE_APPLICATIONS_WRITE,
+}
+
+// Checks the scope required when the session is backed by an API token before authorizing the action
+func authorizeApplicationAction(session *sessions.Session, user *models.User, action ApplicationAction, business *models.Business, targetUser *models.User, application *models.UserApplication, query *models.UserApplicationQueryParams) error {
+	if err := session.RequireScope(applicationActionScopes[action]); err != nil {
+		return err
+	}
+	return AuthorizeApplicationAction(user, action, business, targetUser, application, query)
+}
+
 func AuthorizeApplicationAction(user *models.User, action ApplicationAction, business *models.Business, targetUser *models.User, application *models.UserApplication, query *models.UserApplicationQueryParams) error {
 	if user == nil {
 		return services.NewUnauthenticatedServiceError(nil)
@@ -274,6 +296,13 @@ func AuthorizeApplicationAction(user *models.User, action ApplicationAction, bus
 			case APPLICATION_ACTION_WITHDRAW:
 				return nil
 			}
+		case models.USER_ROLE_MODERATOR:
+			switch action {
+			case APPLICATION_ACTION_READ_USER:
+				return nil
+			case APPLICATION_ACTION_READ:
+				return nil
+			}
 		case models.USER_ROLE_USER:
 			switch action {
 			case APPLICATION_ACTION_CREATE:
@@ -285,23 +314,23 @@ func AuthorizeApplicationAction(user *models.User, action ApplicationAction, bus
 					return nil
 				}
 			case APPLICATION_ACTION_READ:
-				if business != nil && business.UserId == user.Id {
+				if business != nil && business.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_VIEW) {
 					return nil
 				}
 			case APPLICATION_ACTION_ACCEPT:
-				if business != nil && business.UserId == user.Id {
+				if business != nil && business.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_REVIEW_APPLICATIONS) {
 					return nil
 				}
 			case APPLICATION_ACTION_REJECT:
-				if business != nil && business.UserId == user.Id {
+				if business != nil && business.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_REVIEW_APPLICATIONS) {
 					return nil
 				}
 			case APPLICATION_ACTION_COMPLETE:
-				if business != nil && business.UserId == user.Id {
+				if business != nil && business.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_REVIEW_APPLICATIONS) {
 					return nil
 				}
 			case APPLICATION_ACTION_INCOMPLETE:
-				if business != nil && business.UserId == user.Id {
+				if business != nil && business.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_REVIEW_APPLICATIONS) {
 					return nil
 				}
 			case APPLICATION_ACTION_WITHDRAW:
@@ -337,6 +366,9 @@ func (h *BusinessHandler) sendStatusWithdrawnNotificiation(ctx context.Context,
 	if err != nil {
 		return err
 	}
+	if !recipient.CanBeNotified() {
+		return nil
+	}
 	return h.notifications.EnqueueWithTimeout(ctx, h.NewApplicationWithdrawnNotification(recipient, applicant, application))
 }
 
@@ -358,5 +390,8 @@ func (h *BusinessHandler) sendStatusUpdateNotificiation(ctx context.Context, bus
 	if err != nil {
 		return err
 	}
+	if !applicant.CanBeNotified() {
+		return nil
+	}
 	return h.notifications.EnqueueWithTimeout(ctx, h.NewApplicationUpdatedNotification(applicant, application))
 }
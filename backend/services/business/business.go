@@ -1,16 +1,19 @@
 package business
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/john-vh/college_testing/backend/db"
 	"github.com/john-vh/college_testing/backend/filestore"
+	"github.com/john-vh/college_testing/backend/images"
 	"github.com/john-vh/college_testing/backend/models"
 	"github.com/john-vh/college_testing/backend/services"
 	"github.com/john-vh/college_testing/backend/services/notifications"
@@ -24,6 +27,7 @@ type BusinessHandler struct {
 	users                     *user.UserHandler
 	store                     *db.PgxStore
 	filestore                 filestore.FileStore
+	documents                 filestore.FileStore
 	notifications             *notifications.NotificationsService
 	notificationsTemplatesDir string
 	frontendURL               string
@@ -36,6 +40,7 @@ func NewBusinessHandler(
 	users *user.UserHandler,
 	store *db.PgxStore,
 	filestore filestore.FileStore,
+	documents filestore.FileStore,
 	notifications *notifications.NotificationsService,
 	notificationsTemplatesDir string,
 	frontendURL string,
@@ -47,6 +52,7 @@ func NewBusinessHandler(
 		users:                     users,
 		store:                     store,
 		filestore:                 filestore,
+		documents:                 documents,
 		notifications:             notifications,
 		notificationsTemplatesDir: notificationsTemplatesDir,
 		frontendURL:               frontendURL,
@@ -71,7 +77,7 @@ func (h *BusinessHandler) RequestBusiness(ctx context.Context, session *sessions
 			return nil, services.NewUnauthenticatedServiceError(err)
 		}
 
-		err = AuthorizeBusinessAction(user, BUSINESS_ACTION_CREATE, nil, nil)
+		err = authorizeBusinessAction(session, user, BUSINESS_ACTION_CREATE, nil, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -96,59 +102,93 @@ func (h *BusinessHandler) RequestBusiness(ctx context.Context, session *sessions
 	return business, nil
 }
 
-func (h *BusinessHandler) setBusinessImage(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, filename string, f io.ReadSeeker) (err error) {
-	userId := session.GetUserId()
-	if userId == nil {
-		return services.NewUnauthenticatedServiceError(nil)
-	}
+var (
+	businessLogoLimits = images.Limits{MinWidth: 64, MinHeight: 64, MaxWidth: 4096, MaxHeight: 4096}
+	businessLogoThumb  = images.Variant{Name: "thumb", MaxWidth: 128, MaxHeight: 128}
+	businessLogoCard   = images.Variant{Name: "card", MaxWidth: 480, MaxHeight: 480}
+	businessLogoFull   = images.Variant{Name: "full", MaxWidth: 1024, MaxHeight: 1024}
+)
 
-	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
-		user, err := pq.GetUserForId(ctx, userId)
+// Re-encodes the uploaded logo into its variants and replaces the business's previous logo
+func (h *BusinessHandler) setBusinessImage(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, f io.Reader) error {
+	authorize := func(pq *db.PgxQueries) error {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
 		if err != nil {
-			if errors.Is(err, db.ErrNoRows) {
-				return services.NewNotFoundServiceError(err)
-			}
 			return err
 		}
+		return authorizeBusinessAction(session, user, BUSINESS_ACTION_UPDATE, business, nil)
+	}
+	// Rejects requests that may not change the logo before any work is done for them
+	if err := db.WithTx(ctx, h.store, authorize); err != nil {
+		return err
+	}
 
-		business, err := pq.GetBusinessForId(ctx, businessId)
-		if err != nil {
-			if errors.Is(err, db.ErrNoRows) {
-				return services.NewNotFoundServiceError(err)
+	// Decoding, encoding and uploading are the slow part, so they are done outside of a transaction
+	encoded, err := images.Process(f, businessLogoLimits, []images.Variant{businessLogoThumb, businessLogoCard, businessLogoFull})
+	if err != nil {
+		if errors.Is(err, images.ErrUnsupportedFormat) || errors.Is(err, images.ErrInvalidDimensions) {
+			return services.NewBadRequestServiceError(err)
+		}
+		return err
+	}
+
+	// Every upload gets new keys so cached copies of the previous logo are never served
+	version := time.Now().UnixMilli()
+	variants := models.BusinessLogoVariants{}
+	urls := map[string]*string{
+		businessLogoThumb.Name: &variants.Thumb,
+		businessLogoCard.Name:  &variants.Card,
+		businessLogoFull.Name:  &variants.Full,
+	}
+	var keys []string
+	deleteNewKeys := func() {
+		for _, key := range keys {
+			if err := h.filestore.DeleteObject(key); err != nil {
+				h.logger.Warn("Failed to delete unused business logo", "err", err, "key", key)
 			}
-			return err
 		}
-		err = AuthorizeBusinessAction(user, BUSINESS_ACTION_UPDATE, business, nil)
-		if err != nil {
+	}
+	for _, image := range encoded {
+		key := fmt.Sprintf("logos/%v/%v-%v%v", businessId, version, image.Variant.Name, image.Ext)
+		if err := h.filestore.UploadObject(key, bytes.NewReader(image.Data)); err != nil {
+			h.logger.Warn("Failed to upload image for business", "err", err, "variant", image.Variant.Name)
+			deleteNewKeys()
 			return err
 		}
-		ext := filepath.Ext(filename)
-		key := fmt.Sprintf("%v%v", businessId.String(), ext)
-		prevKey := ""
-		if business.LogoUrl != nil {
-			prevKey = h.filestore.GetKey(*business.LogoUrl)
-		}
-		url := h.filestore.GetURI(key)
-		err = pq.SetBusinessLogo(ctx, businessId, url)
-		if err != nil {
-			return err
+		keys = append(keys, key)
+		*urls[image.Variant.Name] = h.filestore.GetURI(key)
+	}
+
+	prevUrls, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]string, error) {
+		// Membership may have changed while the logo was processed
+		if err := authorize(pq); err != nil {
+			return nil, err
 		}
-		err = h.filestore.UploadObject(key, f)
+		business, err := pq.GetBusinessForIdForUpdate(ctx, businessId)
 		if err != nil {
-			h.logger.Warn("Failed to upload image for business", "err", err)
-			return err
+			return nil, err
 		}
 
-		if prevKey != key && prevKey != "" {
-			err = h.filestore.DeleteObject(prevKey)
-			if err != nil {
-				h.logger.Warn("Failed to delete old business logo", "err", err)
-				return err
-			}
+		var prevUrls []string
+		if business.LogoVariants != nil {
+			prevUrls = business.LogoVariants.URLs()
+		} else if business.LogoUrl != nil {
+			prevUrls = []string{*business.LogoUrl}
 		}
-
-		return nil
+		return prevUrls, pq.SetBusinessLogo(ctx, businessId, &variants)
 	})
+	if err != nil {
+		deleteNewKeys()
+		return err
+	}
+
+	// The new logo is in place, so a leftover previous logo is not worth failing over
+	for _, url := range prevUrls {
+		if err := h.filestore.DeleteObject(h.filestore.GetKey(url)); err != nil {
+			h.logger.Warn("Failed to delete old business logo", "err", err, "business_id", businessId)
+		}
+	}
+	return nil
 }
 
 func (h *BusinessHandler) sendBusinessRequestedNotifications(b *models.Business) error {
@@ -162,11 +202,18 @@ func (h *BusinessHandler) sendBusinessRequestedNotifications(b *models.Business)
 			return err
 		}
 
+		// Moderators can approve businesses too, so they are notified alongside admins
 		status := models.USER_STATUS_ACTIVE
-		role := models.USER_ROLE_ADMIN
-		admins, err = pq.QueryUsers(context.Background(), &models.UserQueryParams{Status: &status, Role: &role})
-		if err != nil {
-			return err
+		for _, role := range []models.UserRole{models.USER_ROLE_ADMIN, models.USER_ROLE_MODERATOR} {
+			users, err := pq.QueryUsers(context.Background(), &models.UserQueryParams{Status: &status, Role: &role})
+			if err != nil {
+				return err
+			}
+			for _, u := range users {
+				if !slices.ContainsFunc(admins, func(a models.User) bool { return a.Id == u.Id }) {
+					admins = append(admins, u)
+				}
+			}
 		}
 		return nil
 	})
@@ -176,6 +223,9 @@ func (h *BusinessHandler) sendBusinessRequestedNotifications(b *models.Business)
 	}
 
 	for _, admin := range admins {
+		if !admin.CanBeNotified() {
+			continue
+		}
 		err := h.notifications.Enqueue(context.Background(), h.NewBusinessRequestedAdminNotification(&admin, owner, b))
 		if err != nil {
 			h.logger.Warn("Failed to enqueue business requested admin notification", "err", err)
@@ -200,7 +250,7 @@ func (h *BusinessHandler) GetBusinesses(ctx context.Context, session *sessions.S
 		if err != nil {
 			return nil, services.NewUnauthenticatedServiceError(err)
 		}
-		if err := AuthorizeBusinessAction(user, BUSINESS_ACTION_READ, nil, params); err != nil {
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_READ, nil, params); err != nil {
 			return nil, err
 		}
 		return pq.GetBusinesses(ctx, params)
@@ -225,7 +275,7 @@ func (h *BusinessHandler) GetBusinessForId(ctx context.Context, session *session
 			}
 			return nil, err
 		}
-		if err := AuthorizeBusinessAction(user, BUSINESS_ACTION_READ, business, nil); err != nil {
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_READ, business, nil); err != nil {
 			return nil, err
 		}
 		return business, nil
@@ -255,7 +305,7 @@ func (h *BusinessHandler) UpdateBusiness(ctx context.Context, session *sessions.
 			}
 			return err
 		}
-		if err := AuthorizeBusinessAction(user, BUSINESS_ACTION_UPDATE, business, nil); err != nil {
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_UPDATE, business, nil); err != nil {
 			return err
 		}
 		err = pq.UpdateBusiness(ctx, businessId, data)
@@ -266,44 +316,183 @@ func (h *BusinessHandler) UpdateBusiness(ctx context.Context, session *sessions.
 			return err
 		}
 
+		// Ownership of the previous domain says nothing about the new one
+		prevDomain, prevErr := business.Domain()
+		domain, err := data.Domain()
+		if prevErr != nil || err != nil || prevDomain != domain {
+			return pq.ClearDomainVerification(ctx, businessId)
+		}
 		return nil
 	})
 
 }
 
+type businessStatusTransition struct {
+	action BusinessAction
+	from   []models.BusinessStatus
+	to     models.BusinessStatus
+	// Name of the template the owner is notified with
+	templateName string
+	subject      string
+	// The business must have a completed review before the transition
+	requireReview bool
+}
+
+var (
+	// Rejected businesses can be approved as well, disabled businesses only come back through reinstatement
+	businessApproval = businessStatusTransition{
+		action:        BUSINESS_ACTION_APPROVE,
+		from:          []models.BusinessStatus{models.BUSINESS_STATUS_PENDING, models.BUSINESS_STATUS_REJECTED},
+		to:            models.BUSINESS_STATUS_ACTIVE,
+		templateName:  "BusinessApproved",
+		subject:       "Business Approved",
+		requireReview: true,
+	}
+	businessRejection = businessStatusTransition{
+		action:       BUSINESS_ACTION_MODERATE,
+		from:         []models.BusinessStatus{models.BUSINESS_STATUS_PENDING},
+		to:           models.BUSINESS_STATUS_REJECTED,
+		templateName: "BusinessRejected",
+		subject:      "Business Rejected",
+	}
+	// Also disables the business's active posts, reinstating does not re-activate them
+	businessSuspension = businessStatusTransition{
+		action:       BUSINESS_ACTION_MODERATE,
+		from:         []models.BusinessStatus{models.BUSINESS_STATUS_ACTIVE},
+		to:           models.BUSINESS_STATUS_DISABLED,
+		templateName: "BusinessSuspended",
+		subject:      "Business Suspended",
+	}
+	businessReinstatement = businessStatusTransition{
+		action:       BUSINESS_ACTION_MODERATE,
+		from:         []models.BusinessStatus{models.BUSINESS_STATUS_DISABLED},
+		to:           models.BUSINESS_STATUS_ACTIVE,
+		templateName: "BusinessReinstated",
+		subject:      "Business Reinstated",
+	}
+)
+
 func (h *BusinessHandler) ApproveBusiness(ctx context.Context, session *sessions.Session, businessId *uuid.UUID) error {
+	return h.changeBusinessStatus(ctx, session, businessId, businessApproval, nil)
+}
+
+func (h *BusinessHandler) RejectBusiness(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, data *models.BusinessStatusUpdate) error {
+	if err := models.ValidateData(data); err != nil {
+		return err
+	}
+	return h.changeBusinessStatus(ctx, session, businessId, businessRejection, &data.Reason)
+}
+
+func (h *BusinessHandler) SuspendBusiness(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, data *models.BusinessStatusUpdate) error {
+	if err := models.ValidateData(data); err != nil {
+		return err
+	}
+	return h.changeBusinessStatus(ctx, session, businessId, businessSuspension, &data.Reason)
+}
+
+func (h *BusinessHandler) ReinstateBusiness(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, data *models.BusinessStatusUpdate) error {
+	if err := models.ValidateData(data); err != nil {
+		return err
+	}
+	return h.changeBusinessStatus(ctx, session, businessId, businessReinstatement, &data.Reason)
+}
+
+func (h *BusinessHandler) GetBusinessStatusHistory(ctx context.Context, session *sessions.Session, businessId *uuid.UUID) ([]models.BusinessStatusChange, error) {
 	userId := session.GetUserId()
 	if userId == nil {
-		return services.NewUnauthenticatedServiceError(nil)
+		return nil, services.NewUnauthenticatedServiceError(nil)
 	}
 
-	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.BusinessStatusChange, error) {
 		user, err := pq.GetUserForId(ctx, userId)
+		if err != nil {
+			return nil, services.NewUnauthenticatedServiceError(err)
+		}
+		business, err := pq.GetBusinessForId(ctx, businessId)
 		if err != nil {
 			if errors.Is(err, db.ErrNoRows) {
-				return services.NewNotFoundServiceError(err)
+				return nil, services.NewNotFoundServiceError(err)
 			}
-			return err
+			return nil, err
 		}
-		business, err := pq.GetBusinessForId(ctx, businessId)
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_READ_STATUS_HISTORY, business, nil); err != nil {
+			return nil, err
+		}
+		return pq.GetBusinessStatusHistory(ctx, businessId)
+	})
+}
+
+// Applies the transition if the business is in one of its allowed statuses, then notifies the owner
+func (h *BusinessHandler) changeBusinessStatus(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, transition businessStatusTransition, reason *string) error {
+	userId := session.GetUserId()
+	if userId == nil {
+		return services.NewUnauthenticatedServiceError(nil)
+	}
+
+	var business *models.Business
+	var owner *models.User
+	err := db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		user, err := pq.GetUserForId(ctx, userId)
+		if err != nil {
+			return services.NewUnauthenticatedServiceError(err)
+		}
+		business, err = pq.GetBusinessForId(ctx, businessId)
 		if err != nil {
 			if errors.Is(err, db.ErrNoRows) {
 				return services.NewNotFoundServiceError(err)
 			}
 			return err
 		}
-		if err := AuthorizeBusinessAction(user, BUSINESS_ACTION_APPROVE, business, nil); err != nil {
+		if err := authorizeBusinessAction(session, user, transition.action, business, nil); err != nil {
 			return err
 		}
-		err = pq.SetBusinessStatus(ctx, businessId, models.BUSINESS_STATUS_ACTIVE)
+		if !slices.Contains(transition.from, business.Status) {
+			return services.NewDataConflictServiceError(nil, fmt.Sprintf("Business can not be changed from %v to %v", business.Status, transition.to))
+		}
+		owner, err = pq.GetUserForId(ctx, &business.UserId)
 		if err != nil {
+			return err
+		}
+		if transition.to == models.BUSINESS_STATUS_ACTIVE && owner.DeletedAt != nil {
+			return services.NewDataConflictServiceError(nil, "Business owner's account has been deleted")
+		}
+		if transition.requireReview {
+			review, err := pq.GetBusinessReview(ctx, businessId)
+			if err != nil {
+				return err
+			}
+			if !review.IsComplete() {
+				return services.NewDataConflictServiceError(nil, "Business review must be completed before approval")
+			}
+		}
+
+		if err := pq.SetBusinessStatus(ctx, businessId, transition.to, reason, userId); err != nil {
 			if errors.Is(err, db.ErrNoRows) {
 				return services.NewNotFoundServiceError(err)
 			}
 			return err
 		}
+		if transition.to == models.BUSINESS_STATUS_DISABLED {
+			if err := pq.DisableActivePostsForBusiness(ctx, businessId); err != nil {
+				return err
+			}
+		}
+		business.Status = transition.to
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	h.logger.Info("Changed business status", "business_id", businessId, "status", transition.to, "admin_id", userId)
+	if !owner.CanBeNotified() {
+		return nil
+	}
+	err = h.notifications.EnqueueWithTimeout(ctx, h.newBusinessStatusNotification(owner, business, reason, transition))
+	if err != nil {
+		h.logger.Warn("Failed to enqueue business status notification", "err", err, "business_id", businessId)
+	}
+	return nil
 }
 
 type BusinessAction string
@@ -313,8 +502,55 @@ const (
 	BUSINESS_ACTION_UPDATE  BusinessAction = "business:update"
 	BUSINESS_ACTION_APPROVE BusinessAction = "business:approve"
 	BUSINESS_ACTION_READ    BusinessAction = "business:read"
+	// List members and invitations
+	BUSINESS_ACTION_READ_MEMBERS BusinessAction = "business:read_members"
+	// Invite, change and remove members
+	BUSINESS_ACTION_MANAGE_MEMBERS BusinessAction = "business:manage_members"
+	// Offer ownership to another user and view past transfers
+	BUSINESS_ACTION_TRANSFER BusinessAction = "business:transfer"
+	// Move ownership without the recipient's acceptance
+	BUSINESS_ACTION_FORCE_TRANSFER BusinessAction = "business:force_transfer"
+	// Reject, suspend and reinstate businesses
+	BUSINESS_ACTION_MODERATE            BusinessAction = "business:moderate"
+	BUSINESS_ACTION_READ_STATUS_HISTORY BusinessAction = "business:read_status_history"
+	// View verification documents with their previews
+	BUSINESS_ACTION_READ_DOCUMENTS BusinessAction = "business:read_documents"
+	// Upload and delete verification documents
+	BUSINESS_ACTION_MANAGE_DOCUMENTS BusinessAction = "business:manage_documents"
+	// Work through the review queue and fill in review checklists
+	BUSINESS_ACTION_REVIEW BusinessAction = "business:review"
+	// File a report against a business
+	BUSINESS_ACTION_REPORT BusinessAction = "business:report"
+	// List and close reports against businesses and their posts
+	BUSINESS_ACTION_REVIEW_REPORTS BusinessAction = "business:review_reports"
 )
 
+var businessActionScopes = map[BusinessAction]models.TokenScope{
+	BUSINESS_ACTION_CREATE:              models.TOKEN_SCOPE_BUSINESSES_WRITE,
+	BUSINESS_ACTION_UPDATE:              models.TOKEN_SCOPE_BUSINESSES_WRITE,
+	BUSINESS_ACTION_APPROVE:             models.TOKEN_SCOPE_BUSINESSES_WRITE,
+	BUSINESS_ACTION_READ:                models.TOKEN_SCOPE_BUSINESSES_READ,
+	BUSINESS_ACTION_READ_MEMBERS:        models.TOKEN_SCOPE_BUSINESSES_READ,
+	BUSINESS_ACTION_MANAGE_MEMBERS:      models.TOKEN_SCOPE_BUSINESSES_WRITE,
+	BUSINESS_ACTION_TRANSFER:            models.TOKEN_SCOPE_BUSINESSES_WRITE,
+	BUSINESS_ACTION_FORCE_TRANSFER:      models.TOKEN_SCOPE_BUSINESSES_WRITE,
+	BUSINESS_ACTION_MODERATE:            models.TOKEN_SCOPE_BUSINESSES_WRITE,
+	BUSINESS_ACTION_READ_STATUS_HISTORY: models.TOKEN_SCOPE_BUSINESSES_READ,
+	BUSINESS_ACTION_READ_DOCUMENTS:      models.TOKEN_SCOPE_BUSINESSES_READ,
+	BUSINESS_ACTION_MANAGE_DOCUMENTS:    models.TOKEN_SCOPE_BUSINESSES_WRITE,
+	BUSINESS_ACTION_REVIEW:              models.TOKEN_SCOPE_BUSINESSES_WRITE,
+	BUSINESS_ACTION_REPORT:              models.TOKEN_SCOPE_BUSINESSES_WRITE,
+	BUSINESS_ACTION_REVIEW_REPORTS:      models.TOKEN_SCOPE_BUSINESSES_WRITE,
+}
+
+// Checks the scope required when the session is backed by an API token before authorizing the action
+func authorizeBusinessAction(session *sessions.Session, user *models.User, action BusinessAction, data *models.Business, query *models.BusinessQueryParams) error {
+	if err := session.RequireScope(businessActionScopes[action]); err != nil {
+		return err
+	}
+	return AuthorizeBusinessAction(user, action, data, query)
+}
+
 func AuthorizeBusinessAction(user *models.User, action BusinessAction, data *models.Business, query *models.BusinessQueryParams) error {
 	if user == nil {
 		return services.NewUnauthenticatedServiceError(nil)
@@ -332,20 +568,90 @@ func AuthorizeBusinessAction(user *models.User, action BusinessAction, data *mod
 				return nil
 			case BUSINESS_ACTION_READ:
 				return nil
+			case BUSINESS_ACTION_READ_MEMBERS:
+				return nil
+			case BUSINESS_ACTION_MANAGE_MEMBERS:
+				return nil
+			case BUSINESS_ACTION_TRANSFER:
+				return nil
+			case BUSINESS_ACTION_FORCE_TRANSFER:
+				return nil
+			case BUSINESS_ACTION_MODERATE:
+				return nil
+			case BUSINESS_ACTION_READ_STATUS_HISTORY:
+				return nil
+			case BUSINESS_ACTION_READ_DOCUMENTS:
+				return nil
+			case BUSINESS_ACTION_MANAGE_DOCUMENTS:
+				return nil
+			case BUSINESS_ACTION_REVIEW:
+				return nil
+			case BUSINESS_ACTION_REPORT:
+				return nil
+			case BUSINESS_ACTION_REVIEW_REPORTS:
+				return nil
+			}
+		case models.USER_ROLE_MODERATOR:
+			switch action {
+			case BUSINESS_ACTION_APPROVE:
+				return nil
+			case BUSINESS_ACTION_READ:
+				return nil
+			case BUSINESS_ACTION_READ_MEMBERS:
+				return nil
+			case BUSINESS_ACTION_MODERATE:
+				return nil
+			case BUSINESS_ACTION_READ_STATUS_HISTORY:
+				return nil
+			case BUSINESS_ACTION_READ_DOCUMENTS:
+				return nil
+			case BUSINESS_ACTION_REVIEW:
+				return nil
+			case BUSINESS_ACTION_REPORT:
+				return nil
+			case BUSINESS_ACTION_REVIEW_REPORTS:
+				return nil
 			}
 		case models.USER_ROLE_USER:
 			switch action {
 			case BUSINESS_ACTION_CREATE:
 				return nil
 			case BUSINESS_ACTION_UPDATE:
-				if data != nil && data.UserId == user.Id {
+				if data != nil && data.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_UPDATE) {
 					return nil
 				}
 			case BUSINESS_ACTION_READ:
 				if (query != nil &&
 					((query.UserId != nil && *query.UserId == user.Id) ||
 						(query.Status != nil && *query.Status == models.BUSINESS_STATUS_ACTIVE))) ||
-					(data != nil && data.Status == models.BUSINESS_STATUS_ACTIVE) {
+					(data != nil && (data.Status == models.BUSINESS_STATUS_ACTIVE ||
+						data.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_VIEW))) {
+					return nil
+				}
+			case BUSINESS_ACTION_READ_MEMBERS:
+				if data != nil && data.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_VIEW) {
+					return nil
+				}
+			case BUSINESS_ACTION_MANAGE_MEMBERS:
+				if data != nil && data.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_MANAGE_MEMBERS) {
+					return nil
+				}
+			case BUSINESS_ACTION_READ_STATUS_HISTORY:
+				if data != nil && data.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_VIEW) {
+					return nil
+				}
+			case BUSINESS_ACTION_READ_DOCUMENTS, BUSINESS_ACTION_MANAGE_DOCUMENTS:
+				if data != nil && data.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_UPDATE) {
+					return nil
+				}
+			case BUSINESS_ACTION_TRANSFER:
+				if data != nil {
+					if role, ok := data.MemberRole(user.Id); ok && role == models.BUSINESS_MEMBER_ROLE_OWNER {
+						return nil
+					}
+				}
+			case BUSINESS_ACTION_REPORT:
+				if data != nil && data.Status == models.BUSINESS_STATUS_ACTIVE {
 					return nil
 				}
 			}
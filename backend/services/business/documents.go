@@ -0,0 +1,263 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+const (
+	maxBusinessDocuments = 20
+	// Preview links are handed to reviewers, so they only last long enough to open the documents
+	documentPreviewTTL = time.Minute * 15
+)
+
+var businessDocumentTypes = []string{"application/pdf", "image/png", "image/jpeg"}
+
+// Documents are stored in their own private bucket and are only readable through presigned links
+func businessDocumentKey(document *models.BusinessDocument) string {
+	return fmt.Sprintf("documents/%v/%v%v", document.BusinessId, document.Id, strings.ToLower(filepath.Ext(document.Filename)))
+}
+
+func (h *BusinessHandler) GetBusinessDocuments(ctx context.Context, session *sessions.Session, businessId *uuid.UUID) ([]models.BusinessDocument, error) {
+	documents, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.BusinessDocument, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_READ_DOCUMENTS, business, nil); err != nil {
+			return nil, err
+		}
+		return pq.GetBusinessDocuments(ctx, businessId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.setDocumentPreviews(documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// Stores verification evidence for the business, reopening any completed review
+func (h *BusinessHandler) UploadBusinessDocument(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, data *models.BusinessDocumentCreate, filename string, contentType string, size int64, f io.ReadSeeker) (*models.BusinessDocument, error) {
+	if err := models.ValidateData(data); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(businessDocumentTypes, contentType) {
+		return nil, services.NewBadRequestServiceError(fmt.Errorf("Invalid document type: %v", contentType))
+	}
+
+	filename = filepath.Base(filename)
+	if len(filename) > 255 {
+		filename = filename[len(filename)-255:]
+	}
+
+	var uploadedKey string
+	document, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.BusinessDocument, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_MANAGE_DOCUMENTS, business, nil); err != nil {
+			return nil, err
+		}
+
+		documents, err := pq.GetBusinessDocuments(ctx, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if len(documents) >= maxBusinessDocuments {
+			return nil, services.NewDataConflictServiceError(nil, fmt.Sprintf("A business can have at most %v documents", maxBusinessDocuments))
+		}
+
+		document, err := pq.CreateBusinessDocument(ctx, businessId, &user.Id, data.Kind, filename, contentType, size)
+		if err != nil {
+			return nil, err
+		}
+		if err := pq.ResetBusinessReview(ctx, businessId); err != nil {
+			return nil, err
+		}
+		key := businessDocumentKey(document)
+		if err := h.documents.UploadObject(key, f); err != nil {
+			h.logger.Warn("Failed to upload business document", "err", err, "business_id", businessId)
+			return nil, err
+		}
+		uploadedKey = key
+		return document, nil
+	})
+	if err != nil {
+		// The document row was rolled back, so the object would never be listed or removed
+		if uploadedKey != "" {
+			if err := h.documents.DeleteObject(uploadedKey); err != nil {
+				h.logger.Warn("Failed to delete orphaned business document", "err", err, "key", uploadedKey)
+			}
+		}
+		return nil, err
+	}
+
+	h.logger.Info("Uploaded business document", "business_id", businessId, "document_id", document.Id, "kind", document.Kind)
+	return document, nil
+}
+
+func (h *BusinessHandler) DeleteBusinessDocument(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, documentId *uuid.UUID) error {
+
+	document, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.BusinessDocument, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_MANAGE_DOCUMENTS, business, nil); err != nil {
+			return nil, err
+		}
+
+		document, err := pq.DeleteBusinessDocument(ctx, businessId, documentId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return nil, services.NewNotFoundServiceError(err)
+			}
+			return nil, err
+		}
+		if err := pq.ResetBusinessReview(ctx, businessId); err != nil {
+			return nil, err
+		}
+		return document, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Deleted only once the row is gone, a failure leaves an unreferenced object rather than a broken document
+	if err := h.documents.DeleteObject(businessDocumentKey(document)); err != nil {
+		h.logger.Warn("Failed to delete business document", "err", err, "document_id", documentId)
+	}
+	return nil
+}
+
+// Pending businesses along with their documents and any review in progress
+func (h *BusinessHandler) GetBusinessReviewQueue(ctx context.Context, session *sessions.Session) ([]models.BusinessReviewQueueItem, error) {
+	userId := session.GetUserId()
+	if userId == nil {
+		return nil, services.NewUnauthenticatedServiceError(nil)
+	}
+
+	queue, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.BusinessReviewQueueItem, error) {
+		user, err := pq.GetUserForId(ctx, userId)
+		if err != nil {
+			return nil, services.NewUnauthenticatedServiceError(err)
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_REVIEW, nil, nil); err != nil {
+			return nil, err
+		}
+
+		status := models.BUSINESS_STATUS_PENDING
+		businesses, err := pq.GetBusinesses(ctx, &models.BusinessQueryParams{Status: &status})
+		if err != nil {
+			return nil, err
+		}
+
+		queue := make([]models.BusinessReviewQueueItem, 0, len(businesses))
+		for _, business := range businesses {
+			item, err := getBusinessReviewItem(ctx, pq, &business)
+			if err != nil {
+				return nil, err
+			}
+			queue = append(queue, *item)
+		}
+		return queue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range queue {
+		if err := h.setDocumentPreviews(queue[i].Documents); err != nil {
+			return nil, err
+		}
+	}
+	return queue, nil
+}
+
+func (h *BusinessHandler) GetBusinessReview(ctx context.Context, session *sessions.Session, businessId *uuid.UUID) (*models.BusinessReviewQueueItem, error) {
+	item, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.BusinessReviewQueueItem, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_REVIEW, business, nil); err != nil {
+			return nil, err
+		}
+		return getBusinessReviewItem(ctx, pq, business)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.setDocumentPreviews(item.Documents); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Records the reviewer's checklist and notes, the business can be approved once the checklist is complete
+func (h *BusinessHandler) ReviewBusiness(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, data *models.BusinessReviewUpdate) (*models.BusinessReview, error) {
+	if err := models.ValidateData(data); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.BusinessReview, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_REVIEW, business, nil); err != nil {
+			return nil, err
+		}
+		if business.Status == models.BUSINESS_STATUS_ACTIVE {
+			return nil, services.NewDataConflictServiceError(nil, "Business is already active")
+		}
+
+		h.logger.Info("Reviewing business", "business_id", businessId, "reviewer_id", user.Id, "complete", data.Checklist.IsComplete())
+		return pq.SaveBusinessReview(ctx, businessId, &user.Id, data)
+	})
+}
+
+func getBusinessReviewItem(ctx context.Context, pq *db.PgxQueries, business *models.Business) (*models.BusinessReviewQueueItem, error) {
+	documents, err := pq.GetBusinessDocuments(ctx, &business.Id)
+	if err != nil {
+		return nil, err
+	}
+	review, err := pq.GetBusinessReview(ctx, &business.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BusinessReviewQueueItem{
+		Business:  *business,
+		Documents: documents,
+		Review:    review,
+	}, nil
+}
+
+func (h *BusinessHandler) setDocumentPreviews(documents []models.BusinessDocument) error {
+	for i := range documents {
+		uri, err := h.documents.GetPresignedURI(businessDocumentKey(&documents[i]), documentPreviewTTL)
+		if err != nil {
+			h.logger.Warn("Failed to presign business document", "err", err, "document_id", documents[i].Id)
+			return err
+		}
+		documents[i].PreviewURL = uri
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+	"github.com/john-vh/college_testing/backend/util"
+)
+
+const (
+	// How long the background checker looks for a token before a new one has to be requested
+	domainVerificationTTL           = time.Hour * 24 * 7
+	domainVerificationRetryInterval = time.Minute * 5
+	domainVerificationBatchSize     = 50
+	domainVerificationCheckTimeout  = time.Second * 15
+
+	domainVerificationPath        = "/.well-known/testhive-verification.txt"
+	domainVerificationRecord      = "_testhive-verification"
+	domainVerificationValuePrefix = "testhive-verification="
+)
+
+// Starts verification of the domain of the business's website. The token has to be published at
+// the well-known path of the website or in a TXT record, the background checker picks it up.
+func (h *BusinessHandler) RequestDomainVerification(ctx context.Context, session *sessions.Session, businessId *uuid.UUID) (*models.DomainVerification, error) {
+	verification, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.DomainVerification, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_UPDATE, business, nil); err != nil {
+			return nil, err
+		}
+		if business.DomainVerified {
+			return nil, services.NewDataConflictServiceError(nil, "Domain is already verified")
+		}
+
+		domain, err := business.Domain()
+		if err != nil {
+			return nil, services.NewValidationServiceError(err, services.ValidationErrMap{
+				"Website": services.ValidationErrData{Tag: "domain", Value: business.Website},
+			})
+		}
+		token, err := util.RandString(24)
+		if err != nil {
+			return nil, services.NewInternalServiceError(err)
+		}
+		return pq.CreateDomainVerification(ctx, businessId, domain, token, time.Now().Add(domainVerificationTTL))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h.logger.Info("Requested domain verification", "business_id", businessId, "domain", verification.Domain)
+	setDomainVerificationInstructions(verification)
+	return verification, nil
+}
+
+func (h *BusinessHandler) GetDomainVerification(ctx context.Context, session *sessions.Session, businessId *uuid.UUID) (*models.DomainVerification, error) {
+	verification, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.DomainVerification, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_UPDATE, business, nil); err != nil {
+			return nil, err
+		}
+
+		verification, err := pq.GetDomainVerification(ctx, businessId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return nil, services.NewNotFoundServiceError(err)
+			}
+			return nil, err
+		}
+		return verification, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	setDomainVerificationInstructions(verification)
+	return verification, nil
+}
+
+func setDomainVerificationInstructions(verification *models.DomainVerification) {
+	verification.HTTPURL = "https://" + verification.Domain + domainVerificationPath
+	verification.DNSName = domainVerificationRecord + "." + verification.Domain
+	verification.DNSValue = domainVerificationValuePrefix + verification.Token
+}
+
+// Retrieves the content published at a URL
+type DomainFetcher interface {
+	Fetch(ctx context.Context, url string) ([]byte, error)
+}
+
+// Looks up TXT records, satisfied by *net.Resolver
+type DomainResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Fetches over HTTPS from public addresses only, following redirects within the requested domain
+type HTTPDomainFetcher struct {
+	client  *http.Client
+	maxSize int64
+}
+
+// Ranges that are not covered by the net.IP checks: "this network", which reaches the local host on
+// most systems, the carrier-grade NAT shared address space, and NAT64, which embeds any IPv4 address
+var blockedNetworks = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+	{IP: net.ParseIP("64:ff9b::"), Mask: net.CIDRMask(96, 128)},
+}
+
+// Business websites are user input, so internal services must not be reachable through them
+func refuseInternalAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("Refusing to connect to %v", address)
+	}
+	for _, blocked := range blockedNetworks {
+		if blocked.Contains(ip) {
+			return fmt.Errorf("Refusing to connect to %v", address)
+		}
+	}
+	return nil
+}
+
+func NewHTTPDomainFetcher(timeout time.Duration) *HTTPDomainFetcher {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: refuseInternalAddress,
+	}
+
+	return &HTTPDomainFetcher{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: timeout,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 3 {
+					return errors.New("Too many redirects")
+				}
+				domain := via[0].URL.Hostname()
+				if host := req.URL.Hostname(); req.URL.Scheme != "https" || (host != domain && !strings.HasSuffix(host, "."+domain)) {
+					return fmt.Errorf("Redirect to %v leaves the domain", req.URL.Redacted())
+				}
+				return nil
+			},
+		},
+		maxSize: 1024,
+	}
+}
+
+func (f *HTTPDomainFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status %v", res.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, f.maxSize))
+}
+
+// Periodically checks pending domain verifications, marking businesses as verified once their token is found
+type DomainVerificationService struct {
+	logger   *slog.Logger
+	store    *db.PgxStore
+	fetcher  DomainFetcher
+	resolver DomainResolver
+	interval time.Duration
+	done     chan struct{}
+}
+
+func NewDomainVerificationService(logger *slog.Logger, store *db.PgxStore, fetcher DomainFetcher, resolver DomainResolver, interval time.Duration) *DomainVerificationService {
+	return &DomainVerificationService{
+		logger:   logger,
+		store:    store,
+		fetcher:  fetcher,
+		resolver: resolver,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Background service interface implementations
+func (s *DomainVerificationService) Start() {
+	go s.run()
+}
+
+func (s *DomainVerificationService) Stop() {
+	close(s.done)
+}
+
+func (s *DomainVerificationService) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.checkDueVerifications(context.Background())
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *DomainVerificationService) checkDueVerifications(ctx context.Context) {
+	verifications, err := db.WithTxRet(ctx, s.store, func(pq *db.PgxQueries) ([]models.DomainVerification, error) {
+		return pq.GetDomainVerificationsDue(ctx, time.Now().Add(-domainVerificationRetryInterval), domainVerificationBatchSize)
+	})
+	if err != nil {
+		s.logger.Warn("Failed to query due domain verifications", "err", err)
+		return
+	}
+
+	for _, verification := range verifications {
+		if err := s.checkVerification(ctx, &verification); err != nil {
+			s.logger.Warn("Failed to record domain verification check", "business_id", verification.BusinessId, "err", err)
+		}
+	}
+}
+
+func (s *DomainVerificationService) checkVerification(ctx context.Context, verification *models.DomainVerification) error {
+	checkCtx, cancel := context.WithTimeout(ctx, domainVerificationCheckTimeout)
+	method, checkErr := s.Verify(checkCtx, verification)
+	cancel()
+
+	return db.WithTx(ctx, s.store, func(pq *db.PgxQueries) error {
+		if checkErr != nil {
+			s.logger.Debug("Domain verification check failed", "business_id", verification.BusinessId, "domain", verification.Domain, "err", checkErr)
+			return pq.RecordDomainVerificationCheck(ctx, &verification.BusinessId, verification.Token, checkErr.Error())
+		}
+
+		err := pq.CompleteDomainVerification(ctx, &verification.BusinessId, verification.Token, method)
+		if errors.Is(err, db.ErrNoRows) {
+			// A new token was requested or the website changed while checking
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.logger.Info("Verified business domain", "business_id", verification.BusinessId, "domain", verification.Domain, "method", method)
+		return nil
+	})
+}
+
+// Looks for the token at the well-known path of the domain, then in its TXT records
+func (s *DomainVerificationService) Verify(ctx context.Context, verification *models.DomainVerification) (models.DomainVerificationMethod, error) {
+	setDomainVerificationInstructions(verification)
+
+	body, httpErr := s.fetcher.Fetch(ctx, verification.HTTPURL)
+	if httpErr == nil {
+		if strings.TrimSpace(string(body)) == verification.Token {
+			return models.DOMAIN_VERIFICATION_METHOD_HTTP, nil
+		}
+		httpErr = errors.New("Token does not match")
+	}
+
+	records, dnsErr := s.resolver.LookupTXT(ctx, verification.DNSName)
+	if dnsErr == nil {
+		for _, record := range records {
+			if strings.TrimSpace(record) == verification.DNSValue {
+				return models.DOMAIN_VERIFICATION_METHOD_DNS, nil
+			}
+		}
+		dnsErr = errors.New("No matching TXT record")
+	}
+
+	return "", errors.Join(fmt.Errorf("HTTP: %w", httpErr), fmt.Errorf("DNS: %w", dnsErr))
+}
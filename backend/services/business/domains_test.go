@@ -0,0 +1,140 @@
+package business
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/john-vh/college_testing/backend/models"
+)
+
+type testResolver struct {
+	records map[string][]string
+}
+
+func (r *testResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := r.records[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+// Sends every request of the fetcher to the test server, whatever domain it is for
+func newTestDomainFetcher(t *testing.T, handler http.Handler) *HTTPDomainFetcher {
+	t.Helper()
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+
+	fetcher := NewHTTPDomainFetcher(time.Second)
+	fetcher.client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	return fetcher
+}
+
+func TestDomainVerificationVerify(t *testing.T) {
+	const token = "verification-token"
+	tests := []struct {
+		name    string
+		body    string
+		records []string
+		want    models.DomainVerificationMethod
+	}{
+		{"token published", token + "\n", nil, models.DOMAIN_VERIFICATION_METHOD_HTTP},
+		{"token mismatch", "another-token", nil, ""},
+		{"txt record", "another-token", []string{"unrelated", domainVerificationValuePrefix + token}, models.DOMAIN_VERIFICATION_METHOD_DNS},
+		{"txt mismatch", "", []string{domainVerificationValuePrefix + "another-token"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET example.com"+domainVerificationPath, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			})
+			s := &DomainVerificationService{
+				fetcher:  newTestDomainFetcher(t, mux),
+				resolver: &testResolver{records: map[string][]string{domainVerificationRecord + ".example.com": tt.records}},
+			}
+
+			method, err := s.Verify(context.Background(), &models.DomainVerification{Domain: "example.com", Token: token})
+			if method != tt.want {
+				t.Fatalf("got method %q (%v), want %q", method, err, tt.want)
+			}
+			if (err == nil) != (tt.want != "") {
+				t.Fatalf("got err %v for method %q", err, method)
+			}
+		})
+	}
+}
+
+func TestHTTPDomainFetcherRefusesInternalAddresses(t *testing.T) {
+	for _, address := range []string{
+		"127.0.0.1:443",
+		"[::1]:443",
+		"10.0.0.1:443",
+		"192.168.1.1:443",
+		"169.254.169.254:443",
+		"100.64.0.1:443",
+		"0.1.2.3:443",
+		"[64:ff9b::a00:1]:443",
+		"[::ffff:127.0.0.1]:443",
+	} {
+		if err := refuseInternalAddress("tcp", address, nil); err == nil {
+			t.Errorf("%v was not refused", address)
+		}
+	}
+	for _, address := range []string{"93.184.215.14:443", "[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443"} {
+		if err := refuseInternalAddress("tcp", address, nil); err != nil {
+			t.Errorf("%v was refused: %v", address, err)
+		}
+	}
+
+	// The guard is applied when dialing, so it also covers names that resolve to internal addresses
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer srv.Close()
+	if _, err := NewHTTPDomainFetcher(time.Second).Fetch(context.Background(), srv.URL); err == nil || !strings.Contains(err.Error(), "Refusing to connect") {
+		t.Fatalf("fetching a loopback server got %v, want it refused", err)
+	}
+}
+
+func TestHTTPDomainFetcherRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET example.com/inside", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://www.example.com/token", http.StatusFound)
+	})
+	mux.HandleFunc("GET example.com/outside", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://attacker.test/token", http.StatusFound)
+	})
+	mux.HandleFunc("GET example.com/suffix", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://notexample.com/token", http.StatusFound)
+	})
+	mux.HandleFunc("GET example.com/downgrade", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/token", http.StatusFound)
+	})
+	mux.HandleFunc("GET /token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("token"))
+	})
+	fetcher := newTestDomainFetcher(t, mux)
+	ctx := context.Background()
+
+	if body, err := fetcher.Fetch(ctx, "https://example.com/inside"); err != nil || string(body) != "token" {
+		t.Fatalf("redirect within the domain got %q (%v), want the token", body, err)
+	}
+	for _, path := range []string{"/outside", "/suffix", "/downgrade"} {
+		_, err := fetcher.Fetch(ctx, "https://example.com"+path)
+		if err == nil || !strings.Contains(err.Error(), "leaves the domain") {
+			t.Errorf("%v: got %v, want the redirect refused", path, err)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+// Owners and admins may assign any role below owner, managers may only assign reviewer and viewer
+func canAssignMemberRole(user *models.User, business *models.Business, role models.BusinessMemberRole) bool {
+	if role == models.BUSINESS_MEMBER_ROLE_OWNER {
+		return false
+	}
+	if user.HasRole(models.USER_ROLE_ADMIN) {
+		return true
+	}
+	userRole, ok := business.MemberRole(user.Id)
+	if !ok {
+		return false
+	}
+	switch userRole {
+	case models.BUSINESS_MEMBER_ROLE_OWNER:
+		return true
+	case models.BUSINESS_MEMBER_ROLE_MANAGER:
+		return role == models.BUSINESS_MEMBER_ROLE_REVIEWER || role == models.BUSINESS_MEMBER_ROLE_VIEWER
+	}
+	return false
+}
+
+func (h *BusinessHandler) getMemberContext(ctx context.Context, pq *db.PgxQueries, session *sessions.Session, businessId *uuid.UUID) (*models.User, *models.Business, error) {
+	userId := session.GetUserId()
+	if userId == nil {
+		return nil, nil, services.NewUnauthenticatedServiceError(nil)
+	}
+	user, err := pq.GetUserForId(ctx, userId)
+	if err != nil {
+		return nil, nil, services.NewUnauthenticatedServiceError(err)
+	}
+	business, err := pq.GetBusinessForId(ctx, businessId)
+	if err != nil {
+		if errors.Is(err, db.ErrNoRows) {
+			return nil, nil, services.NewNotFoundServiceError(err)
+		}
+		return nil, nil, err
+	}
+	return user, business, nil
+}
+
+func (h *BusinessHandler) GetBusinessMembers(ctx context.Context, session *sessions.Session, businessId *uuid.UUID) ([]models.BusinessMemberInfo, error) {
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.BusinessMemberInfo, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_READ_MEMBERS, business, nil); err != nil {
+			return nil, err
+		}
+		return pq.GetBusinessMembers(ctx, businessId)
+	})
+}
+
+func (h *BusinessHandler) RemoveBusinessMember(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, memberId *uuid.UUID) error {
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return err
+		}
+
+		role, ok := business.MemberRole(*memberId)
+		if !ok {
+			return services.NewNotFoundServiceError(nil)
+		}
+		if role == models.BUSINESS_MEMBER_ROLE_OWNER {
+			return services.NewDataConflictServiceError(nil, "The business owner cannot be removed")
+		}
+
+		// Members may always leave a business on their own
+		if *memberId == user.Id {
+			if err := session.RequireScope(businessActionScopes[BUSINESS_ACTION_MANAGE_MEMBERS]); err != nil {
+				return err
+			}
+		} else {
+			if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_MANAGE_MEMBERS, business, nil); err != nil {
+				return err
+			}
+			if !canAssignMemberRole(user, business, role) {
+				return services.NewUnauthorizedServiceError(nil)
+			}
+		}
+
+		err = pq.RemoveBusinessMember(ctx, businessId, memberId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func (h *BusinessHandler) GetBusinessInvitations(ctx context.Context, session *sessions.Session, businessId *uuid.UUID) ([]models.BusinessInvitation, error) {
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.BusinessInvitation, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_MANAGE_MEMBERS, business, nil); err != nil {
+			return nil, err
+		}
+		return pq.GetBusinessInvitations(ctx, businessId)
+	})
+}
+
+func (h *BusinessHandler) InviteBusinessMember(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, data *models.BusinessInvitationCreate) (*models.BusinessInvitation, error) {
+	if err := models.ValidateData(data); err != nil {
+		return nil, err
+	}
+
+	var inviter *models.User
+	var business *models.Business
+	invitation, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.BusinessInvitation, error) {
+		var err error
+		inviter, business, err = h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, inviter, BUSINESS_ACTION_MANAGE_MEMBERS, business, nil); err != nil {
+			return nil, err
+		}
+		if !canAssignMemberRole(inviter, business, data.Role) {
+			return nil, services.NewUnauthorizedServiceError(nil)
+		}
+
+		invitation, err := pq.CreateBusinessInvitation(ctx, businessId, &inviter.Id, data, time.Now().Add(invitationTTL))
+		if err != nil {
+			if errors.Is(err, db.ErrUnique) {
+				return nil, services.NewDataConflictServiceError(err, "An invitation is already pending for this email")
+			}
+			return nil, err
+		}
+		return invitation, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.notifications.Enqueue(ctx, h.newBusinessInvitationNotification(inviter, business, invitation)); err != nil {
+		h.logger.Warn("Failed to enqueue business invitation notification", "err", err)
+	}
+
+	return invitation, nil
+}
+
+func (h *BusinessHandler) RevokeBusinessInvitation(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, invitationId *uuid.UUID) error {
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_MANAGE_MEMBERS, business, nil); err != nil {
+			return err
+		}
+
+		invitation, err := pq.GetBusinessInvitation(ctx, invitationId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		if invitation.BusinessId != business.Id {
+			return services.NewNotFoundServiceError(nil)
+		}
+		if invitation.Status != models.BUSINESS_INVITATION_STATUS_PENDING {
+			return services.NewDataConflictServiceError(nil, "Invitation is no longer pending")
+		}
+
+		return pq.SetBusinessInvitationStatus(ctx, invitationId, models.BUSINESS_INVITATION_STATUS_REVOKED)
+	})
+}
+
+func (h *BusinessHandler) GetUserInvitations(ctx context.Context, session *sessions.Session) ([]models.UserBusinessInvitation, error) {
+	userId := session.GetUserId()
+	if userId == nil {
+		return nil, services.NewUnauthenticatedServiceError(nil)
+	}
+	if err := session.RequireScope(models.TOKEN_SCOPE_BUSINESSES_READ); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.UserBusinessInvitation, error) {
+		return pq.GetInvitationsForUser(ctx, userId)
+	})
+}
+
+// Accepts or declines an invitation sent to one of the user's verified emails
+func (h *BusinessHandler) RespondToInvitation(ctx context.Context, session *sessions.Session, invitationId *uuid.UUID, accept bool) error {
+	userId := session.GetUserId()
+	if userId == nil {
+		return services.NewUnauthenticatedServiceError(nil)
+	}
+	if err := session.RequireScope(models.TOKEN_SCOPE_BUSINESSES_WRITE); err != nil {
+		return err
+	}
+
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		invitation, err := pq.GetBusinessInvitation(ctx, invitationId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+
+		// Hide invitations addressed to someone else
+		ok, err := pq.HasVerifiedEmail(ctx, userId, invitation.Email)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return services.NewNotFoundServiceError(nil)
+		}
+		if !invitation.IsPending() {
+			return services.NewDataConflictServiceError(nil, "Invitation is no longer pending")
+		}
+
+		if !accept {
+			return pq.SetBusinessInvitationStatus(ctx, invitationId, models.BUSINESS_INVITATION_STATUS_DECLINED)
+		}
+
+		business, err := pq.GetBusinessForId(ctx, &invitation.BusinessId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		if _, ok := business.MemberRole(*userId); ok {
+			return services.NewDataConflictServiceError(nil, "Already a member of this business")
+		}
+
+		if _, err := pq.AddBusinessMember(ctx, &invitation.BusinessId, userId, invitation.Role); err != nil {
+			return err
+		}
+		return pq.SetBusinessInvitationStatus(ctx, invitationId, models.BUSINESS_INVITATION_STATUS_ACCEPTED)
+	})
+}
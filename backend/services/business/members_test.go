@@ -0,0 +1,235 @@
+package business
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/models"
+)
+
+type testMembers struct {
+	business  *models.Business
+	owner     *models.User
+	manager   *models.User
+	reviewer  *models.User
+	viewer    *models.User
+	outsider  *models.User
+	moderator *models.User
+	admin     *models.User
+}
+
+func newTestUser(roles ...models.UserRole) *models.User {
+	user := &models.User{Roles: append([]models.UserRole{models.USER_ROLE_USER}, roles...)}
+	user.Id = uuid.New()
+	return user
+}
+
+// A pending business with one member of each role, plus users outside it
+func newTestMembers() *testMembers {
+	tm := &testMembers{
+		business:  &models.Business{},
+		owner:     newTestUser(),
+		manager:   newTestUser(),
+		reviewer:  newTestUser(),
+		viewer:    newTestUser(),
+		outsider:  newTestUser(),
+		moderator: newTestUser(models.USER_ROLE_MODERATOR),
+		admin:     newTestUser(models.USER_ROLE_ADMIN),
+	}
+	tm.business.Id = uuid.New()
+	tm.business.Status = models.BUSINESS_STATUS_PENDING
+	tm.business.UserId = tm.owner.Id
+	tm.business.Members = []models.BusinessMember{
+		{UserId: tm.owner.Id, Role: models.BUSINESS_MEMBER_ROLE_OWNER},
+		{UserId: tm.manager.Id, Role: models.BUSINESS_MEMBER_ROLE_MANAGER},
+		{UserId: tm.reviewer.Id, Role: models.BUSINESS_MEMBER_ROLE_REVIEWER},
+		{UserId: tm.viewer.Id, Role: models.BUSINESS_MEMBER_ROLE_VIEWER},
+	}
+	return tm
+}
+
+func TestAuthorizeBusinessActionMembers(t *testing.T) {
+	tm := newTestMembers()
+	actions := []BusinessAction{
+		BUSINESS_ACTION_UPDATE,
+		BUSINESS_ACTION_APPROVE,
+		BUSINESS_ACTION_READ,
+		BUSINESS_ACTION_READ_MEMBERS,
+		BUSINESS_ACTION_MANAGE_MEMBERS,
+		BUSINESS_ACTION_TRANSFER,
+		BUSINESS_ACTION_FORCE_TRANSFER,
+		BUSINESS_ACTION_MODERATE,
+		BUSINESS_ACTION_READ_STATUS_HISTORY,
+		BUSINESS_ACTION_READ_DOCUMENTS,
+		BUSINESS_ACTION_MANAGE_DOCUMENTS,
+		BUSINESS_ACTION_REVIEW,
+		BUSINESS_ACTION_REPORT,
+		BUSINESS_ACTION_REVIEW_REPORTS,
+	}
+	tests := []struct {
+		name    string
+		user    *models.User
+		allowed []BusinessAction
+	}{
+		{"owner", tm.owner, []BusinessAction{
+			BUSINESS_ACTION_UPDATE, BUSINESS_ACTION_READ, BUSINESS_ACTION_READ_MEMBERS, BUSINESS_ACTION_MANAGE_MEMBERS,
+			BUSINESS_ACTION_TRANSFER, BUSINESS_ACTION_READ_STATUS_HISTORY, BUSINESS_ACTION_READ_DOCUMENTS, BUSINESS_ACTION_MANAGE_DOCUMENTS,
+		}},
+		{"manager", tm.manager, []BusinessAction{
+			BUSINESS_ACTION_UPDATE, BUSINESS_ACTION_READ, BUSINESS_ACTION_READ_MEMBERS, BUSINESS_ACTION_MANAGE_MEMBERS,
+			BUSINESS_ACTION_READ_STATUS_HISTORY, BUSINESS_ACTION_READ_DOCUMENTS, BUSINESS_ACTION_MANAGE_DOCUMENTS,
+		}},
+		{"reviewer", tm.reviewer, []BusinessAction{
+			BUSINESS_ACTION_READ, BUSINESS_ACTION_READ_MEMBERS, BUSINESS_ACTION_READ_STATUS_HISTORY,
+		}},
+		{"viewer", tm.viewer, []BusinessAction{
+			BUSINESS_ACTION_READ, BUSINESS_ACTION_READ_MEMBERS, BUSINESS_ACTION_READ_STATUS_HISTORY,
+		}},
+		{"outsider", tm.outsider, nil},
+		{"moderator", tm.moderator, []BusinessAction{
+			BUSINESS_ACTION_APPROVE, BUSINESS_ACTION_READ, BUSINESS_ACTION_READ_MEMBERS, BUSINESS_ACTION_MODERATE,
+			BUSINESS_ACTION_READ_STATUS_HISTORY, BUSINESS_ACTION_READ_DOCUMENTS, BUSINESS_ACTION_REVIEW,
+			BUSINESS_ACTION_REPORT, BUSINESS_ACTION_REVIEW_REPORTS,
+		}},
+		{"admin", tm.admin, actions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, action := range actions {
+				err := AuthorizeBusinessAction(tt.user, action, tm.business, nil)
+				if want := slices.Contains(tt.allowed, action); (err == nil) != want {
+					t.Errorf("%v: got err %v, want allowed %v", action, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthorizeBusinessActionActiveBusiness(t *testing.T) {
+	tm := newTestMembers()
+	tm.business.Status = models.BUSINESS_STATUS_ACTIVE
+
+	// Active businesses are visible to everyone, but their members and documents are not
+	for action, want := range map[BusinessAction]bool{
+		BUSINESS_ACTION_READ:           true,
+		BUSINESS_ACTION_REPORT:         true,
+		BUSINESS_ACTION_READ_MEMBERS:   false,
+		BUSINESS_ACTION_READ_DOCUMENTS: false,
+		BUSINESS_ACTION_UPDATE:         false,
+	} {
+		if err := AuthorizeBusinessAction(tm.outsider, action, tm.business, nil); (err == nil) != want {
+			t.Errorf("%v: got err %v, want allowed %v", action, err, want)
+		}
+	}
+}
+
+func TestAuthorizePostActionMembers(t *testing.T) {
+	tm := newTestMembers()
+	post := &models.Post{BusinessId: tm.business.Id, Status: models.POST_STATUS_ACTIVE}
+	otherPost := &models.Post{BusinessId: uuid.New(), Status: models.POST_STATUS_ACTIVE}
+	query := &models.PostQueryParams{BusinessId: &tm.business.Id}
+
+	tests := []struct {
+		name  string
+		user  *models.User
+		write bool
+		read  bool
+	}{
+		{"owner", tm.owner, true, true},
+		{"manager", tm.manager, true, true},
+		{"reviewer", tm.reviewer, false, true},
+		{"viewer", tm.viewer, false, true},
+		{"outsider", tm.outsider, false, false},
+		{"moderator", tm.moderator, false, true},
+		{"admin", tm.admin, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := AuthorizePostAction(tt.user, POST_ACTION_CREATE, tm.business, nil, nil); (err == nil) != tt.write {
+				t.Errorf("create: got err %v, want allowed %v", err, tt.write)
+			}
+			if err := AuthorizePostAction(tt.user, POST_ACTION_UPDATE, tm.business, post, nil); (err == nil) != tt.write {
+				t.Errorf("update: got err %v, want allowed %v", err, tt.write)
+			}
+			if err := AuthorizePostAction(tt.user, POST_ACTION_READ, tm.business, nil, query); (err == nil) != tt.read {
+				t.Errorf("read: got err %v, want allowed %v", err, tt.read)
+			}
+		})
+	}
+
+	// Members can not edit another business's posts through their own
+	if err := AuthorizePostAction(tm.owner, POST_ACTION_UPDATE, tm.business, otherPost, nil); err == nil {
+		t.Error("owner updated a post of another business")
+	}
+}
+
+func TestAuthorizeApplicationActionMembers(t *testing.T) {
+	tm := newTestMembers()
+	decisions := []ApplicationAction{
+		APPLICATION_ACTION_ACCEPT,
+		APPLICATION_ACTION_REJECT,
+		APPLICATION_ACTION_COMPLETE,
+		APPLICATION_ACTION_INCOMPLETE,
+	}
+
+	tests := []struct {
+		name   string
+		user   *models.User
+		decide bool
+		read   bool
+	}{
+		{"owner", tm.owner, true, true},
+		{"manager", tm.manager, true, true},
+		{"reviewer", tm.reviewer, true, true},
+		{"viewer", tm.viewer, false, true},
+		{"outsider", tm.outsider, false, false},
+		{"moderator", tm.moderator, false, true},
+		{"admin", tm.admin, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, action := range decisions {
+				if err := AuthorizeApplicationAction(tt.user, action, tm.business, nil, nil, nil); (err == nil) != tt.decide {
+					t.Errorf("%v: got err %v, want allowed %v", action, err, tt.decide)
+				}
+			}
+			if err := AuthorizeApplicationAction(tt.user, APPLICATION_ACTION_READ, tm.business, nil, nil, nil); (err == nil) != tt.read {
+				t.Errorf("read: got err %v, want allowed %v", err, tt.read)
+			}
+		})
+	}
+}
+
+func TestCanAssignMemberRole(t *testing.T) {
+	tm := newTestMembers()
+	roles := []models.BusinessMemberRole{
+		models.BUSINESS_MEMBER_ROLE_OWNER,
+		models.BUSINESS_MEMBER_ROLE_MANAGER,
+		models.BUSINESS_MEMBER_ROLE_REVIEWER,
+		models.BUSINESS_MEMBER_ROLE_VIEWER,
+	}
+
+	tests := []struct {
+		name       string
+		user       *models.User
+		assignable []models.BusinessMemberRole
+	}{
+		{"owner", tm.owner, roles[1:]},
+		{"manager", tm.manager, roles[2:]},
+		{"reviewer", tm.reviewer, nil},
+		{"viewer", tm.viewer, nil},
+		{"outsider", tm.outsider, nil},
+		{"moderator", tm.moderator, nil},
+		{"admin", tm.admin, roles[1:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, role := range roles {
+				if got, want := canAssignMemberRole(tt.user, tm.business, role), slices.Contains(tt.assignable, role); got != want {
+					t.Errorf("%v: got %v, want %v", role, got, want)
+				}
+			}
+		})
+	}
+}
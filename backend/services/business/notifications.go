@@ -241,3 +241,194 @@ func (n *ApplicationUpdatedNotification) HTML() (string, error) {
 
 	return res.String(), nil
 }
+
+type businessInvitationNotification struct {
+	inviter       *models.User
+	business      *models.Business
+	invitation    *models.BusinessInvitation
+	invitationURI string
+	templatePath  string
+}
+
+func (h *BusinessHandler) newBusinessInvitationNotification(inviter *models.User, business *models.Business, invitation *models.BusinessInvitation) *businessInvitationNotification {
+	const templateName = "BusinessInvitation"
+	// FIXME: Ignoring error
+	invitationURI, _ := invitation.URI(h.frontendURL)
+	return &businessInvitationNotification{
+		inviter:       inviter,
+		business:      business,
+		invitation:    invitation,
+		invitationURI: invitationURI,
+		templatePath:  filepath.Join(h.notificationsTemplatesDir, templateName) + ".html",
+	}
+}
+
+func (n *businessInvitationNotification) ShouldNotify() bool { return true }
+
+// The invitee may not have an account yet
+func (n *businessInvitationNotification) To() *models.User { return nil }
+func (n *businessInvitationNotification) Address() string  { return n.invitation.Email }
+func (n *businessInvitationNotification) Subject() string {
+	return fmt.Sprintf("Invitation to join %v", n.business.Name)
+}
+func (n *businessInvitationNotification) HTML() (string, error) {
+	type templateData struct {
+		InviterName    string
+		BusinessName   string
+		Role           string
+		Email          string
+		ExpiresAt      string
+		InvitationLink string
+	}
+
+	data := templateData{
+		InviterName:    n.inviter.Name,
+		BusinessName:   n.business.Name,
+		Role:           string(n.invitation.Role),
+		Email:          n.invitation.Email,
+		ExpiresAt:      n.invitation.ExpiresAt.Format("January 2, 2006"),
+		InvitationLink: n.invitationURI,
+	}
+
+	t, err := template.ParseFiles(n.templatePath)
+	if err != nil {
+		return "", err
+	}
+
+	var res bytes.Buffer
+	err = t.Execute(&res, data)
+	if err != nil {
+		return "", err
+	}
+
+	return res.String(), nil
+}
+
+type businessTransferNotification struct {
+	recipient    *models.User
+	from         *models.User
+	to           *models.User
+	business     *models.Business
+	transfer     *models.BusinessTransfer
+	transferURI  string
+	templatePath string
+}
+
+func (h *BusinessHandler) newBusinessTransferNotification(recipient, from, to *models.User, business *models.Business, transfer *models.BusinessTransfer) *businessTransferNotification {
+	templateName := fmt.Sprintf("BusinessTransfer%v", cases.Title(language.English).String(string(transfer.Status)))
+	// FIXME: Ignoring error
+	transferURI, _ := transfer.URI(h.frontendURL)
+	return &businessTransferNotification{
+		recipient:    recipient,
+		from:         from,
+		to:           to,
+		business:     business,
+		transfer:     transfer,
+		transferURI:  transferURI,
+		templatePath: filepath.Join(h.notificationsTemplatesDir, templateName) + ".html",
+	}
+}
+
+func (n *businessTransferNotification) ShouldNotify() bool { return true }
+func (n *businessTransferNotification) To() *models.User   { return n.recipient }
+func (n *businessTransferNotification) Subject() string {
+	switch n.transfer.Status {
+	case models.BUSINESS_TRANSFER_STATUS_PENDING:
+		return "Business Transfer Requested"
+	case models.BUSINESS_TRANSFER_STATUS_DECLINED:
+		return "Business Transfer Declined"
+	case models.BUSINESS_TRANSFER_STATUS_CANCELLED:
+		return "Business Transfer Cancelled"
+	}
+	return "Business Transfer Completed"
+}
+func (n *businessTransferNotification) HTML() (string, error) {
+	type templateData struct {
+		RecipientName string
+		FromName      string
+		ToName        string
+		BusinessName  string
+		Reason        string
+		TransferLink  string
+	}
+
+	data := templateData{
+		RecipientName: n.recipient.Name,
+		FromName:      n.from.Name,
+		ToName:        n.to.Name,
+		BusinessName:  n.business.Name,
+		TransferLink:  n.transferURI,
+	}
+	if n.transfer.Reason != nil {
+		data.Reason = *n.transfer.Reason
+	}
+
+	t, err := template.ParseFiles(n.templatePath)
+	if err != nil {
+		return "", err
+	}
+
+	var res bytes.Buffer
+	err = t.Execute(&res, data)
+	if err != nil {
+		return "", err
+	}
+
+	return res.String(), nil
+}
+
+type businessStatusNotification struct {
+	recipient    *models.User
+	business     *models.Business
+	reason       *string
+	subject      string
+	businessURI  string
+	templatePath string
+}
+
+func (h *BusinessHandler) newBusinessStatusNotification(recipient *models.User, business *models.Business, reason *string, transition businessStatusTransition) *businessStatusNotification {
+	// FIXME: Ignoring error
+	businessURI, _ := business.URI(h.frontendURL)
+	return &businessStatusNotification{
+		recipient:    recipient,
+		business:     business,
+		reason:       reason,
+		subject:      transition.subject,
+		businessURI:  businessURI,
+		templatePath: filepath.Join(h.notificationsTemplatesDir, transition.templateName) + ".html",
+	}
+}
+
+func (n *businessStatusNotification) ShouldNotify() bool { return true }
+func (n *businessStatusNotification) To() *models.User   { return n.recipient }
+func (n *businessStatusNotification) Subject() string    { return n.subject }
+func (n *businessStatusNotification) HTML() (string, error) {
+	type templateData struct {
+		RecipientName string
+		BusinessName  string
+		Reason        string
+		BusinessURI   string
+	}
+
+	data := templateData{
+		RecipientName: n.recipient.Name,
+		BusinessName:  n.business.Name,
+		BusinessURI:   n.businessURI,
+	}
+	if n.reason != nil {
+		data.Reason = *n.reason
+	}
+
+	t, err := template.ParseFiles(n.templatePath)
+	if err != nil {
+		return "", err
+	}
+
+	var res bytes.Buffer
+	err = t.Execute(&res, data)
+	if err != nil {
+		return "", err
+	}
+
+	return res.String(), nil
+}
@@ -35,7 +35,7 @@ func (h *BusinessHandler) GetPosts(ctx context.Context, session *sessions.Sessio
 				business = nil
 			}
 		}
-		if err := AuthorizePostAction(user, POST_ACTION_READ, business, nil, params); err != nil {
+		if err := authorizePostAction(session, user, POST_ACTION_READ, business, nil, params); err != nil {
 			return nil, err
 		}
 
@@ -63,7 +63,7 @@ func (h *BusinessHandler) CreatePost(ctx context.Context, session *sessions.Sess
 			return nil, services.NewUnauthorizedServiceError(err)
 		}
 
-		if err := AuthorizePostAction(user, POST_ACTION_CREATE, business, nil, nil); err != nil {
+		if err := authorizePostAction(session, user, POST_ACTION_CREATE, business, nil, nil); err != nil {
 			return nil, err
 		}
 
@@ -107,7 +107,7 @@ func (h *BusinessHandler) UpdatePost(ctx context.Context, session *sessions.Sess
 			return err
 		}
 
-		if err := AuthorizePostAction(user, POST_ACTION_UPDATE, business, post, nil); err != nil {
+		if err := authorizePostAction(session, user, POST_ACTION_UPDATE, business, post, nil); err != nil {
 			return err
 		}
 
@@ -143,9 +143,12 @@ func (h *BusinessHandler) SetPostStatus(ctx context.Context, session *sessions.S
 			return err
 		}
 
-		if err := AuthorizePostAction(user, POST_ACTION_UPDATE, business, post, nil); err != nil {
+		if err := authorizePostAction(session, user, POST_ACTION_UPDATE, business, post, nil); err != nil {
 			return err
 		}
+		if status == models.POST_STATUS_ACTIVE && business.Status != models.BUSINESS_STATUS_ACTIVE {
+			return services.NewDataConflictServiceError(nil, "Business is not active")
+		}
 
 		err = pq.SetPostStatus(ctx, businessId, postId, status)
 		if err != nil {
@@ -164,8 +167,25 @@ const (
 	POST_ACTION_CREATE PostAction = "post:create"
 	POST_ACTION_UPDATE PostAction = "post:update"
 	POST_ACTION_READ   PostAction = "post:read"
+	// File a report against a post
+	POST_ACTION_REPORT PostAction = "post:report"
 )
 
+var postActionScopes = map[PostAction]models.TokenScope{
+	POST_ACTION_CREATE: models.TOKEN_SCOPE_POSTS_WRITE,
+	POST_ACTION_UPDATE: models.TOKEN_SCOPE_POSTS_WRITE,
+	POST_ACTION_READ:   models.TOKEN_SCOPE_POSTS_READ,
+	POST_ACTION_REPORT: models.TOKEN_SCOPE_POSTS_WRITE,
+}
+
+// Checks the scope required when the session is backed by an API token before authorizing the action
+func authorizePostAction(session *sessions.Session, user *models.User, action PostAction, business *models.Business, post *models.Post, query *models.PostQueryParams) error {
+	if err := session.RequireScope(postActionScopes[action]); err != nil {
+		return err
+	}
+	return AuthorizePostAction(user, action, business, post, query)
+}
+
 func AuthorizePostAction(user *models.User, action PostAction, business *models.Business, post *models.Post, query *models.PostQueryParams) error {
 	if user == nil {
 		return services.NewUnauthenticatedServiceError(nil)
@@ -181,22 +201,37 @@ func AuthorizePostAction(user *models.User, action PostAction, business *models.
 				return nil
 			case POST_ACTION_READ:
 				return nil
+			case POST_ACTION_REPORT:
+				return nil
+			}
+		case models.USER_ROLE_MODERATOR:
+			switch action {
+			case POST_ACTION_READ:
+				return nil
+			case POST_ACTION_REPORT:
+				return nil
 			}
 		case models.USER_ROLE_USER:
 			switch action {
 			case POST_ACTION_CREATE:
-				if business != nil && (business.UserId == user.Id) {
+				if business != nil && business.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_MANAGE_POSTS) {
 					return nil
 				}
 			case POST_ACTION_UPDATE:
 				if business != nil && post != nil &&
-					(business.UserId == user.Id && business.Id == post.BusinessId) {
+					(business.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_MANAGE_POSTS) && business.Id == post.BusinessId) {
 					return nil
 				}
 			case POST_ACTION_READ:
 				if query != nil && ((query.UserId != nil && *query.UserId == user.Id) ||
 					(query.Status != nil && *query.Status == models.POST_STATUS_ACTIVE) ||
-					(business != nil && query.BusinessId != nil && business.Id == *query.BusinessId && business.UserId == user.Id)) {
+					(business != nil && query.BusinessId != nil && business.Id == *query.BusinessId &&
+						business.HasMemberPermission(user.Id, models.BUSINESS_PERMISSION_VIEW))) {
+					return nil
+				}
+			case POST_ACTION_REPORT:
+				if business != nil && post != nil && business.Id == post.BusinessId &&
+					business.Status == models.BUSINESS_STATUS_ACTIVE && post.Status == models.POST_STATUS_ACTIVE {
 					return nil
 				}
 			}
@@ -0,0 +1,38 @@
+package business
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+)
+
+// The public listings need no session, they only ever contain active businesses and posts
+
+func (h *BusinessHandler) GetPublicBusinesses(ctx context.Context) ([]models.PublicBusiness, error) {
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.PublicBusiness, error) {
+		return pq.GetPublicBusinesses(ctx)
+	})
+}
+
+func (h *BusinessHandler) GetPublicBusiness(ctx context.Context, businessId *uuid.UUID) (*models.PublicBusiness, error) {
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.PublicBusiness, error) {
+		business, err := pq.GetPublicBusinessForId(ctx, businessId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return nil, services.NewNotFoundServiceError(err)
+			}
+			return nil, err
+		}
+		return business, nil
+	})
+}
+
+func (h *BusinessHandler) GetPublicPosts(ctx context.Context, businessId *uuid.UUID) ([]models.PublicPost, error) {
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.PublicPost, error) {
+		return pq.GetPublicPosts(ctx, businessId)
+	})
+}
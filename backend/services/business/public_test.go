@@ -0,0 +1,181 @@
+package business
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+)
+
+func TestWritePublicJSON(t *testing.T) {
+	write := func(v any, ifNoneMatch string, setCookie bool) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		if setCookie {
+			rec.Header().Set("Set-Cookie", "session=id")
+		}
+		req := httptest.NewRequest(http.MethodGet, "/public/businesses", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		if err := writePublicJSON(rec, req, v); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+
+	rec := write([]string{"a"}, "", false)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" || rec.Body.String() != `["a"]` {
+		t.Fatalf("got %v with etag %q and body %q", rec.Code, etag, rec.Body.String())
+	}
+	if cacheControl := rec.Header().Get("Cache-Control"); !strings.HasPrefix(cacheControl, "public,") {
+		t.Fatalf("got Cache-Control %q, want public", cacheControl)
+	}
+
+	rec = write([]string{"a"}, etag, false)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("matching If-None-Match got %v with body %q, want %v without a body", rec.Code, rec.Body.String(), http.StatusNotModified)
+	}
+	if rec.Header().Get("ETag") != etag {
+		t.Fatalf("not modified response has etag %q, want %q", rec.Header().Get("ETag"), etag)
+	}
+
+	rec = write([]string{"b"}, etag, false)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("changed content got %v with etag %q, want a new etag", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// Responses that set a cookie must not be stored by shared caches
+	rec = write([]string{"a"}, "", true)
+	if cacheControl := rec.Header().Get("Cache-Control"); !strings.HasPrefix(cacheControl, "private,") {
+		t.Fatalf("got Cache-Control %q with a cookie, want private", cacheControl)
+	}
+}
+
+func TestPublicModelsOmitPrivateFields(t *testing.T) {
+	business := models.PublicBusiness{}
+	post := models.PublicPost{}
+	for name, v := range map[string]any{"business": business, "post": post, "post business": post.Business} {
+		body, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := map[string]any{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []string{"user_id", "members", "status", "status_reason"} {
+			if _, ok := fields[field]; ok {
+				t.Errorf("%v exposes %v", name, field)
+			}
+		}
+	}
+}
+
+// Runs against a migrated database given by TEST_DATABASE_URL
+func TestPublicEndpointsListOnlyActive(t *testing.T) {
+	h := newTestBusinessHandler(t, newTestStore(t))
+	ctx := context.Background()
+
+	type fixture struct {
+		active, pending          *models.Business
+		activePost, disabledPost *models.Post
+		pendingBusinessPost      *models.Post
+	}
+	f, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*fixture, error) {
+		owner, err := pq.CreateUser(ctx)
+		if err != nil {
+			return nil, err
+		}
+		newBusiness := func(status models.BusinessStatus) (*models.Business, error) {
+			business, err := pq.CreateBusiness(ctx, owner, &models.BusinessCreate{BusinessUpdate: models.BusinessUpdate{
+				Name:    "Public Test",
+				Desc:    "A business to list publicly",
+				Website: "https://example.com",
+			}})
+			if err != nil {
+				return nil, err
+			}
+			if status != models.BUSINESS_STATUS_PENDING {
+				return business, pq.SetBusinessStatus(ctx, &business.Id, status, nil, owner)
+			}
+			return business, nil
+		}
+		newPost := func(business *models.Business, status models.PostStatus) (*models.Post, error) {
+			post, err := pq.CreatePost(ctx, &business.Id, &models.PostCreate{PostUpdate: models.PostUpdate{
+				Title:   "Public test post",
+				Desc:    "A post to list publicly",
+				Pay:     10,
+				TimeEst: 30,
+			}})
+			if err != nil {
+				return nil, err
+			}
+			return post, pq.SetPostStatus(ctx, &business.Id, post.Id, status)
+		}
+
+		f := &fixture{}
+		if f.active, err = newBusiness(models.BUSINESS_STATUS_ACTIVE); err != nil {
+			return nil, err
+		}
+		if f.pending, err = newBusiness(models.BUSINESS_STATUS_PENDING); err != nil {
+			return nil, err
+		}
+		if f.activePost, err = newPost(f.active, models.POST_STATUS_ACTIVE); err != nil {
+			return nil, err
+		}
+		if f.disabledPost, err = newPost(f.active, models.POST_STATUS_DISABLED); err != nil {
+			return nil, err
+		}
+		if f.pendingBusinessPost, err = newPost(f.pending, models.POST_STATUS_ACTIVE); err != nil {
+			return nil, err
+		}
+		return f, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	businesses, err := h.GetPublicBusinesses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := map[uuid.UUID]bool{}
+	for _, business := range businesses {
+		listed[business.Id] = true
+	}
+	if !listed[f.active.Id] || listed[f.pending.Id] {
+		t.Fatalf("listed active %v and pending %v, want only the active business", listed[f.active.Id], listed[f.pending.Id])
+	}
+
+	if _, err := h.GetPublicBusiness(ctx, &f.active.Id); err != nil {
+		t.Fatalf("active business got %v", err)
+	}
+	if _, err := h.GetPublicBusiness(ctx, &f.pending.Id); serviceErrorStatus(err) != http.StatusNotFound {
+		t.Fatalf("pending business got %v, want not found", err)
+	}
+
+	for _, businessId := range []uuid.UUID{f.active.Id, f.pending.Id} {
+		posts, err := h.GetPublicPosts(ctx, &businessId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, post := range posts {
+			ids = append(ids, post.Id)
+		}
+		want := []int{}
+		if businessId == f.active.Id {
+			want = []int{f.activePost.Id}
+		}
+		if len(ids) != len(want) || (len(want) > 0 && ids[0] != want[0]) {
+			t.Fatalf("business %v listed posts %v, want %v", businessId, ids, want)
+		}
+	}
+}
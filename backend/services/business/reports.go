@@ -0,0 +1,117 @@
+package business
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+func (h *BusinessHandler) ReportBusiness(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, data *models.ReportCreate) (*models.Report, error) {
+	if err := models.ValidateData(data); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.Report, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_REPORT, business, nil); err != nil {
+			return nil, err
+		}
+		return h.createReport(ctx, pq, user, businessId, nil, data)
+	})
+}
+
+func (h *BusinessHandler) ReportPost(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, postId int, data *models.ReportCreate) (*models.Report, error) {
+	if err := models.ValidateData(data); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.Report, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		post, err := pq.GetPostForId(ctx, businessId, postId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return nil, services.NewNotFoundServiceError(err)
+			}
+			return nil, err
+		}
+		if err := authorizePostAction(session, user, POST_ACTION_REPORT, business, post, nil); err != nil {
+			return nil, err
+		}
+		return h.createReport(ctx, pq, user, businessId, &postId, data)
+	})
+}
+
+func (h *BusinessHandler) createReport(ctx context.Context, pq *db.PgxQueries, user *models.User, businessId *uuid.UUID, postId *int, data *models.ReportCreate) (*models.Report, error) {
+	report, err := pq.CreateReport(ctx, &user.Id, businessId, postId, data)
+	if err != nil {
+		if errors.Is(err, db.ErrUnique) {
+			return nil, services.NewDataConflictServiceError(err, "You already have an open report for this")
+		}
+		return nil, err
+	}
+
+	h.logger.Info("Filed report", "report_id", report.Id, "business_id", businessId, "post_id", postId, "reason", report.Reason)
+	return report, nil
+}
+
+// Lists reports for moderators, oldest first
+func (h *BusinessHandler) GetReports(ctx context.Context, session *sessions.Session, params *models.ReportQueryParams) ([]models.Report, error) {
+	userId := session.GetUserId()
+	if userId == nil {
+		return nil, services.NewUnauthenticatedServiceError(nil)
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.Report, error) {
+		user, err := pq.GetUserForId(ctx, userId)
+		if err != nil {
+			return nil, services.NewUnauthenticatedServiceError(err)
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_REVIEW_REPORTS, nil, nil); err != nil {
+			return nil, err
+		}
+		return pq.GetReports(ctx, params)
+	})
+}
+
+// Closes an open report, acting on the reported business or post is done through the moderation endpoints
+func (h *BusinessHandler) ReviewReport(ctx context.Context, session *sessions.Session, reportId *uuid.UUID, data *models.ReportReview) (*models.Report, error) {
+	userId := session.GetUserId()
+	if userId == nil {
+		return nil, services.NewUnauthenticatedServiceError(nil)
+	}
+	if err := models.ValidateData(data); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.Report, error) {
+		user, err := pq.GetUserForId(ctx, userId)
+		if err != nil {
+			return nil, services.NewUnauthenticatedServiceError(err)
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_REVIEW_REPORTS, nil, nil); err != nil {
+			return nil, err
+		}
+
+		report, err := pq.ReviewReport(ctx, reportId, userId, data)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return nil, services.NewNotFoundServiceError(err)
+			}
+			return nil, err
+		}
+
+		h.logger.Info("Reviewed report", "report_id", reportId, "status", report.Status, "reviewer_id", userId)
+		return report, nil
+	})
+}
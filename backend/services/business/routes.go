@@ -1,6 +1,9 @@
 package business
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,21 +14,39 @@ import (
 	"github.com/google/uuid"
 	"github.com/john-vh/college_testing/backend/models"
 	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
 )
 
 const (
 	businessIdParam = "businessId"
 	postIdParam     = "postId"
 	userIdParam     = "userId"
+	invitationParam = "invitationId"
+	transferIdParam = "transferId"
+	documentIdParam = "documentId"
+	reportIdParam   = "reportId"
 )
 
 func (h *BusinessHandler) RegisterRoutes(router *http.ServeMux) {
 	router.HandleFunc("GET /admin/businesses", h.handleErr(h.handleQueryAllBusinesses))
 	router.HandleFunc("GET /admin/posts", h.handleErr(h.handleQueryAllPosts))
 	router.HandleFunc("POST /admin/businesses/{businessId}/approve", h.handleErr(h.handleApproveBusiness))
+	router.HandleFunc("POST /admin/businesses/{businessId}/reject", h.handleErr(h.handleChangeBusinessStatus(h.RejectBusiness)))
+	router.HandleFunc("POST /admin/businesses/{businessId}/suspend", h.handleErr(h.handleChangeBusinessStatus(h.SuspendBusiness)))
+	router.HandleFunc("POST /admin/businesses/{businessId}/reinstate", h.handleErr(h.handleChangeBusinessStatus(h.ReinstateBusiness)))
+	router.HandleFunc("POST /admin/businesses/{businessId}/transfer", h.handleErr(h.handleForceBusinessTransfer))
+	router.HandleFunc("GET /admin/business-reviews", h.handleErr(h.handleGetBusinessReviewQueue))
+	router.HandleFunc("GET /admin/businesses/{businessId}/review", h.handleErr(h.handleGetBusinessReview))
+	router.HandleFunc("PUT /admin/businesses/{businessId}/review", h.handleErr(h.handleReviewBusiness))
+	router.HandleFunc("GET /admin/reports", h.handleErr(h.handleGetReports))
+	router.HandleFunc("PUT /admin/reports/{reportId}", h.handleErr(h.handleReviewReport))
 
 	router.HandleFunc("GET /posts", h.handleErr(h.handleGetActivePosts))
 
+	router.HandleFunc("GET /public/businesses", h.handleErr(h.handleGetPublicBusinesses))
+	router.HandleFunc("GET /public/businesses/{businessId}", h.handleErr(h.handleGetPublicBusiness))
+	router.HandleFunc("GET /public/posts", h.handleErr(h.handleGetPublicPosts))
+
 	router.HandleFunc("GET /businesses", h.handleErr(h.handleGetBusinesses))
 	router.HandleFunc("GET /businesses/{businessId}", h.handleErr(h.handleGetBusiness))
 	router.HandleFunc("GET /users/0/businesses", h.handleErr(h.handleGetUserBusinesses))
@@ -33,13 +54,36 @@ func (h *BusinessHandler) RegisterRoutes(router *http.ServeMux) {
 	router.HandleFunc("GET /users/0/applications", h.handleErr(h.handleGetUserApplications))
 	router.HandleFunc("POST /users/0/businesses", h.handleErr(h.handleRequestBusiness))
 	router.HandleFunc("PATCH /businesses/{businessId}", h.handleErr(h.handleUpdateBusiness))
+	router.HandleFunc("GET /businesses/{businessId}/status-history", h.handleErr(h.handleGetBusinessStatusHistory))
 	router.HandleFunc("POST /businesses/{businessId}/upload-image", h.handleErr(h.handleUploadBusinessImage))
+	router.HandleFunc("GET /businesses/{businessId}/domain-verification", h.handleErr(h.handleGetDomainVerification))
+	router.HandleFunc("POST /businesses/{businessId}/domain-verification", h.handleErr(h.handleRequestDomainVerification))
+	router.HandleFunc("GET /businesses/{businessId}/documents", h.handleErr(h.handleGetBusinessDocuments))
+	router.HandleFunc("POST /businesses/{businessId}/documents", h.handleErr(h.handleUploadBusinessDocument))
+	router.HandleFunc("DELETE /businesses/{businessId}/documents/{documentId}", h.handleErr(h.handleDeleteBusinessDocument))
+	router.HandleFunc("POST /businesses/{businessId}/reports", h.handleErr(h.handleReportBusiness))
+
+	router.HandleFunc("GET /businesses/{businessId}/members", h.handleErr(h.handleGetBusinessMembers))
+	router.HandleFunc("DELETE /businesses/{businessId}/members/{userId}", h.handleErr(h.handleRemoveBusinessMember))
+	router.HandleFunc("GET /businesses/{businessId}/invitations", h.handleErr(h.handleGetBusinessInvitations))
+	router.HandleFunc("POST /businesses/{businessId}/invitations", h.handleErr(h.handleInviteBusinessMember))
+	router.HandleFunc("DELETE /businesses/{businessId}/invitations/{invitationId}", h.handleErr(h.handleRevokeBusinessInvitation))
+	router.HandleFunc("GET /businesses/{businessId}/transfers", h.handleErr(h.handleGetBusinessTransfers))
+	router.HandleFunc("POST /businesses/{businessId}/transfers", h.handleErr(h.handleRequestBusinessTransfer))
+	router.HandleFunc("DELETE /businesses/{businessId}/transfers/{transferId}", h.handleErr(h.handleCancelBusinessTransfer))
+	router.HandleFunc("GET /users/0/transfers", h.handleErr(h.handleGetUserTransfers))
+	router.HandleFunc("POST /users/0/transfers/{transferId}/accept", h.handleErr(h.handleAcceptTransfer))
+	router.HandleFunc("POST /users/0/transfers/{transferId}/decline", h.handleErr(h.handleDeclineTransfer))
+	router.HandleFunc("GET /users/0/invitations", h.handleErr(h.handleGetUserInvitations))
+	router.HandleFunc("POST /users/0/invitations/{invitationId}/accept", h.handleErr(h.handleAcceptInvitation))
+	router.HandleFunc("POST /users/0/invitations/{invitationId}/decline", h.handleErr(h.handleDeclineInvitation))
 
 	router.HandleFunc("POST /businesses/{businessId}/posts", h.handleErr(h.handleCreatePost))
 	router.HandleFunc("PATCH /businesses/{businessId}/posts/{postId}", h.handleErr(h.handleUpdatePost))
 	router.HandleFunc("POST /businesses/{businessId}/posts/{postId}/activate", h.handleErr(h.handleActivatePost))
 	router.HandleFunc("POST /businesses/{businessId}/posts/{postId}/deactivate", h.handleErr(h.handleDeactivatePost))
 	router.HandleFunc("POST /businesses/{businessId}/posts/{postId}/archive", h.handleErr(h.handleArchivePost))
+	router.HandleFunc("POST /businesses/{businessId}/posts/{postId}/reports", h.handleErr(h.handleReportPost))
 
 	router.HandleFunc("POST /businesses/{businessId}/posts/{postId}/apply", h.handleErr(h.handleApplyToPost))
 	router.HandleFunc("GET /businesses/{businessId}/posts/{postId}/applications", h.handleErr(h.handleGetPostApplications))
@@ -152,6 +196,80 @@ func (h *BusinessHandler) handleGetActivePosts(w http.ResponseWriter, r *http.Re
 	return nil
 }
 
+func (h *BusinessHandler) handleGetPublicBusinesses(w http.ResponseWriter, r *http.Request) error {
+	businesses, err := h.GetPublicBusinesses(r.Context())
+	if err != nil {
+		return err
+	}
+
+	return writePublicJSON(w, r, businesses)
+}
+
+func (h *BusinessHandler) handleGetPublicBusiness(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	business, err := h.GetPublicBusiness(r.Context(), &businessId)
+	if err != nil {
+		return err
+	}
+
+	return writePublicJSON(w, r, business)
+}
+
+func (h *BusinessHandler) handleGetPublicPosts(w http.ResponseWriter, r *http.Request) error {
+	const (
+		param_business string = "business"
+	)
+
+	var businessId *uuid.UUID
+	if r.URL.Query().Has(param_business) {
+		id, err := uuid.Parse(r.URL.Query().Get(param_business))
+		if err != nil {
+			return services.NewBadRequestServiceError(err)
+		}
+		businessId = &id
+	}
+
+	posts, err := h.GetPublicPosts(r.Context(), businessId)
+	if err != nil {
+		return err
+	}
+
+	return writePublicJSON(w, r, posts)
+}
+
+// Writes a response that shared caches may store, answering conditional requests with 304 Not Modified
+func writePublicJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	const maxAge = 60 // seconds
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	// Responses carrying session cookies or CSRF tokens must not end up in a shared cache
+	if w.Header().Get("Set-Cookie") != "" || w.Header().Get("X-CSRF-Token") != "" {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%v", maxAge))
+	} else {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%v", maxAge))
+	}
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+	return nil
+}
+
 func (h *BusinessHandler) handleRequestBusiness(w http.ResponseWriter, r *http.Request) error {
 	session, err := h.sessions.GetSession(r)
 	if err != nil {
@@ -212,7 +330,7 @@ func (h *BusinessHandler) handleUploadBusinessImage(w http.ResponseWriter, r *ht
 	}
 
 	file.Seek(0, io.SeekStart)
-	err = h.setBusinessImage(r.Context(), session, &businessId, header.Filename, file)
+	err = h.setBusinessImage(r.Context(), session, &businessId, file)
 	if err != nil {
 		return err
 	}
@@ -372,7 +490,28 @@ func (h *BusinessHandler) handleApproveBusiness(_ http.ResponseWriter, r *http.R
 	return h.ApproveBusiness(r.Context(), session, &businessId)
 }
 
-func (h *BusinessHandler) handleCreatePost(w http.ResponseWriter, r *http.Request) error {
+func (h *BusinessHandler) handleChangeBusinessStatus(change func(context.Context, *sessions.Session, *uuid.UUID, *models.BusinessStatusUpdate) error) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+		if err != nil {
+			return services.NewNotFoundServiceError(err)
+		}
+
+		session, err := h.sessions.GetSession(r)
+		if err != nil {
+			return err
+		}
+
+		data := models.BusinessStatusUpdate{}
+		if err := models.ReadRequestJson(r, &data); err != nil {
+			return err
+		}
+
+		return change(r.Context(), session, &businessId, &data)
+	}
+}
+
+func (h *BusinessHandler) handleGetBusinessStatusHistory(w http.ResponseWriter, r *http.Request) error {
 	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
 	if err != nil {
 		return services.NewNotFoundServiceError(err)
@@ -383,95 +522,138 @@ func (h *BusinessHandler) handleCreatePost(w http.ResponseWriter, r *http.Reques
 		return err
 	}
 
-	data := models.PostCreate{}
-	if err := models.ReadRequestJson(r, &data); err != nil {
+	history, err := h.GetBusinessStatusHistory(r.Context(), session, &businessId)
+	if err != nil {
 		return err
 	}
 
-	post, err := h.CreatePost(r.Context(), session, &businessId, &data)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+	return nil
+}
+
+func (h *BusinessHandler) handleGetDomainVerification(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	verification, err := h.GetDomainVerification(r.Context(), session, &businessId)
 	if err != nil {
 		return err
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(post)
+	json.NewEncoder(w).Encode(verification)
 	return nil
 }
 
-func (h *BusinessHandler) handleUpdatePost(w http.ResponseWriter, r *http.Request) error {
+func (h *BusinessHandler) handleRequestDomainVerification(w http.ResponseWriter, r *http.Request) error {
 	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
 	if err != nil {
 		return services.NewNotFoundServiceError(err)
 	}
 
-	postId, err := strconv.Atoi(r.PathValue(postIdParam))
+	session, err := h.sessions.GetSession(r)
 	if err != nil {
-		return services.NewNotFoundServiceError(err)
+		return err
 	}
 
-	session, err := h.sessions.GetSession(r)
+	verification, err := h.RequestDomainVerification(r.Context(), session, &businessId)
 	if err != nil {
 		return err
 	}
 
-	data := models.PostUpdate{}
-	if err := models.ReadRequestJson(r, &data); err != nil {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verification)
+	return nil
+}
+
+func (h *BusinessHandler) handleGetBusinessDocuments(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
 		return err
 	}
 
-	err = h.UpdatePost(r.Context(), session, &businessId, postId, &data)
+	documents, err := h.GetBusinessDocuments(r.Context(), session, &businessId)
 	if err != nil {
 		return err
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(documents)
 	return nil
 }
 
-func (h *BusinessHandler) handleSetPostStatus(status models.PostStatus) func(w http.ResponseWriter, r *http.Request) error {
-	return func(w http.ResponseWriter, r *http.Request) error {
-		businessId, err := uuid.Parse(r.PathValue(businessIdParam))
-		if err != nil {
-			return services.NewNotFoundServiceError(err)
-		}
-
-		postId, err := strconv.Atoi(r.PathValue(postIdParam))
-		if err != nil {
-			return services.NewNotFoundServiceError(err)
-		}
+func (h *BusinessHandler) handleUploadBusinessDocument(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
 
-		session, err := h.sessions.GetSession(r)
-		if err != nil {
-			return err
-		}
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
 
-		err = h.SetPostStatus(r.Context(), session, &businessId, postId, status)
-		if err != nil {
-			return err
-		}
+	const maxSize = 10 << 20 // 10 MB
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+(1<<20))
+	err = r.ParseMultipartForm(maxSize)
+	if err != nil {
+		h.logger.Debug("Error parsing multipart form", "err", err)
+		return services.NewBadRequestServiceError(err)
+	}
 
-		return nil
+	file, header, err := r.FormFile("document")
+	if err != nil {
+		h.logger.Debug("Error getting file from form", "err", err)
+		return services.NewBadRequestServiceError(err)
+	}
+	defer file.Close()
+	if header.Size > maxSize {
+		return services.NewBadRequestServiceError(fmt.Errorf("Document exceeds %v bytes", maxSize))
 	}
-}
 
-func (h *BusinessHandler) handleActivatePost(w http.ResponseWriter, r *http.Request) error {
-	return h.handleSetPostStatus(models.POST_STATUS_ACTIVE)(w, r)
-}
+	start := make([]byte, 512)
+	n, err := file.Read(start)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	mtype := http.DetectContentType(start[:n])
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
 
-func (h *BusinessHandler) handleDeactivatePost(w http.ResponseWriter, r *http.Request) error {
-	return h.handleSetPostStatus(models.POST_STATUS_DISABLED)(w, r)
-}
+	data := models.BusinessDocumentCreate{
+		Kind: models.BusinessDocumentKind(r.FormValue("kind")),
+	}
+	document, err := h.UploadBusinessDocument(r.Context(), session, &businessId, &data, header.Filename, mtype, header.Size, file)
+	if err != nil {
+		return err
+	}
 
-func (h *BusinessHandler) handleArchivePost(w http.ResponseWriter, r *http.Request) error {
-	return h.handleSetPostStatus(models.POST_STATUS_ARCHIVED)(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(document)
+	return nil
 }
 
-func (h *BusinessHandler) handleApplyToPost(w http.ResponseWriter, r *http.Request) error {
+func (h *BusinessHandler) handleDeleteBusinessDocument(_ http.ResponseWriter, r *http.Request) error {
 	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
 	if err != nil {
 		return services.NewNotFoundServiceError(err)
 	}
 
-	postId, err := strconv.Atoi(r.PathValue(postIdParam))
+	documentId, err := uuid.Parse(r.PathValue(documentIdParam))
 	if err != nil {
 		return services.NewNotFoundServiceError(err)
 	}
@@ -480,26 +662,49 @@ func (h *BusinessHandler) handleApplyToPost(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		return err
 	}
-	userId := session.GetUserId()
-	if userId == nil {
-		return services.NewUnauthenticatedServiceError(nil)
+
+	return h.DeleteBusinessDocument(r.Context(), session, &businessId, &documentId)
+}
+
+func (h *BusinessHandler) handleGetBusinessReviewQueue(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
 	}
 
-	err = h.CreateApplication(r.Context(), session, &businessId, postId, userId)
+	queue, err := h.GetBusinessReviewQueue(r.Context(), session)
 	if err != nil {
 		return err
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(queue)
 	return nil
 }
 
-func (h *BusinessHandler) handleGetPostApplications(w http.ResponseWriter, r *http.Request) error {
+func (h *BusinessHandler) handleGetBusinessReview(w http.ResponseWriter, r *http.Request) error {
 	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
 	if err != nil {
 		return services.NewNotFoundServiceError(err)
 	}
 
-	postId, err := strconv.Atoi(r.PathValue(postIdParam))
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	review, err := h.GetBusinessReview(r.Context(), session, &businessId)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+	return nil
+}
+
+func (h *BusinessHandler) handleReviewBusiness(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
 	if err != nil {
 		return services.NewNotFoundServiceError(err)
 	}
@@ -508,63 +713,630 @@ func (h *BusinessHandler) handleGetPostApplications(w http.ResponseWriter, r *ht
 	if err != nil {
 		return err
 	}
-	applications, err := h.GetPostApplications(r.Context(), session, &businessId, postId)
+
+	data := models.BusinessReviewUpdate{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	review, err := h.ReviewBusiness(r.Context(), session, &businessId, &data)
 	if err != nil {
 		return err
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(applications)
+	json.NewEncoder(w).Encode(review)
 	return nil
 }
 
-func (h *BusinessHandler) handleSetApplicationStatus(status models.ApplicationStatus) func(w http.ResponseWriter, r *http.Request) error {
-	return func(w http.ResponseWriter, r *http.Request) error {
-		businessId, err := uuid.Parse(r.PathValue(businessIdParam))
-		if err != nil {
-			return services.NewNotFoundServiceError(err)
-		}
-
-		postId, err := strconv.Atoi(r.PathValue(postIdParam))
-		if err != nil {
-			return services.NewNotFoundServiceError(err)
-		}
-
-		userId, err := uuid.Parse(r.PathValue(userIdParam))
-		if err != nil {
-			return services.NewNotFoundServiceError(err)
-		}
+func (h *BusinessHandler) handleGetReports(w http.ResponseWriter, r *http.Request) error {
+	const (
+		param_status   = "status"
+		param_business = "business"
+	)
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
 
-		session, err := h.sessions.GetSession(r)
-		if err != nil {
-			return err
+	params := models.ReportQueryParams{}
+	if r.URL.Query().Has(param_status) {
+		status := models.ReportStatus(r.URL.Query().Get(param_status))
+		switch status {
+		case models.REPORT_STATUS_OPEN, models.REPORT_STATUS_RESOLVED, models.REPORT_STATUS_DISMISSED:
+		default:
+			return services.NewBadRequestServiceError(nil)
 		}
-
-		err = h.SetApplicationStatus(r.Context(), session, &businessId, postId, &userId, status)
+		params.Status = &status
+	}
+	if r.URL.Query().Has(param_business) {
+		businessId, err := uuid.Parse(r.URL.Query().Get(param_business))
 		if err != nil {
-			return err
+			return services.NewBadRequestServiceError(err)
 		}
-
-		return nil
+		params.BusinessId = &businessId
 	}
-}
 
-func (h *BusinessHandler) handleAcceptApplication(w http.ResponseWriter, r *http.Request) error {
-	return h.handleSetApplicationStatus(models.APPLICATION_STATUS_ACCEPTED)(w, r)
-}
+	reports, err := h.GetReports(r.Context(), session, &params)
+	if err != nil {
+		return err
+	}
 
-func (h *BusinessHandler) handleRejectApplication(w http.ResponseWriter, r *http.Request) error {
-	return h.handleSetApplicationStatus(models.APPLICATION_STATUS_REJECTED)(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+	return nil
 }
 
-func (h *BusinessHandler) handleCompleteApplication(w http.ResponseWriter, r *http.Request) error {
-	return h.handleSetApplicationStatus(models.APPLICATION_STATUS_COMPLETED)(w, r)
-}
+func (h *BusinessHandler) handleReviewReport(w http.ResponseWriter, r *http.Request) error {
+	reportId, err := uuid.Parse(r.PathValue(reportIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
 
-func (h *BusinessHandler) handleAbandonApplication(w http.ResponseWriter, r *http.Request) error {
-	return h.handleSetApplicationStatus(models.APPLICATION_STATUS_CANCELLED)(w, r)
-}
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
 
-func (h *BusinessHandler) handleWithdrawApplication(w http.ResponseWriter, r *http.Request) error {
+	data := models.ReportReview{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	report, err := h.ReviewReport(r.Context(), session, &reportId, &data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+	return nil
+}
+
+func (h *BusinessHandler) handleReportBusiness(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.ReportCreate{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	report, err := h.ReportBusiness(r.Context(), session, &businessId, &data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+	return nil
+}
+
+func (h *BusinessHandler) handleReportPost(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	postId, err := strconv.Atoi(r.PathValue(postIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.ReportCreate{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	report, err := h.ReportPost(r.Context(), session, &businessId, postId, &data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+	return nil
+}
+
+func (h *BusinessHandler) handleCreatePost(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.PostCreate{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	post, err := h.CreatePost(r.Context(), session, &businessId, &data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(post)
+	return nil
+}
+
+func (h *BusinessHandler) handleUpdatePost(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	postId, err := strconv.Atoi(r.PathValue(postIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.PostUpdate{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	err = h.UpdatePost(r.Context(), session, &businessId, postId, &data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (h *BusinessHandler) handleSetPostStatus(status models.PostStatus) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+		if err != nil {
+			return services.NewNotFoundServiceError(err)
+		}
+
+		postId, err := strconv.Atoi(r.PathValue(postIdParam))
+		if err != nil {
+			return services.NewNotFoundServiceError(err)
+		}
+
+		session, err := h.sessions.GetSession(r)
+		if err != nil {
+			return err
+		}
+
+		err = h.SetPostStatus(r.Context(), session, &businessId, postId, status)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func (h *BusinessHandler) handleActivatePost(w http.ResponseWriter, r *http.Request) error {
+	return h.handleSetPostStatus(models.POST_STATUS_ACTIVE)(w, r)
+}
+
+func (h *BusinessHandler) handleDeactivatePost(w http.ResponseWriter, r *http.Request) error {
+	return h.handleSetPostStatus(models.POST_STATUS_DISABLED)(w, r)
+}
+
+func (h *BusinessHandler) handleArchivePost(w http.ResponseWriter, r *http.Request) error {
+	return h.handleSetPostStatus(models.POST_STATUS_ARCHIVED)(w, r)
+}
+
+func (h *BusinessHandler) handleApplyToPost(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	postId, err := strconv.Atoi(r.PathValue(postIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+	userId := session.GetUserId()
+	if userId == nil {
+		return services.NewUnauthenticatedServiceError(nil)
+	}
+
+	err = h.CreateApplication(r.Context(), session, &businessId, postId, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (h *BusinessHandler) handleGetPostApplications(w http.ResponseWriter, r *http.Request) error {
+	const (
+		param_institution string = "institution"
+	)
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	postId, err := strconv.Atoi(r.PathValue(postIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	params := models.PostApplicationQueryParams{}
+	if r.URL.Query().Has(param_institution) {
+		institutionId, err := uuid.Parse(r.URL.Query().Get(param_institution))
+		if err != nil {
+			return services.NewBadRequestServiceError(err)
+		}
+		params.InstitutionId = &institutionId
+	}
+
+	applications, err := h.GetPostApplications(r.Context(), session, &businessId, postId, &params)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(applications)
+	return nil
+}
+
+func (h *BusinessHandler) handleSetApplicationStatus(status models.ApplicationStatus) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+		if err != nil {
+			return services.NewNotFoundServiceError(err)
+		}
+
+		postId, err := strconv.Atoi(r.PathValue(postIdParam))
+		if err != nil {
+			return services.NewNotFoundServiceError(err)
+		}
+
+		userId, err := uuid.Parse(r.PathValue(userIdParam))
+		if err != nil {
+			return services.NewNotFoundServiceError(err)
+		}
+
+		session, err := h.sessions.GetSession(r)
+		if err != nil {
+			return err
+		}
+
+		err = h.SetApplicationStatus(r.Context(), session, &businessId, postId, &userId, status)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func (h *BusinessHandler) handleAcceptApplication(w http.ResponseWriter, r *http.Request) error {
+	return h.handleSetApplicationStatus(models.APPLICATION_STATUS_ACCEPTED)(w, r)
+}
+
+func (h *BusinessHandler) handleRejectApplication(w http.ResponseWriter, r *http.Request) error {
+	return h.handleSetApplicationStatus(models.APPLICATION_STATUS_REJECTED)(w, r)
+}
+
+func (h *BusinessHandler) handleCompleteApplication(w http.ResponseWriter, r *http.Request) error {
+	return h.handleSetApplicationStatus(models.APPLICATION_STATUS_COMPLETED)(w, r)
+}
+
+func (h *BusinessHandler) handleAbandonApplication(w http.ResponseWriter, r *http.Request) error {
+	return h.handleSetApplicationStatus(models.APPLICATION_STATUS_CANCELLED)(w, r)
+}
+
+func (h *BusinessHandler) handleWithdrawApplication(w http.ResponseWriter, r *http.Request) error {
 	return h.handleSetApplicationStatus(models.APPLICATION_STATUS_WITHDRAWN)(w, r)
 }
+
+func (h *BusinessHandler) handleGetBusinessMembers(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	members, err := h.GetBusinessMembers(r.Context(), session, &businessId)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+	return nil
+}
+
+func (h *BusinessHandler) handleRemoveBusinessMember(_ http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	userId, err := uuid.Parse(r.PathValue(userIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.RemoveBusinessMember(r.Context(), session, &businessId, &userId)
+}
+
+func (h *BusinessHandler) handleGetBusinessInvitations(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	invitations, err := h.GetBusinessInvitations(r.Context(), session, &businessId)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+	return nil
+}
+
+func (h *BusinessHandler) handleInviteBusinessMember(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.BusinessInvitationCreate{}
+	err = models.ReadRequestJson(r, &data)
+	if err != nil {
+		return err
+	}
+
+	invitation, err := h.InviteBusinessMember(r.Context(), session, &businessId, &data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitation)
+	return nil
+}
+
+func (h *BusinessHandler) handleRevokeBusinessInvitation(_ http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	invitationId, err := uuid.Parse(r.PathValue(invitationParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.RevokeBusinessInvitation(r.Context(), session, &businessId, &invitationId)
+}
+
+func (h *BusinessHandler) handleGetUserInvitations(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	invitations, err := h.GetUserInvitations(r.Context(), session)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+	return nil
+}
+
+func (h *BusinessHandler) handleRespondToInvitation(accept bool) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		invitationId, err := uuid.Parse(r.PathValue(invitationParam))
+		if err != nil {
+			return services.NewNotFoundServiceError(err)
+		}
+
+		session, err := h.sessions.GetSession(r)
+		if err != nil {
+			return err
+		}
+
+		return h.RespondToInvitation(r.Context(), session, &invitationId, accept)
+	}
+}
+
+func (h *BusinessHandler) handleAcceptInvitation(w http.ResponseWriter, r *http.Request) error {
+	return h.handleRespondToInvitation(true)(w, r)
+}
+
+func (h *BusinessHandler) handleDeclineInvitation(w http.ResponseWriter, r *http.Request) error {
+	return h.handleRespondToInvitation(false)(w, r)
+}
+
+func (h *BusinessHandler) handleGetBusinessTransfers(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	transfers, err := h.GetBusinessTransfers(r.Context(), session, &businessId)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+	return nil
+}
+
+func (h *BusinessHandler) handleRequestBusinessTransfer(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.BusinessTransferCreate{}
+	err = models.ReadRequestJson(r, &data)
+	if err != nil {
+		return err
+	}
+
+	transfer, err := h.RequestBusinessTransfer(r.Context(), session, &businessId, &data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+	return nil
+}
+
+func (h *BusinessHandler) handleCancelBusinessTransfer(_ http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	transferId, err := uuid.Parse(r.PathValue(transferIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.CancelBusinessTransfer(r.Context(), session, &businessId, &transferId)
+}
+
+func (h *BusinessHandler) handleForceBusinessTransfer(w http.ResponseWriter, r *http.Request) error {
+	businessId, err := uuid.Parse(r.PathValue(businessIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.BusinessTransferForce{}
+	err = models.ReadRequestJson(r, &data)
+	if err != nil {
+		return err
+	}
+
+	transfer, err := h.ForceBusinessTransfer(r.Context(), session, &businessId, &data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+	return nil
+}
+
+func (h *BusinessHandler) handleGetUserTransfers(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	transfers, err := h.GetUserTransfers(r.Context(), session)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+	return nil
+}
+
+func (h *BusinessHandler) handleRespondToTransfer(accept bool) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		transferId, err := uuid.Parse(r.PathValue(transferIdParam))
+		if err != nil {
+			return services.NewNotFoundServiceError(err)
+		}
+
+		session, err := h.sessions.GetSession(r)
+		if err != nil {
+			return err
+		}
+
+		return h.RespondToTransfer(r.Context(), session, &transferId, accept)
+	}
+}
+
+func (h *BusinessHandler) handleAcceptTransfer(w http.ResponseWriter, r *http.Request) error {
+	return h.handleRespondToTransfer(true)(w, r)
+}
+
+func (h *BusinessHandler) handleDeclineTransfer(w http.ResponseWriter, r *http.Request) error {
+	return h.handleRespondToTransfer(false)(w, r)
+}
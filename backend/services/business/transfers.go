@@ -0,0 +1,309 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+const transferTTL = 14 * 24 * time.Hour
+
+// Looks up the user that will receive a business, who must be active and not already the owner
+func getTransferRecipient(ctx context.Context, pq *db.PgxQueries, business *models.Business, userId *uuid.UUID) (*models.User, error) {
+	recipient, err := pq.GetUserForId(ctx, userId)
+	if err != nil {
+		if errors.Is(err, db.ErrNoRows) {
+			return nil, services.NewNotFoundServiceError(err)
+		}
+		return nil, err
+	}
+	if recipient.Status != models.USER_STATUS_ACTIVE {
+		return nil, services.NewDataConflictServiceError(nil, "Recipient account is not active")
+	}
+	if recipient.DeletionScheduledAt != nil {
+		return nil, services.NewDataConflictServiceError(nil, "Recipient account is scheduled for deletion")
+	}
+	if recipient.Id == business.UserId {
+		return nil, services.NewDataConflictServiceError(nil, "User already owns this business")
+	}
+	return recipient, nil
+}
+
+// Checks that the transfer is still pending for the user and that the business has not changed hands since
+func validateTransferResponse(transfer *models.BusinessTransfer, business *models.Business, userId *uuid.UUID) error {
+	if transfer.ToUserId != *userId {
+		return services.NewNotFoundServiceError(nil)
+	}
+	if !transfer.IsPending() {
+		return services.NewDataConflictServiceError(nil, "Transfer is no longer pending")
+	}
+	if business.UserId != transfer.FromUserId {
+		return services.NewDataConflictServiceError(nil, "Business owner has changed")
+	}
+	return nil
+}
+
+func (h *BusinessHandler) GetBusinessTransfers(ctx context.Context, session *sessions.Session, businessId *uuid.UUID) ([]models.BusinessTransfer, error) {
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.BusinessTransfer, error) {
+		user, business, err := h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_TRANSFER, business, nil); err != nil {
+			return nil, err
+		}
+		return pq.GetBusinessTransfers(ctx, businessId)
+	})
+}
+
+func (h *BusinessHandler) RequestBusinessTransfer(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, data *models.BusinessTransferCreate) (*models.BusinessTransfer, error) {
+	if err := session.RequireInteractive(); err != nil {
+		return nil, err
+	}
+	if err := models.ValidateData(data); err != nil {
+		return nil, err
+	}
+
+	var owner, recipient *models.User
+	var business *models.Business
+	transfer, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.BusinessTransfer, error) {
+		var user *models.User
+		var err error
+		user, business, err = h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_TRANSFER, business, nil); err != nil {
+			return nil, err
+		}
+
+		owner, err = pq.GetUserForId(ctx, &business.UserId)
+		if err != nil {
+			return nil, err
+		}
+		recipient, err = getTransferRecipient(ctx, pq, business, &data.UserId)
+		if err != nil {
+			return nil, err
+		}
+
+		transfer, err := pq.CreateBusinessTransfer(ctx, businessId, &owner.Id, &recipient.Id, &user.Id, models.BUSINESS_TRANSFER_STATUS_PENDING, nil, time.Now().Add(transferTTL))
+		if err != nil {
+			if errors.Is(err, db.ErrUnique) {
+				return nil, services.NewDataConflictServiceError(err, "A transfer is already pending for this business")
+			}
+			return nil, err
+		}
+		return transfer, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h.sendBusinessTransferNotifications(business, transfer, owner, recipient, recipient)
+
+	return transfer, nil
+}
+
+func (h *BusinessHandler) CancelBusinessTransfer(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, transferId *uuid.UUID) error {
+	var owner, recipient *models.User
+	var business *models.Business
+	var transfer *models.BusinessTransfer
+	err := db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		var user *models.User
+		var err error
+		user, business, err = h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_TRANSFER, business, nil); err != nil {
+			return err
+		}
+
+		transfer, err = pq.GetBusinessTransfer(ctx, transferId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		if transfer.BusinessId != business.Id {
+			return services.NewNotFoundServiceError(nil)
+		}
+		if !transfer.IsPending() {
+			return services.NewDataConflictServiceError(nil, "Transfer is no longer pending")
+		}
+
+		if err := pq.SetBusinessTransferStatus(ctx, transferId, models.BUSINESS_TRANSFER_STATUS_CANCELLED); err != nil {
+			return err
+		}
+		transfer.Status = models.BUSINESS_TRANSFER_STATUS_CANCELLED
+
+		owner, err = pq.GetUserForId(ctx, &transfer.FromUserId)
+		if err != nil {
+			return err
+		}
+		recipient, err = pq.GetUserForId(ctx, &transfer.ToUserId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	h.sendBusinessTransferNotifications(business, transfer, owner, recipient, recipient)
+
+	return nil
+}
+
+func (h *BusinessHandler) GetUserTransfers(ctx context.Context, session *sessions.Session) ([]models.UserBusinessTransfer, error) {
+	userId := session.GetUserId()
+	if userId == nil {
+		return nil, services.NewUnauthenticatedServiceError(nil)
+	}
+	if err := session.RequireScope(models.TOKEN_SCOPE_BUSINESSES_READ); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.UserBusinessTransfer, error) {
+		return pq.GetPendingTransfersForUser(ctx, userId)
+	})
+}
+
+// Accepts or declines a transfer offered to the session user
+func (h *BusinessHandler) RespondToTransfer(ctx context.Context, session *sessions.Session, transferId *uuid.UUID, accept bool) error {
+	userId := session.GetUserId()
+	if userId == nil {
+		return services.NewUnauthenticatedServiceError(nil)
+	}
+	if err := session.RequireInteractive(); err != nil {
+		return err
+	}
+
+	var owner, recipient *models.User
+	var business *models.Business
+	var transfer *models.BusinessTransfer
+	err := db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		var err error
+		transfer, err = pq.GetBusinessTransfer(ctx, transferId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		if transfer.ToUserId != *userId {
+			return services.NewNotFoundServiceError(nil)
+		}
+
+		// The business stays locked until the owner is set, so a concurrent transfer can not move it first
+		business, err = pq.GetBusinessForIdForUpdate(ctx, &transfer.BusinessId)
+		if err != nil {
+			return err
+		}
+		if err := validateTransferResponse(transfer, business, userId); err != nil {
+			return err
+		}
+
+		owner, err = pq.GetUserForId(ctx, &transfer.FromUserId)
+		if err != nil {
+			return err
+		}
+
+		if !accept {
+			recipient, err = pq.GetUserForId(ctx, userId)
+			if err != nil {
+				return err
+			}
+			transfer.Status = models.BUSINESS_TRANSFER_STATUS_DECLINED
+			return pq.SetBusinessTransferStatus(ctx, transferId, transfer.Status)
+		}
+
+		// The recipient may have been banned or disabled since the transfer was offered
+		recipient, err = getTransferRecipient(ctx, pq, business, userId)
+		if err != nil {
+			return err
+		}
+		if err := pq.SetBusinessOwner(ctx, &business.Id, userId); err != nil {
+			return err
+		}
+		transfer.Status = models.BUSINESS_TRANSFER_STATUS_ACCEPTED
+		return pq.SetBusinessTransferStatus(ctx, transferId, transfer.Status)
+	})
+	if err != nil {
+		return err
+	}
+
+	if accept {
+		h.sendBusinessTransferNotifications(business, transfer, owner, recipient, owner, recipient)
+	} else {
+		h.sendBusinessTransferNotifications(business, transfer, owner, recipient, owner)
+	}
+
+	return nil
+}
+
+// Moves ownership immediately, cancelling any pending transfer for the business
+func (h *BusinessHandler) ForceBusinessTransfer(ctx context.Context, session *sessions.Session, businessId *uuid.UUID, data *models.BusinessTransferForce) (*models.BusinessTransfer, error) {
+	if err := models.ValidateData(data); err != nil {
+		return nil, err
+	}
+
+	var owner, recipient *models.User
+	var business *models.Business
+	transfer, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.BusinessTransfer, error) {
+		var user *models.User
+		var err error
+		user, business, err = h.getMemberContext(ctx, pq, session, businessId)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeBusinessAction(session, user, BUSINESS_ACTION_FORCE_TRANSFER, business, nil); err != nil {
+			return nil, err
+		}
+
+		owner, err = pq.GetUserForId(ctx, &business.UserId)
+		if err != nil {
+			return nil, err
+		}
+		recipient, err = getTransferRecipient(ctx, pq, business, &data.UserId)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := pq.CancelPendingBusinessTransfers(ctx, businessId); err != nil {
+			return nil, err
+		}
+		transfer, err := pq.CreateBusinessTransfer(ctx, businessId, &owner.Id, &recipient.Id, &user.Id, models.BUSINESS_TRANSFER_STATUS_FORCED, &data.Reason, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if err := pq.SetBusinessOwner(ctx, businessId, &recipient.Id); err != nil {
+			return nil, err
+		}
+		return transfer, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h.sendBusinessTransferNotifications(business, transfer, owner, recipient, owner, recipient)
+
+	return transfer, nil
+}
+
+func (h *BusinessHandler) sendBusinessTransferNotifications(business *models.Business, transfer *models.BusinessTransfer, from, to *models.User, recipients ...*models.User) {
+	for _, recipient := range recipients {
+		if !recipient.CanBeNotified() {
+			continue
+		}
+		err := h.notifications.Enqueue(context.Background(), h.newBusinessTransferNotification(recipient, from, to, business, transfer))
+		if err != nil {
+			h.logger.Warn("Failed to enqueue business transfer notification", "err", err, "transfer_id", transfer.Id)
+		}
+	}
+}
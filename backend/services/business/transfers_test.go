@@ -0,0 +1,232 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/john-vh/college_testing/backend/cache"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+func serviceErrorStatus(err error) int {
+	var serviceErr *services.ServiceError
+	if errors.As(err, &serviceErr) {
+		return serviceErr.StatusCode()
+	}
+	return 0
+}
+
+func TestValidateTransferResponse(t *testing.T) {
+	owner, recipient := uuid.New(), uuid.New()
+	business := &models.Business{}
+	business.UserId = owner
+
+	newTransfer := func(status models.BusinessTransferStatus, expiresAt time.Time) *models.BusinessTransfer {
+		return &models.BusinessTransfer{FromUserId: owner, ToUserId: recipient, Status: status, ExpiresAt: expiresAt}
+	}
+	later, earlier := time.Now().Add(time.Hour), time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		transfer *models.BusinessTransfer
+		userId   uuid.UUID
+		owner    uuid.UUID
+		want     int
+	}{
+		{"pending", newTransfer(models.BUSINESS_TRANSFER_STATUS_PENDING, later), recipient, owner, 0},
+		{"other user", newTransfer(models.BUSINESS_TRANSFER_STATUS_PENDING, later), uuid.New(), owner, http.StatusNotFound},
+		{"expired", newTransfer(models.BUSINESS_TRANSFER_STATUS_PENDING, earlier), recipient, owner, http.StatusConflict},
+		{"declined", newTransfer(models.BUSINESS_TRANSFER_STATUS_DECLINED, later), recipient, owner, http.StatusConflict},
+		{"accepted", newTransfer(models.BUSINESS_TRANSFER_STATUS_ACCEPTED, later), recipient, owner, http.StatusConflict},
+		{"owner changed", newTransfer(models.BUSINESS_TRANSFER_STATUS_PENDING, later), recipient, uuid.New(), http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			business.UserId = tt.owner
+			err := validateTransferResponse(tt.transfer, business, &tt.userId)
+			if got := serviceErrorStatus(err); got != tt.want {
+				t.Fatalf("got status %v (%v), want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// Connects to a migrated database given by TEST_DATABASE_URL
+func newTestStore(t *testing.T) *db.PgxStore {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	pgConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := db.NewPgxStorage(context.Background(), pgConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func newTestBusinessHandler(t *testing.T, store *db.PgxStore) *BusinessHandler {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sessionStore := cache.NewMemoryCache(0)
+	t.Cleanup(sessionStore.Close)
+	sessionsHandler := sessions.NewSessionHandler(logger, sessionStore, sessions.SessionsConfig{
+		IdleTTL:     time.Hour,
+		MaxLifetime: time.Hour * 24,
+	})
+	return &BusinessHandler{logger: logger, sessions: sessionsHandler, store: store}
+}
+
+func newTestUserSession(t *testing.T, h *BusinessHandler, userId *uuid.UUID) *sessions.Session {
+	t.Helper()
+	session, err := h.sessions.SetNewSession(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/auth/callback", nil), userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+// Creates users without accounts, so no notifications are sent for them
+func createTestTransfer(t *testing.T, h *BusinessHandler, expiresAt time.Time) (*models.BusinessTransfer, *uuid.UUID, *uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	var owner, recipient *uuid.UUID
+	transfer, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.BusinessTransfer, error) {
+		var err error
+		if owner, err = pq.CreateUser(ctx); err != nil {
+			return nil, err
+		}
+		if recipient, err = pq.CreateUser(ctx); err != nil {
+			return nil, err
+		}
+		business, err := pq.CreateBusiness(ctx, owner, &models.BusinessCreate{BusinessUpdate: models.BusinessUpdate{
+			Name:    "Transfer Test",
+			Desc:    "A business to transfer",
+			Website: "https://example.com",
+		}})
+		if err != nil {
+			return nil, err
+		}
+		return pq.CreateBusinessTransfer(ctx, &business.Id, owner, recipient, owner, models.BUSINESS_TRANSFER_STATUS_PENDING, nil, expiresAt)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transfer, owner, recipient
+}
+
+func getTestTransfer(t *testing.T, h *BusinessHandler, transferId *uuid.UUID) (*models.BusinessTransfer, *models.Business) {
+	t.Helper()
+	ctx := context.Background()
+	var business *models.Business
+	transfer, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.BusinessTransfer, error) {
+		transfer, err := pq.GetBusinessTransfer(ctx, transferId)
+		if err != nil {
+			return nil, err
+		}
+		business, err = pq.GetBusinessForId(ctx, &transfer.BusinessId)
+		return transfer, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transfer, business
+}
+
+func TestRespondToTransfer(t *testing.T) {
+	h := newTestBusinessHandler(t, newTestStore(t))
+	ctx := context.Background()
+
+	t.Run("accept", func(t *testing.T) {
+		transfer, _, recipient := createTestTransfer(t, h, time.Now().Add(transferTTL))
+		if err := h.RespondToTransfer(ctx, newTestUserSession(t, h, recipient), &transfer.Id, true); err != nil {
+			t.Fatal(err)
+		}
+		transfer, business := getTestTransfer(t, h, &transfer.Id)
+		if transfer.Status != models.BUSINESS_TRANSFER_STATUS_ACCEPTED {
+			t.Errorf("transfer status is %v, want %v", transfer.Status, models.BUSINESS_TRANSFER_STATUS_ACCEPTED)
+		}
+		if business.UserId != *recipient {
+			t.Errorf("business owner is %v, want the recipient %v", business.UserId, recipient)
+		}
+	})
+
+	t.Run("decline", func(t *testing.T) {
+		transfer, owner, recipient := createTestTransfer(t, h, time.Now().Add(transferTTL))
+		if err := h.RespondToTransfer(ctx, newTestUserSession(t, h, recipient), &transfer.Id, false); err != nil {
+			t.Fatal(err)
+		}
+		transfer, business := getTestTransfer(t, h, &transfer.Id)
+		if transfer.Status != models.BUSINESS_TRANSFER_STATUS_DECLINED {
+			t.Errorf("transfer status is %v, want %v", transfer.Status, models.BUSINESS_TRANSFER_STATUS_DECLINED)
+		}
+		if business.UserId != *owner {
+			t.Errorf("business owner is %v, want the previous owner %v", business.UserId, owner)
+		}
+
+		// Responses are final
+		err := h.RespondToTransfer(ctx, newTestUserSession(t, h, recipient), &transfer.Id, true)
+		if got := serviceErrorStatus(err); got != http.StatusConflict {
+			t.Fatalf("accepting a declined transfer returned %v (%v), want %v", got, err, http.StatusConflict)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		transfer, owner, recipient := createTestTransfer(t, h, time.Now().Add(-time.Minute))
+		err := h.RespondToTransfer(ctx, newTestUserSession(t, h, recipient), &transfer.Id, true)
+		if got := serviceErrorStatus(err); got != http.StatusConflict {
+			t.Fatalf("accepting an expired transfer returned %v (%v), want %v", got, err, http.StatusConflict)
+		}
+		if _, business := getTestTransfer(t, h, &transfer.Id); business.UserId != *owner {
+			t.Errorf("business owner is %v, want the previous owner %v", business.UserId, owner)
+		}
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		transfer, _, _ := createTestTransfer(t, h, time.Now().Add(transferTTL))
+		otherUser, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*uuid.UUID, error) {
+			return pq.CreateUser(ctx)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = h.RespondToTransfer(ctx, newTestUserSession(t, h, otherUser), &transfer.Id, true)
+		if got := serviceErrorStatus(err); got != http.StatusNotFound {
+			t.Fatalf("accepting another user's transfer returned %v (%v), want %v", got, err, http.StatusNotFound)
+		}
+	})
+
+	t.Run("recipient disabled", func(t *testing.T) {
+		transfer, owner, recipient := createTestTransfer(t, h, time.Now().Add(transferTTL))
+		session := newTestUserSession(t, h, recipient)
+		err := db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+			return pq.SetUserStatus(ctx, recipient, models.USER_STATUS_DISABLED, nil, nil, owner)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = h.RespondToTransfer(ctx, session, &transfer.Id, true)
+		if got := serviceErrorStatus(err); got != http.StatusConflict {
+			t.Fatalf("accepting as a disabled user returned %v (%v), want %v", got, err, http.StatusConflict)
+		}
+		if _, business := getTestTransfer(t, h, &transfer.Id); business.UserId != *owner {
+			t.Errorf("business owner is %v, want the previous owner %v", business.UserId, owner)
+		}
+	})
+}
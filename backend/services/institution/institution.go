@@ -0,0 +1,189 @@
+package institution
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+type InstitutionHandler struct {
+	logger    *slog.Logger
+	sessions  *sessions.SessionsHandler
+	store     *db.PgxStore
+	handleErr services.ServicesHTTPErrorHandler
+}
+
+func NewInstitutionHandler(
+	logger *slog.Logger,
+	errHandler services.ServicesHTTPErrorHandler,
+	sessions *sessions.SessionsHandler,
+	store *db.PgxStore,
+) *InstitutionHandler {
+	return &InstitutionHandler{
+		logger:    logger,
+		sessions:  sessions,
+		store:     store,
+		handleErr: errHandler,
+	}
+}
+
+func (h *InstitutionHandler) GetInstitutions(ctx context.Context, session *sessions.Session) ([]models.Institution, error) {
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.Institution, error) {
+		if err := h.authorize(ctx, pq, session, INSTITUTION_ACTION_READ); err != nil {
+			return nil, err
+		}
+		return pq.GetInstitutions(ctx)
+	})
+}
+
+func (h *InstitutionHandler) GetInstitutionForId(ctx context.Context, session *sessions.Session, institutionId *uuid.UUID) (*models.Institution, error) {
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.Institution, error) {
+		if err := h.authorize(ctx, pq, session, INSTITUTION_ACTION_READ); err != nil {
+			return nil, err
+		}
+		institution, err := pq.GetInstitutionForId(ctx, institutionId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return nil, services.NewNotFoundServiceError(err)
+			}
+			return nil, err
+		}
+		return institution, nil
+	})
+}
+
+func (h *InstitutionHandler) CreateInstitution(ctx context.Context, session *sessions.Session, data *models.InstitutionCreate) (*models.Institution, error) {
+	if err := models.ValidateData(data); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.Institution, error) {
+		if err := h.authorize(ctx, pq, session, INSTITUTION_ACTION_MODIFY); err != nil {
+			return nil, err
+		}
+		institutionId, err := pq.CreateInstitution(ctx, data)
+		if err != nil {
+			if errors.Is(err, db.ErrUnique) {
+				return nil, services.NewDataConflictServiceError(err, "Domain already belongs to an institution")
+			}
+			return nil, err
+		}
+		// New domains may match users that are already verified
+		if err := pq.SyncDomainInstitutions(ctx, data.Domains); err != nil {
+			return nil, err
+		}
+		h.logger.Info("Created institution", "institution_id", institutionId, "name", data.Name)
+		return pq.GetInstitutionForId(ctx, institutionId)
+	})
+}
+
+func (h *InstitutionHandler) UpdateInstitution(ctx context.Context, session *sessions.Session, institutionId *uuid.UUID, data *models.InstitutionUpdate) error {
+	if err := models.ValidateData(data); err != nil {
+		return err
+	}
+
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		if err := h.authorize(ctx, pq, session, INSTITUTION_ACTION_MODIFY); err != nil {
+			return err
+		}
+		institution, err := pq.GetInstitutionForId(ctx, institutionId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		if err := pq.UpdateInstitution(ctx, institutionId, data); err != nil {
+			switch {
+			case errors.Is(err, db.ErrNoRows):
+				return services.NewNotFoundServiceError(err)
+			case errors.Is(err, db.ErrUnique):
+				return services.NewDataConflictServiceError(err, "Domain already belongs to an institution")
+			}
+			return err
+		}
+		h.logger.Info("Updated institution", "institution_id", institutionId)
+		// Users under removed domains may fall back to another institution, users under added ones join this one
+		return pq.SyncDomainInstitutions(ctx, append(institution.Domains, data.Domains...))
+	})
+}
+
+func (h *InstitutionHandler) DeleteInstitution(ctx context.Context, session *sessions.Session, institutionId *uuid.UUID) error {
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		if err := h.authorize(ctx, pq, session, INSTITUTION_ACTION_MODIFY); err != nil {
+			return err
+		}
+		institution, err := pq.GetInstitutionForId(ctx, institutionId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		if err := pq.DeleteInstitution(ctx, institutionId); err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		h.logger.Info("Deleted institution", "institution_id", institutionId)
+		// Users of the deleted institution may match another one through a different email
+		return pq.SyncDomainInstitutions(ctx, institution.Domains)
+	})
+}
+
+func (h *InstitutionHandler) authorize(ctx context.Context, pq *db.PgxQueries, session *sessions.Session, action InstitutionAction) error {
+	userId := session.GetUserId()
+	if userId == nil {
+		return services.NewUnauthenticatedServiceError(nil)
+	}
+	if action != INSTITUTION_ACTION_READ {
+		if err := session.RequireInteractive(); err != nil {
+			return err
+		}
+	}
+
+	user, err := pq.GetUserForId(ctx, userId)
+	if err != nil {
+		return services.NewUnauthenticatedServiceError(err)
+	}
+	return AuthorizeInstitutionAction(user, action)
+}
+
+type InstitutionAction string
+
+const (
+	INSTITUTION_ACTION_READ   InstitutionAction = "institution:read"
+	INSTITUTION_ACTION_MODIFY InstitutionAction = "institution:modify"
+)
+
+func AuthorizeInstitutionAction(user *models.User, action InstitutionAction) error {
+	if user == nil {
+		return services.NewUnauthenticatedServiceError(nil)
+	}
+
+	for _, role := range user.Roles {
+		switch role {
+		case models.USER_ROLE_ADMIN:
+			switch action {
+			case INSTITUTION_ACTION_READ:
+				return nil
+			case INSTITUTION_ACTION_MODIFY:
+				return nil
+			}
+		case models.USER_ROLE_USER:
+			switch action {
+			case INSTITUTION_ACTION_READ:
+				return nil
+			}
+		}
+	}
+
+	return services.NewUnauthorizedServiceError(nil)
+}
@@ -0,0 +1,114 @@
+package institution
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+)
+
+const (
+	institutionIdParam = "institutionId"
+)
+
+func (h *InstitutionHandler) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /institutions", h.handleErr(h.handleGetInstitutions))
+	router.HandleFunc("GET /institutions/{institutionId}", h.handleErr(h.handleGetInstitution))
+
+	router.HandleFunc("POST /admin/institutions", h.handleErr(h.handleCreateInstitution))
+	router.HandleFunc("PUT /admin/institutions/{institutionId}", h.handleErr(h.handleUpdateInstitution))
+	router.HandleFunc("DELETE /admin/institutions/{institutionId}", h.handleErr(h.handleDeleteInstitution))
+}
+
+func (h *InstitutionHandler) handleGetInstitutions(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	institutions, err := h.GetInstitutions(r.Context(), session)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(institutions)
+	return nil
+}
+
+func (h *InstitutionHandler) handleGetInstitution(w http.ResponseWriter, r *http.Request) error {
+	institutionId, err := uuid.Parse(r.PathValue(institutionIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	institution, err := h.GetInstitutionForId(r.Context(), session, &institutionId)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(institution)
+	return nil
+}
+
+func (h *InstitutionHandler) handleCreateInstitution(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.InstitutionCreate{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	institution, err := h.CreateInstitution(r.Context(), session, &data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(institution)
+	return nil
+}
+
+func (h *InstitutionHandler) handleUpdateInstitution(w http.ResponseWriter, r *http.Request) error {
+	institutionId, err := uuid.Parse(r.PathValue(institutionIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.InstitutionUpdate{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	return h.UpdateInstitution(r.Context(), session, &institutionId, &data)
+}
+
+func (h *InstitutionHandler) handleDeleteInstitution(w http.ResponseWriter, r *http.Request) error {
+	institutionId, err := uuid.Parse(r.PathValue(institutionIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.DeleteInstitution(r.Context(), session, &institutionId)
+}
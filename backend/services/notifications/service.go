@@ -23,6 +23,13 @@ type Notification interface {
 	ShouldNotify() bool
 }
 
+// Notifications that must reach an address other than the recipient's primary email,
+// such as a school email that is being verified
+type AddressedNotification interface {
+	Notification
+	Address() string
+}
+
 func NewNotificationService(mailClient *MailClient, frontendURL, templatesPath string, logger *slog.Logger) *NotificationsService {
 	const notificationBufferSize = 8
 	return &NotificationsService{
@@ -48,16 +55,25 @@ func (ns *NotificationsService) run() {
 		if !noti.ShouldNotify() {
 			continue
 		}
-		user := noti.To()
+		// Addressed notifications may be sent to people without an account
+		var address string
+		if addressed, ok := noti.(AddressedNotification); ok {
+			address = addressed.Address()
+		} else if noti.To().EmailVerified {
+			address = noti.To().Email
+		} else {
+			ns.logger.Debug("Skipping notification to unverified email", "user_id", noti.To().Id)
+			continue
+		}
 		body, err := noti.HTML()
 		if err != nil {
 			ns.logger.Warn("Failed to parse body of notification", "err", err)
 			return
 		}
 		err = ns.mailClient.SendMsg(
-			[]string{user.Email},
+			[]string{address},
 			&MailInfo{
-				ToList:  []string{user.Email},
+				ToList:  []string{address},
 				Subject: noti.Subject(),
 				Body:    body,
 			})
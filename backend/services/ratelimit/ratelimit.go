@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+	"github.com/john-vh/college_testing/backend/util"
+)
+
+type KeyType string
+
+const (
+	// Limit each client address
+	KEY_IP KeyType = "ip"
+	// Limit each signed in user, falling back to the client address for anonymous requests
+	KEY_USER KeyType = "user"
+)
+
+type Rule struct {
+	// Route pattern in http.ServeMux syntax
+	Pattern string `json:"pattern"`
+	// Rules in the same group share one budget, defaults to the pattern
+	Group  string   `json:"group"`
+	Key    KeyType  `json:"key"`
+	Limit  int      `json:"limit"`
+	Window Duration `json:"window"`
+}
+
+// time.Duration that reads from JSON strings such as "1m" or "30s"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Reads a JSON array of rules
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("Invalid rate limits file %v: %w", path, err)
+	}
+	return rules, nil
+}
+
+type Limiter struct {
+	logger   *slog.Logger
+	store    Store
+	sessions *sessions.SessionsHandler
+	mux      *http.ServeMux
+	rules    map[string]Rule
+}
+
+func NewLimiter(logger *slog.Logger, store Store, sessions *sessions.SessionsHandler, rules []Rule) (*Limiter, error) {
+	l := &Limiter{
+		logger:   logger,
+		store:    store,
+		sessions: sessions,
+		mux:      http.NewServeMux(),
+		rules:    make(map[string]Rule),
+	}
+
+	for _, rule := range rules {
+		if rule.Limit <= 0 || rule.Window <= 0 {
+			return nil, fmt.Errorf("Rate limit for %v needs a positive limit and window", rule.Pattern)
+		}
+		switch rule.Key {
+		case "":
+			rule.Key = KEY_IP
+		case KEY_IP, KEY_USER:
+		default:
+			return nil, fmt.Errorf("Unknown rate limit key %v for %v", rule.Key, rule.Pattern)
+		}
+		if rule.Group == "" {
+			rule.Group = rule.Pattern
+		}
+		l.mux.Handle(rule.Pattern, http.NotFoundHandler())
+		l.rules[rule.Pattern] = rule
+	}
+
+	return l, nil
+}
+
+func (l *Limiter) Middleware(next http.Handler) http.HandlerFunc {
+	return services.HandleHTTPError(func(w http.ResponseWriter, r *http.Request) error {
+		_, pattern := l.mux.Handler(r)
+		rule, ok := l.rules[pattern]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return nil
+		}
+
+		res, err := l.store.Allow(r.Context(), rule.Group+":"+l.clientKey(r, rule.Key), rule.Limit, time.Duration(rule.Window))
+		if err != nil {
+			// Fail open, an unavailable store should not take the API down with it
+			l.logger.Warn("Failed to check rate limit", "group", rule.Group, "err", err)
+			next.ServeHTTP(w, r)
+			return nil
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rule.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if !res.Allowed {
+			l.logger.Debug("Rate limited request", "group", rule.Group, "path", r.URL.Path)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+			return services.NewServiceError(nil, http.StatusTooManyRequests, "Too many requests")
+		}
+
+		next.ServeHTTP(w, r)
+		return nil
+	})
+}
+
+func (l *Limiter) clientKey(r *http.Request, key KeyType) string {
+	if key == KEY_USER {
+		if session, err := l.sessions.GetSession(r); err == nil {
+			if userId := session.GetUserId(); userId != nil {
+				return "user:" + userId.String()
+			}
+		}
+	}
+	return "ip:" + util.RemoteIP(r)
+}
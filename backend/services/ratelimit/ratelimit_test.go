@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(t *testing.T, rules []Rule) http.Handler {
+	t.Helper()
+	limiter, err := NewLimiter(slog.New(slog.NewTextHandler(io.Discard, nil)), NewMemoryStore(), nil, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := http.NewServeMux()
+	router.HandleFunc("GET /auth/providers", func(w http.ResponseWriter, r *http.Request) {})
+	router.HandleFunc("GET /auth/{provider}", func(w http.ResponseWriter, r *http.Request) {})
+	return limiter.Middleware(router)
+}
+
+func get(handler http.Handler, path string, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLimiterRejectsOverLimit(t *testing.T) {
+	handler := newTestLimiter(t, []Rule{
+		{Pattern: "GET /auth/{provider}", Key: KEY_IP, Limit: 2, Window: Duration(time.Hour)},
+	})
+
+	for i := 0; i < 2; i++ {
+		rec := get(handler, "/auth/google", "192.0.2.1:1234")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %v returned %v, want %v", i, rec.Code, http.StatusOK)
+		}
+		if remaining := rec.Header().Get("X-RateLimit-Remaining"); remaining != strconv.Itoa(1-i) {
+			t.Fatalf("request %v has %v remaining, want %v", i, remaining, 1-i)
+		}
+	}
+
+	rec := get(handler, "/auth/google", "192.0.2.1:1234")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit returned %v, want %v", rec.Code, http.StatusTooManyRequests)
+	}
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > int(time.Hour.Seconds()) {
+		t.Fatalf("Retry-After is %q, want seconds within the window", rec.Header().Get("Retry-After"))
+	}
+
+	// Other clients have their own budget
+	if rec := get(handler, "/auth/google", "192.0.2.2:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("request from another client returned %v, want %v", rec.Code, http.StatusOK)
+	}
+}
+
+func TestLimiterSharesGroupBudget(t *testing.T) {
+	handler := newTestLimiter(t, []Rule{
+		{Pattern: "GET /auth/providers", Group: "auth", Key: KEY_IP, Limit: 1, Window: Duration(time.Hour)},
+		{Pattern: "GET /auth/{provider}", Group: "auth", Key: KEY_IP, Limit: 1, Window: Duration(time.Hour)},
+	})
+
+	if rec := get(handler, "/auth/providers", "192.0.2.1:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("first request returned %v, want %v", rec.Code, http.StatusOK)
+	}
+	if rec := get(handler, "/auth/google", "192.0.2.1:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("request in the same group returned %v, want %v", rec.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestLimiterMatchesMostSpecificRule(t *testing.T) {
+	handler := newTestLimiter(t, []Rule{
+		{Pattern: "GET /auth/providers", Key: KEY_IP, Limit: 5, Window: Duration(time.Hour)},
+		{Pattern: "GET /auth/{provider}", Key: KEY_IP, Limit: 1, Window: Duration(time.Hour)},
+	})
+
+	for i := 0; i < 5; i++ {
+		if rec := get(handler, "/auth/providers", "192.0.2.1:1234"); rec.Code != http.StatusOK {
+			t.Fatalf("request %v returned %v, want %v", i, rec.Code, http.StatusOK)
+		}
+	}
+	if rec := get(handler, "/auth/google", "192.0.2.1:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("login request returned %v, want %v", rec.Code, http.StatusOK)
+	}
+}
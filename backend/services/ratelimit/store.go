@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/john-vh/college_testing/backend/cache"
+)
+
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+type Store interface {
+	// Counts a request against key and reports whether it fits within limit per window
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// Approximates a sliding window by weighting the previous fixed window's count by how much of it
+// still overlaps the sliding window
+func slidingWindow(now time.Time, window time.Duration, limit int, prev, curr int64) Result {
+	elapsed := now.Sub(now.Truncate(window))
+	weight := 1 - float64(elapsed)/float64(window)
+	count := int(float64(prev)*weight) + int(curr)
+
+	if count > limit {
+		retryAfter := window - elapsed
+		if retryAfter < time.Second {
+			retryAfter = time.Second
+		}
+		return Result{Allowed: false, Remaining: 0, RetryAfter: retryAfter}
+	}
+	return Result{Allowed: true, Remaining: limit - count}
+}
+
+func windowKeys(key string, now time.Time, window time.Duration) (string, string) {
+	start := now.Truncate(window)
+	return key + ":" + strconv.FormatInt(start.Unix(), 10),
+		key + ":" + strconv.FormatInt(start.Add(-window).Unix(), 10)
+}
+
+type CacheStore struct {
+	cache cache.Cache
+}
+
+func NewCacheStore(cache cache.Cache) *CacheStore {
+	return &CacheStore{cache: cache}
+}
+
+func (s *CacheStore) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	now := time.Now()
+	currKey, prevKey := windowKeys(key, now, window)
+
+	curr, err := s.cache.Increment(ctx, currKey, window*2)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var prev int64
+	b, err := s.cache.Get(ctx, prevKey)
+	switch {
+	case errors.Is(err, cache.ErrNotFound):
+	case err != nil:
+		return Result{}, err
+	default:
+		if prev, err = strconv.ParseInt(string(b), 10, 64); err != nil {
+			return Result{}, err
+		}
+	}
+
+	return slidingWindow(now, window, limit, prev, curr), nil
+}
+
+// Keeps counters in process memory, for tests and single instance development servers
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]memoryCounter
+}
+
+type memoryCounter struct {
+	count   int64
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]memoryCounter)}
+}
+
+func (s *MemoryStore) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	now := time.Now()
+	currKey, prevKey := windowKeys(key, now, window)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, c := range s.counters {
+		if now.After(c.expires) {
+			delete(s.counters, k)
+		}
+	}
+
+	curr, ok := s.counters[currKey]
+	if !ok {
+		curr.expires = now.Add(window * 2)
+	}
+	curr.count++
+	s.counters[currKey] = curr
+
+	return slidingWindow(now, window, limit, s.counters[prevKey].count, curr.count), nil
+}
@@ -0,0 +1,140 @@
+package sessions
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/cache"
+	"github.com/john-vh/college_testing/backend/models"
+)
+
+func newTestSessions(t *testing.T, cfg SessionsConfig) *SessionsHandler {
+	t.Helper()
+	store := cache.NewMemoryCache(0)
+	t.Cleanup(store.Close)
+	return NewSessionHandler(slog.New(slog.NewTextHandler(io.Discard, nil)), store, cfg)
+}
+
+var testSessionsConfig = SessionsConfig{
+	IdleTTL:          time.Hour,
+	MaxLifetime:      time.Hour * 24,
+	UnauthorizedTTL:  time.Hour,
+	ImpersonationTTL: time.Hour,
+}
+
+// Signs a new user in and returns their session cookie
+func newTestSession(t *testing.T, h *SessionsHandler) (*Session, *http.Cookie) {
+	t.Helper()
+	userId := uuid.New()
+	rec := httptest.NewRecorder()
+	session, err := h.SetNewSession(rec, httptest.NewRequest(http.MethodPost, "/auth/callback", nil), &userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == session_cookie_name {
+			return session, c
+		}
+	}
+	t.Fatal("new session did not set a cookie")
+	return nil, nil
+}
+
+type testTokens struct {
+	token *models.APIToken
+}
+
+func (tt *testTokens) AuthenticateToken(ctx context.Context, token string) (*models.APIToken, error) {
+	return tt.token, nil
+}
+
+func newTestCSRF(h *SessionsHandler, cfg CSRFConfig) http.Handler {
+	router := http.NewServeMux()
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	return h.SessionMiddleware(h.CSRFMiddleware(cfg)(router))
+}
+
+func serveCSRF(handler http.Handler, method string, path string, cookie *http.Cookie, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	h := newTestSessions(t, testSessionsConfig)
+	h.SetTokenAuthenticator(&testTokens{token: &models.APIToken{UserId: uuid.New()}})
+	handler := newTestCSRF(h, CSRFConfig{
+		Rotation: CSRF_ROTATE_PER_SESSION,
+		Exempt:   []string{"POST /auth/logout"},
+	})
+	session, cookie := newTestSession(t, h)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		cookie  *http.Cookie
+		headers map[string]string
+		want    int
+	}{
+		{"safe method", http.MethodGet, "/users/me", cookie, nil, http.StatusOK},
+		{"missing token", http.MethodPost, "/businesses", cookie, nil, http.StatusForbidden},
+		{"invalid token", http.MethodPost, "/businesses", cookie, map[string]string{csrf_header_name: "invalid"}, http.StatusForbidden},
+		{"valid token", http.MethodPost, "/businesses", cookie, map[string]string{csrf_header_name: session.data.CSRFToken}, http.StatusOK},
+		{"exempt route", http.MethodPost, "/auth/logout", cookie, nil, http.StatusOK},
+		{"exempt pattern only", http.MethodDelete, "/auth/logout", cookie, nil, http.StatusForbidden},
+		{"no session", http.MethodPost, "/businesses", nil, nil, http.StatusOK},
+		{"token session", http.MethodPost, "/businesses", nil, map[string]string{"Authorization": "Bearer token"}, http.StatusOK},
+		{"token session with cookie", http.MethodPost, "/businesses", cookie, map[string]string{"Authorization": "Bearer token"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveCSRF(handler, tt.method, tt.path, tt.cookie, tt.headers)
+			if rec.Code != tt.want {
+				t.Fatalf("%v %v returned %v, want %v", tt.method, tt.path, rec.Code, tt.want)
+			}
+		})
+	}
+
+	// Safe requests hand the token to the client
+	rec := serveCSRF(handler, http.MethodGet, "/users/me", cookie, nil)
+	if token := rec.Header().Get(csrf_header_name); token != session.data.CSRFToken {
+		t.Fatalf("safe request returned token %q, want the session's token", token)
+	}
+}
+
+func TestCSRFMiddlewareRotatesPerRequest(t *testing.T) {
+	h := newTestSessions(t, testSessionsConfig)
+	handler := newTestCSRF(h, CSRFConfig{Rotation: CSRF_ROTATE_PER_REQUEST})
+	session, cookie := newTestSession(t, h)
+
+	oldToken := session.data.CSRFToken
+	rec := serveCSRF(handler, http.MethodPost, "/businesses", cookie, map[string]string{csrf_header_name: oldToken})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("request with a valid token returned %v, want %v", rec.Code, http.StatusOK)
+	}
+	newToken := rec.Header().Get(csrf_header_name)
+	if newToken == "" || newToken == oldToken {
+		t.Fatalf("accepted request returned token %q, want a new token", newToken)
+	}
+
+	if rec := serveCSRF(handler, http.MethodPost, "/businesses", cookie, map[string]string{csrf_header_name: oldToken}); rec.Code != http.StatusForbidden {
+		t.Fatalf("request with the replaced token returned %v, want %v", rec.Code, http.StatusForbidden)
+	}
+	if rec := serveCSRF(handler, http.MethodPost, "/businesses", cookie, map[string]string{csrf_header_name: newToken}); rec.Code != http.StatusOK {
+		t.Fatalf("request with the new token returned %v, want %v", rec.Code, http.StatusOK)
+	}
+}
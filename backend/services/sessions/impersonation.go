@@ -0,0 +1,207 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/cache"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+)
+
+const (
+	impersonation_header_name = "X-Impersonated-By"
+	defaultImpersonationTTL   = time.Minute * 30
+)
+
+// Records the start, end and every request of an impersonation session
+type ImpersonationAuditor interface {
+	RecordImpersonation(ctx context.Context, entry *models.ImpersonationLogEntry) error
+}
+
+func (h *SessionsHandler) SetImpersonationAuditor(auditor ImpersonationAuditor) {
+	h.auditor = auditor
+}
+
+// Wrapped by ImpersonatorValidator errors for admins that may no longer act as other users
+var ErrImpersonationRevoked = errors.New("Impersonation is no longer allowed")
+
+// Checks on every impersonated request that the impersonator may still act as other users
+type ImpersonatorValidator interface {
+	ValidateImpersonator(ctx context.Context, impersonatorId *uuid.UUID) error
+}
+
+func (h *SessionsHandler) SetImpersonatorValidator(impersonators ImpersonatorValidator) {
+	h.impersonators = impersonators
+}
+
+// Impersonation ends as soon as the admin behind it may no longer use the API or impersonate
+func (h *SessionsHandler) validateImpersonator(ctx context.Context, session *Session) error {
+	if !session.IsImpersonating() {
+		return nil
+	}
+	if err := h.ValidateUser(ctx, session.data.ImpersonatorId); err != nil {
+		return err
+	}
+	if h.impersonators == nil {
+		return nil
+	}
+	return h.impersonators.ValidateImpersonator(ctx, session.data.ImpersonatorId)
+}
+
+func (s *Session) GetImpersonatorId() *uuid.UUID {
+	return s.data.ImpersonatorId
+}
+
+func (s *Session) IsImpersonating() bool {
+	return s.data.ImpersonatorId != nil
+}
+
+// Describes the impersonation, or returns nil for regular sessions
+func (s *Session) Impersonation() *models.Impersonation {
+	if !s.IsImpersonating() || s.data.UserId == nil {
+		return nil
+	}
+	return &models.Impersonation{
+		UserId:         *s.data.UserId,
+		ImpersonatorId: *s.data.ImpersonatorId,
+		ExpiresAt:      s.data.ExpiresAt,
+	}
+}
+
+// Replaces the impersonator's session cookie with a short lived session acting as the user. The
+// impersonator's session is kept and restored by EndImpersonation.
+func (h *SessionsHandler) StartImpersonation(w http.ResponseWriter, r *http.Request, userId *uuid.UUID, reason string) (*Session, error) {
+	if h.auditor == nil {
+		return nil, services.NewInternalServiceError(errors.New("Impersonation requires an auditor"))
+	}
+
+	current, err := h.GetSession(r)
+	if err != nil {
+		return nil, err
+	}
+	if current.GetUserId() == nil {
+		return nil, services.NewUnauthenticatedServiceError(nil)
+	}
+	if err := current.RequireInteractive(); err != nil {
+		return nil, err
+	}
+
+	session, err := h.newSessionFromUserId(r, userId)
+	if err != nil {
+		return nil, err
+	}
+	ttl := h.cfg.ImpersonationTTL
+	if ttl <= 0 {
+		ttl = defaultImpersonationTTL
+	}
+	if expiresAt := session.data.CreatedAt.Add(ttl); expiresAt.Before(session.data.ExpiresAt) {
+		session.data.ExpiresAt = expiresAt
+		session.ttl = ttl
+	}
+	session.data.ImpersonatorId = current.GetUserId()
+	session.data.ReturnSessionId = current.Id
+
+	// Nothing is done as the user unless the start was recorded
+	if err := h.auditor.RecordImpersonation(r.Context(), impersonationLogEntry(r, session, models.IMPERSONATION_ACTION_START, nil, &reason)); err != nil {
+		return nil, err
+	}
+
+	if err := h.saveSessionToStore(r.Context(), session); err != nil {
+		return nil, err
+	}
+	h.saveSessionToResponse(w, session)
+
+	h.logger.Info("Started impersonation", "user_id", userId, "impersonator_id", session.data.ImpersonatorId)
+	return session, nil
+}
+
+// Ends the impersonation session of the request, restoring the impersonator's own session if it is
+// still valid
+func (h *SessionsHandler) EndImpersonation(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.GetSession(r)
+	if err != nil {
+		return err
+	}
+	if !session.IsImpersonating() {
+		return services.NewNotFoundServiceError(nil)
+	}
+
+	if h.auditor != nil {
+		if err := h.auditor.RecordImpersonation(r.Context(), impersonationLogEntry(r, session, models.IMPERSONATION_ACTION_END, nil, nil)); err != nil {
+			h.logger.Warn("Failed to record end of impersonation", "err", err)
+		}
+	}
+	if err := h.deleteSessionFromStore(r.Context(), session); err != nil {
+		return err
+	}
+	h.logger.Info("Ended impersonation", "user_id", session.data.UserId, "impersonator_id", session.data.ImpersonatorId)
+
+	restored, err := h.getSessionFromStore(r.Context(), session.data.ReturnSessionId)
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		return err
+	}
+	if err == nil && restored.data.UserId != nil && *restored.data.UserId == *session.data.ImpersonatorId {
+		h.saveSessionToResponse(w, restored)
+		return nil
+	}
+
+	// The impersonator's own session expired in the meantime, they have to sign in again
+	http.SetCookie(w, &http.Cookie{
+		Name:     session_cookie_name,
+		Value:    "",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		Path:     "/",
+		SameSite: http.SameSiteNoneMode,
+	})
+	return nil
+}
+
+// Serves a request made while impersonating, flagging the response and recording the request
+func (h *SessionsHandler) serveImpersonated(w http.ResponseWriter, r *http.Request, session *Session, next http.Handler) {
+	w.Header().Set(impersonation_header_name, session.data.ImpersonatorId.String())
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(rec, r)
+
+	if h.auditor == nil {
+		return
+	}
+	// The request is recorded even if the client has gone away
+	ctx := context.WithoutCancel(r.Context())
+	if err := h.auditor.RecordImpersonation(ctx, impersonationLogEntry(r, session, models.IMPERSONATION_ACTION_REQUEST, &rec.status, nil)); err != nil {
+		h.logger.Error("Failed to record impersonated request", "err", err, "impersonator_id", session.data.ImpersonatorId, "path", r.URL.Path)
+	}
+}
+
+func impersonationLogEntry(r *http.Request, session *Session, action models.ImpersonationAction, status *int, reason *string) *models.ImpersonationLogEntry {
+	return &models.ImpersonationLogEntry{
+		ImpersonatorId: *session.data.ImpersonatorId,
+		UserId:         *session.data.UserId,
+		SessionId:      session.PublicId(),
+		Action:         action,
+		Method:         r.Method,
+		Path:           r.URL.Path,
+		StatusCode:     status,
+		Reason:         reason,
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -2,13 +2,19 @@ package sessions
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/john-vh/college_testing/backend/cache"
+	"github.com/john-vh/college_testing/backend/models"
 	"github.com/john-vh/college_testing/backend/services"
 	"github.com/john-vh/college_testing/backend/util"
 )
@@ -16,45 +22,217 @@ import (
 const (
 	session_cookie_name = "session"
 	csrf_header_name    = "X-CSRF-TOKEN"
+	user_sessions_key   = "user_sessions:"
+	renewInterval       = time.Minute * 5
+	rotationGracePeriod = time.Second * 30
 )
 
+type sessionContextKey struct{}
+
 type sessionData struct {
 	UserId    *uuid.UUID
 	CSRFToken string
+	IP        string
+	UserAgent string
+	CreatedAt time.Time
+	IssuedAt  time.Time
+	LastSeen  time.Time
+	ExpiresAt time.Time
+	// Set when an admin is acting as UserId
+	ImpersonatorId *uuid.UUID
+	// The impersonator's own session, restored once impersonation ends
+	ReturnSessionId string
 }
 
 type Session struct {
-	Id   string
-	ttl  time.Duration
-	data sessionData
+	Id    string
+	ttl   time.Duration
+	data  sessionData
+	token *models.APIToken
 }
 
 func (s *Session) GetUserId() *uuid.UUID {
 	return s.data.UserId
 }
 
+// The user whose session index lists the session, the impersonator for impersonation sessions
+func (s *Session) owner() *uuid.UUID {
+	if s.IsImpersonating() {
+		return s.data.ImpersonatorId
+	}
+	return s.data.UserId
+}
+
+func (s *Session) belongsTo(userId *uuid.UUID) bool {
+	owner := s.owner()
+	return owner != nil && *owner == *userId
+}
+
+// Reports whether the request was authenticated with a personal API token
+func (s *Session) IsToken() bool {
+	return s.token != nil
+}
+
+// Cookie sessions are unrestricted, token sessions are limited to the token's scopes
+func (s *Session) RequireScope(scope models.TokenScope) error {
+	if s.token == nil || s.token.HasScope(scope) {
+		return nil
+	}
+	return services.NewServiceError(nil, http.StatusForbidden, fmt.Sprintf("Token is missing scope %v", scope))
+}
+
+// Rejects requests authenticated with an API token or made while impersonating, for account
+// management actions
+func (s *Session) RequireInteractive() error {
+	if s.IsImpersonating() {
+		return services.NewServiceError(nil, http.StatusForbidden, "Action is not allowed while impersonating a user")
+	}
+	if s.token == nil {
+		return nil
+	}
+	return services.NewServiceError(nil, http.StatusForbidden, "Action is not allowed with an API token")
+}
+
+type TokenAuthenticator interface {
+	AuthenticateToken(ctx context.Context, token string) (*models.APIToken, error)
+}
+
+// Identifies the session to users without exposing the cookie value
+func (s *Session) PublicId() string {
+	sum := sha256.Sum256([]byte(s.Id))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+type SessionInfo struct {
+	Id        string    `json:"id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+}
+
+func (s *Session) Info() SessionInfo {
+	return SessionInfo{
+		Id:        s.PublicId(),
+		IP:        s.data.IP,
+		UserAgent: s.data.UserAgent,
+		CreatedAt: s.data.CreatedAt,
+		LastSeen:  s.data.LastSeen,
+		ExpiresAt: s.data.ExpiresAt,
+	}
+}
+
+type SessionsConfig struct {
+	// Authenticated sessions expire after this long without a request
+	IdleTTL time.Duration
+	// Authenticated sessions never outlive this, regardless of activity
+	MaxLifetime time.Duration
+	// Session ids are reissued once they are older than this, zero disables rotation
+	RotationInterval time.Duration
+	UnauthorizedTTL  time.Duration
+	// Impersonation sessions expire after this long, regardless of activity
+	ImpersonationTTL time.Duration
+}
+
 type SessionsHandler struct {
-	logger          *slog.Logger
-	store           cache.Cache
-	authorizedTTL   time.Duration
-	unauthorizedTTL time.Duration
+	logger  *slog.Logger
+	store   cache.Cache
+	cfg     SessionsConfig
+	tokens  TokenAuthenticator
+	users   UserValidator
+	auditor ImpersonationAuditor
+	// Optional, impersonators are only checked for being blocked when unset
+	impersonators ImpersonatorValidator
+}
+
+// Wrapped by UserValidator errors for users that may no longer use the API
+var ErrUserBlocked = errors.New("User is blocked")
+
+// Checks that a signed in user may still use the API, e.g. that they have not been banned
+type UserValidator interface {
+	ValidateUser(ctx context.Context, userId *uuid.UUID) error
 }
 
-func NewSessionHandler(logger *slog.Logger, store cache.Cache, authedTTL time.Duration, unauthedTTL time.Duration) *SessionsHandler {
+func NewSessionHandler(logger *slog.Logger, store cache.Cache, cfg SessionsConfig) *SessionsHandler {
 	if logger == nil {
 		logger = slog.Default()
 	}
 
 	return &SessionsHandler{
-		logger:          logger,
-		store:           store,
-		authorizedTTL:   authedTTL,
-		unauthorizedTTL: unauthedTTL,
+		logger: logger,
+		store:  store,
+		cfg:    cfg,
+	}
+}
+
+func (h *SessionsHandler) SetTokenAuthenticator(tokens TokenAuthenticator) {
+	h.tokens = tokens
+}
+
+func (h *SessionsHandler) SetUserValidator(users UserValidator) {
+	h.users = users
+}
+
+func (h *SessionsHandler) ValidateUser(ctx context.Context, userId *uuid.UUID) error {
+	if h.users == nil || userId == nil {
+		return nil
+	}
+	return h.users.ValidateUser(ctx, userId)
+}
+
+// Loads the session for the request, sliding its expiry and rotating its id as needed
+func (h *SessionsHandler) SessionMiddleware(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			services.HandleHTTPError(func(w http.ResponseWriter, r *http.Request) error {
+				session, err := h.getTokenSession(r.Context(), bearer)
+				if err != nil {
+					return err
+				}
+				if err := h.ValidateUser(r.Context(), session.data.UserId); err != nil {
+					return err
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, session)))
+				return nil
+			})(w, r)
+			return
+		}
+
+		session, err := h.getSessionFromRequest(r)
+		if err == nil {
+			if session.data.UserId != nil {
+				err := h.ValidateUser(r.Context(), session.data.UserId)
+				if err == nil {
+					err = h.validateImpersonator(r.Context(), session)
+				}
+				if err != nil {
+					// End the session so the user is signed out once they have seen why
+					if errors.Is(err, ErrUserBlocked) || errors.Is(err, ErrImpersonationRevoked) {
+						if err := h.deleteSessionFromStore(r.Context(), session); err != nil {
+							h.logger.Warn("Failed to delete session of blocked user", "err", err)
+						}
+					}
+					services.HandleHTTPError(func(w http.ResponseWriter, r *http.Request) error { return err })(w, r)
+					return
+				}
+				if err := h.renewSession(r, w, session); err != nil {
+					h.logger.Warn("Failed to renew session", "err", err)
+				}
+			}
+			r = r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, session))
+			if session.IsImpersonating() {
+				h.serveImpersonated(w, r, session, next)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
 	}
 }
 
 func (h *SessionsHandler) SetNewSession(w http.ResponseWriter, r *http.Request, userId *uuid.UUID) (*Session, error) {
-	newSession, err := h.newSessionFromUserId(userId)
+	newSession, err := h.newSessionFromUserId(r, userId)
 	if err != nil {
 		return nil, err
 	}
@@ -66,8 +244,11 @@ func (h *SessionsHandler) SetNewSession(w http.ResponseWriter, r *http.Request,
 
 	oldSessionId, err := h.getSessionIdFromCookie(r)
 	if err == nil { // Check if an old session exists
-		err = h.store.Delete(context.TODO(), oldSessionId)
-		if err != nil {
+		oldSession, err := h.getSessionFromStore(context.TODO(), oldSessionId)
+		if err == nil {
+			err = h.deleteSessionFromStore(context.TODO(), oldSession)
+		}
+		if err != nil && !errors.Is(err, cache.ErrNotFound) {
 			h.logger.Warn("Failed to delete old session.", "sessionId", oldSessionId)
 		}
 	}
@@ -78,6 +259,29 @@ func (h *SessionsHandler) SetNewSession(w http.ResponseWriter, r *http.Request,
 }
 
 func (h *SessionsHandler) GetSession(r *http.Request) (*Session, error) {
+	if session, ok := r.Context().Value(sessionContextKey{}).(*Session); ok {
+		return session, nil
+	}
+	return h.getSessionFromRequest(r)
+}
+
+func (h *SessionsHandler) getTokenSession(ctx context.Context, bearer string) (*Session, error) {
+	if h.tokens == nil {
+		return nil, services.NewUnauthenticatedServiceError(nil)
+	}
+
+	token, err := h.tokens.AuthenticateToken(ctx, strings.TrimSpace(bearer))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		data:  sessionData{UserId: &token.UserId},
+		token: token,
+	}, nil
+}
+
+func (h *SessionsHandler) getSessionFromRequest(r *http.Request) (*Session, error) {
 	sessionId, err := h.getSessionIdFromCookie(r)
 	if err != nil {
 		return nil, err
@@ -91,7 +295,128 @@ func (h *SessionsHandler) GetSession(r *http.Request) (*Session, error) {
 	return session, nil
 }
 
-func (h *SessionsHandler) newSessionFromUserId(userId *uuid.UUID) (*Session, error) {
+func (h *SessionsHandler) renewSession(r *http.Request, w http.ResponseWriter, session *Session) error {
+	now := time.Now()
+	if now.Sub(session.data.LastSeen) < renewInterval {
+		return nil
+	}
+
+	retiredId := ""
+	// Impersonation sessions keep their id so the audit log can follow them
+	if h.cfg.RotationInterval > 0 && now.Sub(session.data.IssuedAt) > h.cfg.RotationInterval && !session.IsImpersonating() {
+		newId, err := util.RandString(32)
+		if err != nil {
+			return err
+		}
+		retiredId = session.Id
+
+		session.Id = newId
+		session.data.IssuedAt = now
+		h.logger.Debug("Rotating session id", "user_id", session.data.UserId)
+	}
+
+	session.data.LastSeen = now
+	session.data.IP = util.RemoteIP(r)
+	session.data.UserAgent = r.UserAgent()
+	if !session.IsImpersonating() {
+		session.data.ExpiresAt = h.expiresAt(session.data.CreatedAt, now)
+	}
+	session.ttl = time.Until(session.data.ExpiresAt)
+	if session.ttl <= 0 {
+		return h.deleteSessionFromStore(r.Context(), session)
+	}
+
+	if err := h.saveSessionToStore(r.Context(), session); err != nil {
+		return err
+	}
+
+	if retiredId != "" {
+		// Keep the old id alive briefly so concurrent requests are not logged out. It is marked as just
+		// issued and seen, so it is neither renewed nor rotated again before it expires.
+		retired := session.data
+		retired.ExpiresAt = now.Add(rotationGracePeriod)
+		if data, err := json.Marshal(retired); err != nil {
+			h.logger.Warn("Failed to retire rotated session", "err", err)
+		} else if err := h.store.Set(r.Context(), retiredId, data, rotationGracePeriod); err != nil {
+			h.logger.Warn("Failed to retire rotated session", "err", err)
+		}
+		if session.data.UserId != nil {
+			if err := h.store.SetRemove(r.Context(), userSessionsKey(session.data.UserId), retiredId); err != nil {
+				h.logger.Warn("Failed to remove rotated session from index", "err", err)
+			}
+		}
+	}
+
+	h.saveSessionToResponse(w, session)
+	return nil
+}
+
+func (h *SessionsHandler) expiresAt(createdAt time.Time, lastSeen time.Time) time.Time {
+	idle := lastSeen.Add(h.cfg.IdleTTL)
+	absolute := createdAt.Add(h.cfg.MaxLifetime)
+	if idle.Before(absolute) {
+		return idle
+	}
+	return absolute
+}
+
+func (h *SessionsHandler) GetUserSessions(ctx context.Context, userId *uuid.UUID) ([]*Session, error) {
+	key := userSessionsKey(userId)
+	ids, err := h.store.SetMembers(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	userSessions := make([]*Session, 0, len(ids))
+	for _, id := range ids {
+		session, err := h.getSessionFromStore(ctx, id)
+		if errors.Is(err, cache.ErrNotFound) || (err == nil && !session.belongsTo(userId)) {
+			// Expired or replaced sessions are pruned from the index lazily
+			if err := h.store.SetRemove(ctx, key, id); err != nil {
+				h.logger.Warn("Failed to prune user session index", "err", err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		userSessions = append(userSessions, session)
+	}
+
+	return userSessions, nil
+}
+
+func (h *SessionsHandler) RevokeUserSession(ctx context.Context, userId *uuid.UUID, publicId string) error {
+	userSessions, err := h.GetUserSessions(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range userSessions {
+		if session.PublicId() == publicId {
+			return h.deleteSessionFromStore(ctx, session)
+		}
+	}
+
+	return services.NewNotFoundServiceError(nil)
+}
+
+func (h *SessionsHandler) RevokeUserSessions(ctx context.Context, userId *uuid.UUID) error {
+	key := userSessionsKey(userId)
+	ids, err := h.store.SetMembers(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	h.logger.Info("Revoking user sessions", "user_id", userId, "count", len(ids))
+	return h.store.Delete(ctx, append(ids, key)...)
+}
+
+func userSessionsKey(userId *uuid.UUID) string {
+	return user_sessions_key + userId.String()
+}
+
+func (h *SessionsHandler) newSessionFromUserId(r *http.Request, userId *uuid.UUID) (*Session, error) {
 	csrftoken, err := util.RandString(16)
 	if err != nil {
 		return nil, err
@@ -102,17 +427,24 @@ func (h *SessionsHandler) newSessionFromUserId(userId *uuid.UUID) (*Session, err
 		return nil, err
 	}
 
-	ttl := h.authorizedTTL
+	now := time.Now()
+	expiresAt := h.expiresAt(now, now)
 	if userId == nil {
-		ttl = h.unauthorizedTTL
+		expiresAt = now.Add(h.cfg.UnauthorizedTTL)
 	}
 
 	return &Session{
 		Id:  sessionId,
-		ttl: ttl,
+		ttl: expiresAt.Sub(now),
 		data: sessionData{
 			UserId:    userId,
 			CSRFToken: csrftoken,
+			IP:        util.RemoteIP(r),
+			UserAgent: r.UserAgent(),
+			CreatedAt: now,
+			IssuedAt:  now,
+			LastSeen:  now,
+			ExpiresAt: expiresAt,
 		},
 	}, nil
 }
@@ -128,6 +460,15 @@ func (h *SessionsHandler) saveSessionToStore(ctx context.Context, session *Sessi
 		return err
 	}
 
+	// Impersonation sessions are indexed under the impersonator, so revoking the admin's sessions ends
+	// them, rather than among the impersonated user's sessions
+	if owner := session.owner(); owner != nil {
+		err = h.store.SetAdd(ctx, userSessionsKey(owner), h.cfg.MaxLifetime, session.Id)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -161,8 +502,7 @@ func (h *SessionsHandler) getSessionFromStore(ctx context.Context, id string) (*
 	}
 
 	session := &Session{
-		Id:  id,
-		ttl: h.authorizedTTL,
+		Id: id,
 	}
 
 	err = json.Unmarshal(data, &session.data)
@@ -172,12 +512,32 @@ func (h *SessionsHandler) getSessionFromStore(ctx context.Context, id string) (*
 
 	if session.data.UserId == nil || (*session.data.UserId) == uuid.Nil {
 		session.data.UserId = nil
-		session.ttl = h.unauthorizedTTL
+	}
+
+	if session.data.ExpiresAt.IsZero() {
+		// Sessions saved before expiry tracking start their lifetime now
+		now := time.Now()
+		session.data.CreatedAt = now
+		session.data.IssuedAt = now
+		session.data.ExpiresAt = h.expiresAt(now, now)
+		if session.data.UserId == nil {
+			session.data.ExpiresAt = now.Add(h.cfg.UnauthorizedTTL)
+		}
+	}
+
+	session.ttl = time.Until(session.data.ExpiresAt)
+	if session.ttl <= 0 {
+		return nil, cache.ErrNotFound
 	}
 
 	return session, nil
 }
 
 func (h *SessionsHandler) deleteSessionFromStore(ctx context.Context, session *Session) error {
+	if owner := session.owner(); owner != nil {
+		if err := h.store.SetRemove(ctx, userSessionsKey(owner), session.Id); err != nil {
+			return err
+		}
+	}
 	return h.store.Delete(ctx, session.Id)
 }
@@ -0,0 +1,156 @@
+package sessions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Moves the session's timestamps into the past as if it had been idle since lastSeen
+func ageTestSession(t *testing.T, h *SessionsHandler, session *Session, created, issued, lastSeen time.Duration) {
+	t.Helper()
+	now := time.Now()
+	session.data.CreatedAt = now.Add(-created)
+	session.data.IssuedAt = now.Add(-issued)
+	session.data.LastSeen = now.Add(-lastSeen)
+	session.data.ExpiresAt = h.expiresAt(session.data.CreatedAt, session.data.LastSeen)
+	session.ttl = time.Until(session.data.ExpiresAt)
+	if err := h.saveSessionToStore(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Serves a request with the session cookie and returns the session the handler saw, if any
+func serveSession(h *SessionsHandler, cookie *http.Cookie) (*httptest.ResponseRecorder, *Session) {
+	var seen *Session
+	handler := h.SessionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = r.Context().Value(sessionContextKey{}).(*Session)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec, seen
+}
+
+func responseSessionCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == session_cookie_name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestSessionRenewal(t *testing.T) {
+	tests := []struct {
+		name     string
+		created  time.Duration
+		lastSeen time.Duration
+		// Zero when the session should not be renewed
+		wantExpiry time.Duration
+		wantGone   bool
+	}{
+		{name: "recently seen", created: time.Hour, lastSeen: time.Minute},
+		{name: "idle", created: time.Hour, lastSeen: time.Minute * 10, wantExpiry: testSessionsConfig.IdleTTL},
+		{name: "near absolute limit", created: time.Hour * 23, lastSeen: time.Minute * 10, wantExpiry: time.Minute * 59},
+		{name: "past idle limit", created: time.Hour * 3, lastSeen: time.Hour * 2, wantGone: true},
+		{name: "past absolute limit", created: time.Hour * 25, lastSeen: time.Minute * 10, wantGone: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestSessions(t, testSessionsConfig)
+			session, cookie := newTestSession(t, h)
+			ageTestSession(t, h, session, tt.created, tt.created, tt.lastSeen)
+			expiresAt := session.data.ExpiresAt
+
+			rec, seen := serveSession(h, cookie)
+			if tt.wantGone {
+				if seen != nil {
+					t.Fatal("expired session was accepted")
+				}
+				return
+			}
+			if seen == nil {
+				t.Fatal("session was not accepted")
+			}
+
+			stored, err := h.getSessionFromStore(context.Background(), session.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantExpiry == 0 {
+				if responseSessionCookie(rec) != nil {
+					t.Fatal("recently seen session was renewed")
+				}
+				if !stored.data.ExpiresAt.Equal(expiresAt) {
+					t.Fatalf("session expires at %v, want unchanged %v", stored.data.ExpiresAt, expiresAt)
+				}
+				return
+			}
+
+			if responseSessionCookie(rec) == nil {
+				t.Fatal("renewed session was not sent back")
+			}
+			want := time.Now().Add(tt.wantExpiry)
+			if diff := stored.data.ExpiresAt.Sub(want); diff < -time.Minute || diff > time.Minute {
+				t.Fatalf("session expires at %v, want about %v", stored.data.ExpiresAt, want)
+			}
+		})
+	}
+}
+
+func TestSessionRotation(t *testing.T) {
+	cfg := testSessionsConfig
+	cfg.RotationInterval = time.Hour
+	h := newTestSessions(t, cfg)
+	session, cookie := newTestSession(t, h)
+	ageTestSession(t, h, session, time.Hour*2, time.Hour*2, time.Minute*10)
+
+	rec, _ := serveSession(h, cookie)
+	rotated := responseSessionCookie(rec)
+	if rotated == nil || rotated.Value == cookie.Value {
+		t.Fatal("session older than the rotation interval kept its id")
+	}
+
+	// The retired id stays usable for the grace period without being rotated or listed again
+	rec, seen := serveSession(h, cookie)
+	if seen == nil {
+		t.Fatal("retired id was rejected during the grace period")
+	}
+	if responseSessionCookie(rec) != nil {
+		t.Fatal("retired id was rotated again")
+	}
+	retired, err := h.getSessionFromStore(context.Background(), cookie.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retired.ttl > rotationGracePeriod {
+		t.Fatalf("retired id lives for %v, want at most %v", retired.ttl, rotationGracePeriod)
+	}
+
+	userSessions, err := h.GetUserSessions(context.Background(), session.GetUserId())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(userSessions) != 1 || userSessions[0].Id != rotated.Value {
+		t.Fatalf("user has %v listed sessions, want only the rotated one", len(userSessions))
+	}
+
+	if _, seen := serveSession(h, rotated); seen == nil {
+		t.Fatal("rotated id was rejected")
+	}
+}
+
+func TestSessionRotationDisabled(t *testing.T) {
+	h := newTestSessions(t, testSessionsConfig)
+	session, cookie := newTestSession(t, h)
+	ageTestSession(t, h, session, time.Hour*2, time.Hour*2, time.Minute*10)
+
+	rec, _ := serveSession(h, cookie)
+	if renewed := responseSessionCookie(rec); renewed == nil || renewed.Value != cookie.Value {
+		t.Fatal("session was not renewed under the same id")
+	}
+}
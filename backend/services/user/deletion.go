@@ -0,0 +1,170 @@
+package user
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+// Time a user has to change their mind before their data is removed
+const accountDeletionGracePeriod = 30 * 24 * time.Hour
+
+// Schedules the account for deletion and signs the user out everywhere.
+// Signing in again during the grace period allows the deletion to be cancelled.
+func (h *UserHandler) RequestAccountDeletion(ctx context.Context, session *sessions.Session, userId *uuid.UUID) (*models.UserDeletion, error) {
+	if err := session.RequireInteractive(); err != nil {
+		return nil, err
+	}
+
+	deletion := &models.UserDeletion{ScheduledAt: time.Now().Add(accountDeletionGracePeriod)}
+	err := db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		user, err := h.AuthorizeModifyUser(ctx, pq, session, userId)
+		if err != nil {
+			return err
+		}
+		if user.DeletionScheduledAt != nil {
+			return services.NewDataConflictServiceError(nil, "Account deletion is already scheduled")
+		}
+		if err := requireNoOwnedBusinesses(ctx, pq, userId); err != nil {
+			return err
+		}
+		if user.HasRole(models.USER_ROLE_ADMIN) {
+			admins, err := pq.CountUsersWithRoleForUpdate(ctx, models.USER_ROLE_ADMIN)
+			if err != nil {
+				return err
+			}
+			if admins <= 1 {
+				return services.NewDataConflictServiceError(nil, "Can not delete the last admin")
+			}
+		}
+
+		if err := pq.SetUserDeletionSchedule(ctx, userId, &deletion.ScheduledAt); err != nil {
+			return err
+		}
+		return pq.RevokeAPITokensForUser(ctx, userId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h.logger.Info("Scheduled account deletion", "user_id", userId, "scheduled_at", deletion.ScheduledAt)
+	if err := h.sessions.RevokeUserSessions(ctx, userId); err != nil {
+		return nil, err
+	}
+	return deletion, nil
+}
+
+// Purged users can not own a business, so ownership has to be handed off before deletion
+func requireNoOwnedBusinesses(ctx context.Context, pq *db.PgxQueries, userId *uuid.UUID) error {
+	owned, err := pq.CountBusinessesOwnedByUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if owned > 0 {
+		return services.NewDataConflictServiceError(nil, "Transfer your businesses to another user before deleting your account")
+	}
+	return nil
+}
+
+func (h *UserHandler) CancelAccountDeletion(ctx context.Context, session *sessions.Session, userId *uuid.UUID) error {
+	if err := session.RequireInteractive(); err != nil {
+		return err
+	}
+
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		user, err := h.AuthorizeModifyUser(ctx, pq, session, userId)
+		if err != nil {
+			return err
+		}
+		if user.DeletionScheduledAt == nil {
+			return services.NewDataConflictServiceError(nil, "Account deletion is not scheduled")
+		}
+
+		h.logger.Info("Cancelled account deletion", "user_id", userId)
+		return pq.SetUserDeletionSchedule(ctx, userId, nil)
+	})
+}
+
+// Periodically removes the data of accounts whose deletion grace period has passed
+type AccountPurgeService struct {
+	logger   *slog.Logger
+	store    *db.PgxStore
+	sessions *sessions.SessionsHandler
+	interval time.Duration
+	done     chan struct{}
+}
+
+func NewAccountPurgeService(logger *slog.Logger, store *db.PgxStore, sessions *sessions.SessionsHandler, interval time.Duration) *AccountPurgeService {
+	return &AccountPurgeService{
+		logger:   logger,
+		store:    store,
+		sessions: sessions,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Background service interface implementations
+func (s *AccountPurgeService) Start() {
+	go s.run()
+}
+
+func (s *AccountPurgeService) Stop() {
+	close(s.done)
+}
+
+func (s *AccountPurgeService) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.purgeDueAccounts(context.Background())
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *AccountPurgeService) purgeDueAccounts(ctx context.Context) {
+	userIds, err := db.WithTxRet(ctx, s.store, func(pq *db.PgxQueries) ([]uuid.UUID, error) {
+		return pq.GetUsersDueForDeletion(ctx)
+	})
+	if err != nil {
+		s.logger.Warn("Failed to query accounts due for deletion", "err", err)
+		return
+	}
+
+	for _, userId := range userIds {
+		if err := s.purgeAccount(ctx, &userId); err != nil {
+			s.logger.Warn("Failed to purge account", "user_id", userId, "err", err)
+		}
+	}
+}
+
+func (s *AccountPurgeService) purgeAccount(ctx context.Context, userId *uuid.UUID) error {
+	err := db.WithTx(ctx, s.store, func(pq *db.PgxQueries) error {
+		// A business may have been transferred to the user while the deletion was pending, the
+		// purge is retried once it has been handed off again
+		if err := requireNoOwnedBusinesses(ctx, pq, userId); err != nil {
+			return err
+		}
+		if err := pq.WithdrawPendingApplicationsForUser(ctx, userId); err != nil {
+			return err
+		}
+		return pq.AnonymizeUser(ctx, userId)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Purged account", "user_id", userId)
+
+	return s.sessions.RevokeUserSessions(ctx, userId)
+}
@@ -0,0 +1,93 @@
+package user
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+// Collects everything stored about the user for a personal data export
+func (h *UserHandler) ExportUserData(ctx context.Context, session *sessions.Session, userId *uuid.UUID) (*models.UserExport, error) {
+	if err := session.RequireInteractive(); err != nil {
+		return nil, err
+	}
+
+	h.logger.Info("Exporting user data", "user_id", userId)
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.UserExport, error) {
+		user, err := h.AuthorizeModifyUser(ctx, pq, session, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		businesses, err := pq.GetBusinesses(ctx, &models.BusinessQueryParams{UserId: userId})
+		if err != nil {
+			return nil, err
+		}
+		posts, err := pq.GetPostsForMember(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		applications, err := pq.GetUserApplications(ctx, &models.UserApplicationQueryParams{UserId: userId})
+		if err != nil {
+			return nil, err
+		}
+		tokens, err := pq.GetAPITokens(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		reports, err := pq.GetReports(ctx, &models.ReportQueryParams{ReporterId: userId})
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.UserExport{
+			ExportedAt:   time.Now(),
+			User:         user,
+			Businesses:   businesses,
+			Posts:        posts,
+			Applications: applications,
+			APITokens:    tokens,
+			Reports:      reports,
+		}, nil
+	})
+}
+
+// Writes the export as an archive with one JSON file per section
+func writeExportZip(w io.Writer, export *models.UserExport) error {
+	files := []struct {
+		name string
+		data any
+	}{
+		{"user.json", export.User},
+		{"businesses.json", export.Businesses},
+		{"posts.json", export.Posts},
+		{"applications.json", export.Applications},
+		{"api_tokens.json", export.APITokens},
+		{"reports.json", export.Reports},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,57 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+// Checks that the session may start impersonating the user, the session itself is started by the
+// sessions handler
+func (h *UserHandler) AuthorizeImpersonation(ctx context.Context, session *sessions.Session, userId *uuid.UUID, data *models.ImpersonationCreate) error {
+	if err := models.ValidateData(data); err != nil {
+		return err
+	}
+
+	return h.authorizeUserAction(ctx, session, USER_ACTION_IMPERSONATE, userId)
+}
+
+func (h *UserHandler) GetImpersonationLog(ctx context.Context, session *sessions.Session, params *models.ImpersonationLogQueryParams) ([]models.ImpersonationLogEntry, error) {
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_READ_AUDIT_LOG, nil); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.ImpersonationLogEntry, error) {
+		return pq.GetImpersonationLog(ctx, params)
+	})
+}
+
+// Impersonation auditor interface implementation
+func (h *UserHandler) RecordImpersonation(ctx context.Context, entry *models.ImpersonationLogEntry) error {
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		return pq.CreateImpersonationLogEntry(ctx, entry)
+	})
+}
+
+// Impersonator validator interface implementation
+func (h *UserHandler) ValidateImpersonator(ctx context.Context, impersonatorId *uuid.UUID) error {
+	impersonator, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.User, error) {
+		return pq.GetUserForId(ctx, impersonatorId)
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNoRows) {
+			return services.NewServiceError(sessions.ErrImpersonationRevoked, http.StatusForbidden, nil)
+		}
+		return err
+	}
+	if !impersonator.HasRole(models.USER_ROLE_ADMIN) {
+		return services.NewServiceError(sessions.ErrImpersonationRevoked, http.StatusForbidden, nil)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+// Implements sessions.UserValidator
+func (h *UserHandler) ValidateUser(ctx context.Context, userId *uuid.UUID) error {
+	status, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.UserStatusInfo, error) {
+		return pq.GetUserStatus(ctx, userId)
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNoRows) {
+			return services.NewUnauthenticatedServiceError(err)
+		}
+		return err
+	}
+
+	if status.IsBlocked() {
+		return services.NewServiceError(sessions.ErrUserBlocked, http.StatusForbidden, status)
+	}
+	return nil
+}
+
+// Bans are for serious abuse, so the user's pending applications are withdrawn and their
+// businesses disabled. Reinstating does not re-enable businesses, they must be reinstated individually.
+func (h *UserHandler) BanUser(ctx context.Context, session *sessions.Session, userId *uuid.UUID, data *models.UserModeration) error {
+	return h.moderateUser(ctx, session, userId, models.USER_STATUS_BANNED, data)
+}
+
+func (h *UserHandler) SuspendUser(ctx context.Context, session *sessions.Session, userId *uuid.UUID, data *models.UserModeration) error {
+	return h.moderateUser(ctx, session, userId, models.USER_STATUS_DISABLED, data)
+}
+
+func (h *UserHandler) ReinstateUser(ctx context.Context, session *sessions.Session, userId *uuid.UUID) error {
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_MODERATE, userId); err != nil {
+		return err
+	}
+
+	h.logger.Info("Reinstating user", "user_id", userId, "admin_id", session.GetUserId())
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		return pq.SetUserStatus(ctx, userId, models.USER_STATUS_ACTIVE, nil, nil, session.GetUserId())
+	})
+}
+
+func (h *UserHandler) moderateUser(ctx context.Context, session *sessions.Session, userId *uuid.UUID, status models.UserStatus, data *models.UserModeration) error {
+	if err := models.ValidateData(data); err != nil {
+		return err
+	}
+	if data.ExpiresAt != nil && !data.ExpiresAt.After(time.Now()) {
+		return services.NewValidationServiceError(nil, services.ValidationErrMap{
+			"expires_at": services.ValidationErrData{Value: data.ExpiresAt, Tag: "future"},
+		})
+	}
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_MODERATE, userId); err != nil {
+		return err
+	}
+
+	h.logger.Info("Moderating user", "user_id", userId, "status", status, "admin_id", session.GetUserId(), "expires_at", data.ExpiresAt)
+	err := db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		target, err := pq.GetUserForId(ctx, userId)
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		if target.HasRole(models.USER_ROLE_ADMIN) {
+			return services.NewDataConflictServiceError(nil, "Can not moderate an admin")
+		}
+
+		if err := pq.SetUserStatus(ctx, userId, status, &data.Reason, data.ExpiresAt, session.GetUserId()); err != nil {
+			return err
+		}
+		if status == models.USER_STATUS_BANNED {
+			if err := pq.WithdrawPendingApplicationsForUser(ctx, userId); err != nil {
+				return err
+			}
+			if err := pq.DisableBusinessesForUser(ctx, userId, &data.Reason, session.GetUserId()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.sessions.RevokeUserSessions(ctx, userId)
+}
+
+// Periodically resets suspensions that have run out, status checks on requests already treat them as active
+type UserStatusExpiryService struct {
+	logger   *slog.Logger
+	store    *db.PgxStore
+	interval time.Duration
+	done     chan struct{}
+}
+
+func NewUserStatusExpiryService(logger *slog.Logger, store *db.PgxStore, interval time.Duration) *UserStatusExpiryService {
+	return &UserStatusExpiryService{
+		logger:   logger,
+		store:    store,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Background service interface implementations
+func (s *UserStatusExpiryService) Start() {
+	go s.run()
+}
+
+func (s *UserStatusExpiryService) Stop() {
+	close(s.done)
+}
+
+func (s *UserStatusExpiryService) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.clearExpiredStatuses(context.Background())
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *UserStatusExpiryService) clearExpiredStatuses(ctx context.Context) {
+	cleared, err := db.WithTxRet(ctx, s.store, func(pq *db.PgxQueries) (int64, error) {
+		return pq.ClearExpiredUserStatuses(ctx)
+	})
+	if err != nil {
+		s.logger.Warn("Failed to clear expired user statuses", "err", err)
+		return
+	}
+	if cleared > 0 {
+		s.logger.Info("Cleared expired user statuses", "count", cleared)
+	}
+}
@@ -0,0 +1,62 @@
+package user
+
+import (
+	"bytes"
+	"html/template"
+	"path/filepath"
+	"time"
+
+	"github.com/john-vh/college_testing/backend/models"
+)
+
+type studentVerificationCodeNotification struct {
+	recipient    *models.User
+	schoolEmail  string
+	code         string
+	expiresIn    time.Duration
+	templatePath string
+}
+
+func (h *UserHandler) newStudentVerificationCodeNotification(recipient *models.User, schoolEmail, code string) *studentVerificationCodeNotification {
+	const templateName = "StudentVerificationCode"
+	return &studentVerificationCodeNotification{
+		recipient:    recipient,
+		schoolEmail:  schoolEmail,
+		code:         code,
+		expiresIn:    h.studentVerification.CodeTTL,
+		templatePath: filepath.Join(h.notificationsTemplatesDir, templateName) + ".html",
+	}
+}
+
+func (n *studentVerificationCodeNotification) ShouldNotify() bool { return true }
+func (n *studentVerificationCodeNotification) To() *models.User   { return n.recipient }
+func (n *studentVerificationCodeNotification) Address() string    { return n.schoolEmail }
+func (n *studentVerificationCodeNotification) Subject() string    { return "Verify Your School Email" }
+func (n *studentVerificationCodeNotification) HTML() (string, error) {
+	type templateData struct {
+		RecipientName    string
+		SchoolEmail      string
+		Code             string
+		ExpiresInMinutes int
+	}
+
+	data := templateData{
+		RecipientName:    n.recipient.Name,
+		SchoolEmail:      n.schoolEmail,
+		Code:             n.code,
+		ExpiresInMinutes: int(n.expiresIn.Minutes()),
+	}
+
+	t, err := template.ParseFiles(n.templatePath)
+	if err != nil {
+		return "", err
+	}
+
+	var res bytes.Buffer
+	err = t.Execute(&res, data)
+	if err != nil {
+		return "", err
+	}
+
+	return res.String(), nil
+}
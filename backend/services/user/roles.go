@@ -0,0 +1,60 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+func (h *UserHandler) GrantRole(ctx context.Context, session *sessions.Session, userId *uuid.UUID, role models.UserRole) error {
+	if !slices.Contains(models.UserRoles, role) {
+		return services.NewNotFoundServiceError(nil)
+	}
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_MANAGE_ROLES, userId); err != nil {
+		return err
+	}
+
+	h.logger.Info("Granting role", "user_id", userId, "role", role, "admin_id", session.GetUserId())
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		return pq.GrantUserRole(ctx, userId, role)
+	})
+}
+
+func (h *UserHandler) RevokeRole(ctx context.Context, session *sessions.Session, userId *uuid.UUID, role models.UserRole) error {
+	if !slices.Contains(models.UserRoles, role) {
+		return services.NewNotFoundServiceError(nil)
+	}
+	if role == models.USER_ROLE_USER {
+		return services.NewDataConflictServiceError(nil, "Can not revoke the user role")
+	}
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_MANAGE_ROLES, userId); err != nil {
+		return err
+	}
+
+	h.logger.Info("Revoking role", "user_id", userId, "role", role, "admin_id", session.GetUserId())
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		if role == models.USER_ROLE_ADMIN {
+			admins, err := pq.CountUsersWithRoleForUpdate(ctx, models.USER_ROLE_ADMIN)
+			if err != nil {
+				return err
+			}
+			if admins <= 1 {
+				return services.NewDataConflictServiceError(nil, "Can not revoke the last admin")
+			}
+		}
+
+		if err := pq.RevokeUserRole(ctx, userId, role); err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		return nil
+	})
+}
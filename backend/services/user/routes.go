@@ -1,16 +1,60 @@
 package user
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+)
+
+const (
+	sessionIdParam = "sessionId"
+	userIdParam    = "userId"
+	providerParam  = "provider"
+	accountIdParam = "accountId"
+	tokenIdParam   = "tokenId"
+	roleParam      = "role"
 )
 
 func (h *UserHandler) RegisterRoutes(router *http.ServeMux) {
 	router.HandleFunc("GET /users", h.handleErr(h.handleGetUsers))
 	router.HandleFunc("PATCH /users/0", h.handleErr(h.handleUpdateUser))
+	router.HandleFunc("DELETE /users/0", h.handleErr(h.handleRequestAccountDeletion))
+	router.HandleFunc("POST /users/0/restore", h.handleErr(h.handleCancelAccountDeletion))
+	router.HandleFunc("GET /users/0/export", h.handleErr(h.handleExportUserData))
+	router.HandleFunc("GET /users/0/accounts", h.handleErr(h.handleGetAccounts))
+	router.HandleFunc("POST /users/0/accounts/{provider}/{accountId}/primary", h.handleErr(h.handleSetPrimaryAccount))
+	router.HandleFunc("DELETE /users/0/accounts/{provider}/{accountId}", h.handleErr(h.handleUnlinkAccount))
+	router.HandleFunc("GET /users/0/tokens", h.handleErr(h.handleGetAPITokens))
+	router.HandleFunc("POST /users/0/tokens", h.handleErr(h.handleCreateAPIToken))
+	router.HandleFunc("DELETE /users/0/tokens/{tokenId}", h.handleErr(h.handleRevokeAPIToken))
+	router.HandleFunc("GET /users/0/student-verification", h.handleErr(h.handleGetStudentVerification))
+	router.HandleFunc("POST /users/0/student-verification", h.handleErr(h.handleRequestStudentVerification))
+	router.HandleFunc("POST /users/0/student-verification/confirm", h.handleErr(h.handleConfirmStudentVerification))
+	router.HandleFunc("GET /users/0/sessions", h.handleErr(h.handleGetSessions))
+	router.HandleFunc("DELETE /users/0/sessions/{sessionId}", h.handleErr(h.handleRevokeSession))
+	router.HandleFunc("GET /users/0/impersonation", h.handleErr(h.handleGetImpersonation))
+	router.HandleFunc("DELETE /users/0/impersonation", h.handleErr(h.handleEndImpersonation))
+
+	router.HandleFunc("GET /admin/users", h.handleErr(h.handleQueryUsers))
+	router.HandleFunc("GET /admin/users/{userId}/email-history", h.handleErr(h.handleGetAccountEmailHistory))
+	router.HandleFunc("POST /admin/users/{userId}/impersonate", h.handleErr(h.handleImpersonateUser))
+	router.HandleFunc("GET /admin/impersonations", h.handleErr(h.handleGetImpersonationLog))
+	router.HandleFunc("DELETE /admin/users/{userId}/sessions", h.handleErr(h.handleRevokeUserSessions))
+	router.HandleFunc("PUT /admin/users/{userId}/roles/{role}", h.handleErr(h.handleGrantRole))
+	router.HandleFunc("DELETE /admin/users/{userId}/roles/{role}", h.handleErr(h.handleRevokeRole))
+	router.HandleFunc("POST /admin/users/{userId}/ban", h.handleErr(h.handleModerateUser(h.BanUser)))
+	router.HandleFunc("POST /admin/users/{userId}/suspend", h.handleErr(h.handleModerateUser(h.SuspendUser)))
+	router.HandleFunc("POST /admin/users/{userId}/reinstate", h.handleErr(h.handleReinstateUser))
+	router.HandleFunc("PUT /admin/users/{userId}/student-verification", h.handleErr(h.handleOverrideStudentVerification))
+	router.HandleFunc("DELETE /admin/users/{userId}/student-verification", h.handleErr(h.handleRevokeStudentVerification))
 }
 
 func (h *UserHandler) handleGetUsers(w http.ResponseWriter, r *http.Request) error {
@@ -56,3 +100,480 @@ func (h *UserHandler) handleUpdateUser(w http.ResponseWriter, r *http.Request) e
 
 	return nil
 }
+
+func (h *UserHandler) handleRequestAccountDeletion(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	deletion, err := h.RequestAccountDeletion(r.Context(), session, session.GetUserId())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deletion)
+	return nil
+}
+
+func (h *UserHandler) handleCancelAccountDeletion(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.CancelAccountDeletion(r.Context(), session, session.GetUserId())
+}
+
+func (h *UserHandler) handleExportUserData(w http.ResponseWriter, r *http.Request) error {
+	const (
+		param_format string = "format"
+	)
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	export, err := h.ExportUserData(r.Context(), session, session.GetUserId())
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("testhive-export-%v", export.ExportedAt.Format("2006-01-02"))
+	if r.URL.Query().Get(param_format) == "zip" {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".zip"))
+		return writeExportZip(w, export)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+	json.NewEncoder(w).Encode(export)
+	return nil
+}
+
+func (h *UserHandler) handleGetAccounts(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := h.GetAccounts(r.Context(), session, session.GetUserId())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+	return nil
+}
+
+func (h *UserHandler) handleSetPrimaryAccount(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.SetPrimaryAccount(r.Context(), session, session.GetUserId(), r.PathValue(providerParam), r.PathValue(accountIdParam))
+}
+
+func (h *UserHandler) handleUnlinkAccount(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.UnlinkAccount(r.Context(), session, session.GetUserId(), r.PathValue(providerParam), r.PathValue(accountIdParam))
+}
+
+func (h *UserHandler) handleGetAPITokens(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := h.GetAPITokens(r.Context(), session, session.GetUserId())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+	return nil
+}
+
+func (h *UserHandler) handleCreateAPIToken(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.APITokenCreate{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	token, err := h.CreateAPIToken(r.Context(), session, session.GetUserId(), &data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(token)
+	return nil
+}
+
+func (h *UserHandler) handleRevokeAPIToken(w http.ResponseWriter, r *http.Request) error {
+	tokenId, err := uuid.Parse(r.PathValue(tokenIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.RevokeAPIToken(r.Context(), session, session.GetUserId(), &tokenId)
+}
+
+func (h *UserHandler) handleGetSessions(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	userSessions, err := h.GetSessions(r.Context(), session, session.GetUserId())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(userSessions)
+	return nil
+}
+
+func (h *UserHandler) handleRevokeSession(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.RevokeSession(r.Context(), session, session.GetUserId(), r.PathValue(sessionIdParam))
+}
+
+func (h *UserHandler) handleQueryUsers(w http.ResponseWriter, r *http.Request) error {
+	const (
+		param_role           = "role"
+		param_status         = "status"
+		param_email_verified = "email_verified"
+	)
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	params := models.UserQueryParams{}
+	if r.URL.Query().Has(param_role) {
+		role := models.UserRole(r.URL.Query().Get(param_role))
+		if !slices.Contains(models.UserRoles, role) {
+			return services.NewBadRequestServiceError(nil)
+		}
+		params.Role = &role
+	}
+	if r.URL.Query().Has(param_status) {
+		status := models.UserStatus(r.URL.Query().Get(param_status))
+		switch status {
+		case models.USER_STATUS_ACTIVE, models.USER_STATUS_BANNED, models.USER_STATUS_DISABLED:
+		default:
+			return services.NewBadRequestServiceError(nil)
+		}
+		params.Status = &status
+	}
+	if r.URL.Query().Has(param_email_verified) {
+		verified, err := strconv.ParseBool(r.URL.Query().Get(param_email_verified))
+		if err != nil {
+			return services.NewBadRequestServiceError(err)
+		}
+		params.EmailVerified = &verified
+	}
+
+	users, err := h.QueryUsers(r.Context(), session, &params)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+	return nil
+}
+
+func (h *UserHandler) handleGetAccountEmailHistory(w http.ResponseWriter, r *http.Request) error {
+	userId, err := uuid.Parse(r.PathValue(userIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	history, err := h.GetAccountEmailHistory(r.Context(), session, &userId)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+	return nil
+}
+
+func (h *UserHandler) handleImpersonateUser(w http.ResponseWriter, r *http.Request) error {
+	userId, err := uuid.Parse(r.PathValue(userIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.ImpersonationCreate{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	if err := h.AuthorizeImpersonation(r.Context(), session, &userId, &data); err != nil {
+		return err
+	}
+
+	impersonation, err := h.sessions.StartImpersonation(w, r, &userId, data.Reason)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(impersonation.Impersonation())
+	return nil
+}
+
+func (h *UserHandler) handleGetImpersonation(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	impersonation := session.Impersonation()
+	if impersonation == nil {
+		return services.NewNotFoundServiceError(nil)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(impersonation)
+	return nil
+}
+
+func (h *UserHandler) handleEndImpersonation(w http.ResponseWriter, r *http.Request) error {
+	return h.sessions.EndImpersonation(w, r)
+}
+
+func (h *UserHandler) handleGetImpersonationLog(w http.ResponseWriter, r *http.Request) error {
+	const (
+		param_user         = "user"
+		param_impersonator = "impersonator"
+	)
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	params := models.ImpersonationLogQueryParams{}
+	if r.URL.Query().Has(param_user) {
+		if id, err := uuid.Parse(r.URL.Query().Get(param_user)); err == nil {
+			params.UserId = &id
+		}
+	}
+	if r.URL.Query().Has(param_impersonator) {
+		if id, err := uuid.Parse(r.URL.Query().Get(param_impersonator)); err == nil {
+			params.ImpersonatorId = &id
+		}
+	}
+
+	entries, err := h.GetImpersonationLog(r.Context(), session, &params)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+	return nil
+}
+
+func (h *UserHandler) handleRevokeUserSessions(w http.ResponseWriter, r *http.Request) error {
+	userId, err := uuid.Parse(r.PathValue(userIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.RevokeAllSessions(r.Context(), session, &userId)
+}
+
+func (h *UserHandler) handleGetStudentVerification(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	verification, err := h.GetStudentVerification(r.Context(), session, session.GetUserId())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verification)
+	return nil
+}
+
+func (h *UserHandler) handleRequestStudentVerification(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.StudentVerificationRequest{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	if err := h.RequestStudentVerification(r.Context(), session, session.GetUserId(), &data); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+func (h *UserHandler) handleConfirmStudentVerification(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.StudentVerificationConfirm{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	verification, err := h.ConfirmStudentVerification(r.Context(), session, session.GetUserId(), &data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verification)
+	return nil
+}
+
+func (h *UserHandler) handleOverrideStudentVerification(w http.ResponseWriter, r *http.Request) error {
+	userId, err := uuid.Parse(r.PathValue(userIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	data := models.StudentVerificationOverride{}
+	if err := models.ReadRequestJson(r, &data); err != nil {
+		return err
+	}
+
+	return h.OverrideStudentVerification(r.Context(), session, &userId, &data)
+}
+
+func (h *UserHandler) handleRevokeStudentVerification(w http.ResponseWriter, r *http.Request) error {
+	userId, err := uuid.Parse(r.PathValue(userIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.RevokeStudentVerification(r.Context(), session, &userId)
+}
+
+func (h *UserHandler) handleModerateUser(moderate func(context.Context, *sessions.Session, *uuid.UUID, *models.UserModeration) error) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		userId, err := uuid.Parse(r.PathValue(userIdParam))
+		if err != nil {
+			return services.NewNotFoundServiceError(err)
+		}
+
+		session, err := h.sessions.GetSession(r)
+		if err != nil {
+			return err
+		}
+
+		data := models.UserModeration{}
+		if err := models.ReadRequestJson(r, &data); err != nil {
+			return err
+		}
+
+		return moderate(r.Context(), session, &userId, &data)
+	}
+}
+
+func (h *UserHandler) handleReinstateUser(w http.ResponseWriter, r *http.Request) error {
+	userId, err := uuid.Parse(r.PathValue(userIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.ReinstateUser(r.Context(), session, &userId)
+}
+
+func (h *UserHandler) handleGrantRole(w http.ResponseWriter, r *http.Request) error {
+	userId, err := uuid.Parse(r.PathValue(userIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.GrantRole(r.Context(), session, &userId, models.UserRole(r.PathValue(roleParam)))
+}
+
+func (h *UserHandler) handleRevokeRole(w http.ResponseWriter, r *http.Request) error {
+	userId, err := uuid.Parse(r.PathValue(userIdParam))
+	if err != nil {
+		return services.NewNotFoundServiceError(err)
+	}
+
+	session, err := h.sessions.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	return h.RevokeRole(r.Context(), session, &userId, models.UserRole(r.PathValue(roleParam)))
+}
@@ -0,0 +1,258 @@
+package user
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/cache"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+	"github.com/john-vh/college_testing/backend/util"
+)
+
+const (
+	studentCodeLength      = 6
+	studentCodeMaxAttempts = 5
+	studentCodeResendDelay = time.Minute
+)
+
+type StudentVerificationConfig struct {
+	// School email domain suffixes that are accepted, e.g. "edu" or "ac.uk"
+	Domains []string
+	// How long a mailed code can be confirmed
+	CodeTTL time.Duration
+	// How long a confirmed verification lasts before the student must verify again
+	ValidFor time.Duration
+}
+
+type pendingStudentVerification struct {
+	Email    string    `json:"email"`
+	CodeHash string    `json:"code_hash"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+func studentVerificationKey(userId *uuid.UUID) string {
+	return "student_verification:" + userId.String()
+}
+
+// Failed confirmations are counted separately so concurrent attempts can not overwrite each other
+func studentVerificationAttemptsKey(userId *uuid.UUID) string {
+	return "student_verification_attempts:" + userId.String()
+}
+
+func (h *UserHandler) GetStudentVerification(ctx context.Context, session *sessions.Session, userId *uuid.UUID) (*models.StudentVerification, error) {
+	if err := session.RequireScope(models.TOKEN_SCOPE_USER_READ); err != nil {
+		return nil, err
+	}
+
+	user, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.User, error) {
+		return h.AuthorizeModifyUser(ctx, pq, session, userId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if user.StudentVerification == nil {
+		return nil, services.NewNotFoundServiceError(nil)
+	}
+
+	return user.StudentVerification, nil
+}
+
+// Mails a one-time code to the school email, the verification is recorded once the code is confirmed
+func (h *UserHandler) RequestStudentVerification(ctx context.Context, session *sessions.Session, userId *uuid.UUID, data *models.StudentVerificationRequest) error {
+	if err := session.RequireInteractive(); err != nil {
+		return err
+	}
+
+	if err := models.ValidateData(data); err != nil {
+		return err
+	}
+
+	key := studentVerificationKey(userId)
+	if prev, err := h.getPendingStudentVerification(ctx, key); err == nil && time.Since(prev.SentAt) < studentCodeResendDelay {
+		return services.NewDataConflictServiceError(nil, "A code was sent recently, try again shortly")
+	}
+
+	user, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.User, error) {
+		user, err := h.AuthorizeModifyUser(ctx, pq, session, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !user.EmailVerified {
+			return nil, services.NewDataConflictServiceError(nil, "Primary account email must be verified by its provider")
+		}
+		if !models.IsSchoolEmail(data.Email, h.studentVerification.Domains) {
+			// Registered institutions may use domains outside the configured suffixes
+			if _, err := pq.GetInstitutionForEmail(ctx, data.Email); err != nil {
+				if errors.Is(err, db.ErrNoRows) {
+					return nil, services.NewValidationServiceError(err, services.ValidationErrMap{
+						"email": services.ValidationErrData{Value: data.Email, Tag: "school_email"},
+					})
+				}
+				return nil, err
+			}
+		}
+		claimed, err := pq.IsSchoolEmailClaimed(ctx, userId, data.Email)
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			return nil, services.NewDataConflictServiceError(nil, "School email is already verified by another user")
+		}
+		return user, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	code, err := util.RandDigits(studentCodeLength)
+	if err != nil {
+		return err
+	}
+	pending, err := json.Marshal(pendingStudentVerification{
+		Email:    data.Email,
+		CodeHash: util.HashToken(code),
+		SentAt:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := h.cache.Set(ctx, key, pending, h.studentVerification.CodeTTL); err != nil {
+		return err
+	}
+	if err := h.cache.Delete(ctx, studentVerificationAttemptsKey(userId)); err != nil {
+		return err
+	}
+
+	h.logger.Info("Sending student verification code", "user_id", userId)
+	return h.notifications.EnqueueWithTimeout(ctx, h.newStudentVerificationCodeNotification(user, data.Email, code))
+}
+
+func (h *UserHandler) ConfirmStudentVerification(ctx context.Context, session *sessions.Session, userId *uuid.UUID, data *models.StudentVerificationConfirm) (*models.StudentVerification, error) {
+	if err := session.RequireInteractive(); err != nil {
+		return nil, err
+	}
+
+	if err := models.ValidateData(data); err != nil {
+		return nil, err
+	}
+
+	key := studentVerificationKey(userId)
+	pending, err := h.getPendingStudentVerification(ctx, key)
+	if err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil, services.NewDataConflictServiceError(err, "No pending student verification")
+		}
+		return nil, err
+	}
+
+	// Each confirmation uses up an attempt before the code is compared, so guesses can not race past the limit
+	attemptsKey := studentVerificationAttemptsKey(userId)
+	attempts, err := h.cache.Increment(ctx, attemptsKey, h.studentVerification.CodeTTL)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > studentCodeMaxAttempts {
+		h.cache.Delete(ctx, key, attemptsKey)
+		return nil, services.NewDataConflictServiceError(nil, "No pending student verification")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(util.HashToken(data.Code)), []byte(pending.CodeHash)) != 1 {
+		if attempts >= studentCodeMaxAttempts {
+			h.cache.Delete(ctx, key, attemptsKey)
+		}
+		return nil, services.NewValidationServiceError(nil, services.ValidationErrMap{
+			"code": services.ValidationErrData{Value: data.Code, Tag: "invalid"},
+		})
+	}
+
+	now := time.Now()
+	verification := &models.StudentVerification{
+		Method:      models.STUDENT_VERIFICATION_METHOD_EMAIL,
+		SchoolEmail: &pending.Email,
+		VerifiedAt:  now,
+		ExpiresAt:   now.Add(h.studentVerification.ValidFor),
+	}
+	err = db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		if _, err := h.AuthorizeModifyUser(ctx, pq, session, userId); err != nil {
+			return err
+		}
+		claimed, err := pq.IsSchoolEmailClaimed(ctx, userId, pending.Email)
+		if err != nil {
+			return err
+		}
+		if claimed {
+			return services.NewDataConflictServiceError(nil, "School email is already verified by another user")
+		}
+		if err := pq.SetStudentVerification(ctx, userId, verification); err != nil {
+			return err
+		}
+		return pq.SyncUserInstitutions(ctx, userId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h.cache.Delete(ctx, key, attemptsKey)
+	h.logger.Info("Verified student", "user_id", userId)
+	return verification, nil
+}
+
+// Lets an admin mark a user as a student without a school email, e.g. after checking enrollment documents
+func (h *UserHandler) OverrideStudentVerification(ctx context.Context, session *sessions.Session, userId *uuid.UUID, data *models.StudentVerificationOverride) error {
+	if err := models.ValidateData(data); err != nil {
+		return err
+	}
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_VERIFY_STUDENT, userId); err != nil {
+		return err
+	}
+
+	h.logger.Info("Overriding student verification", "user_id", userId, "admin_id", session.GetUserId())
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		err := pq.SetStudentVerification(ctx, userId, &models.StudentVerification{
+			Method:     models.STUDENT_VERIFICATION_METHOD_ADMIN,
+			VerifiedBy: session.GetUserId(),
+			VerifiedAt: time.Now(),
+			ExpiresAt:  data.ExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+		return pq.SyncUserInstitutions(ctx, userId)
+	})
+}
+
+func (h *UserHandler) RevokeStudentVerification(ctx context.Context, session *sessions.Session, userId *uuid.UUID) error {
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_VERIFY_STUDENT, userId); err != nil {
+		return err
+	}
+
+	h.logger.Info("Revoking student verification", "user_id", userId, "admin_id", session.GetUserId())
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		if err := pq.DeleteStudentVerification(ctx, userId); err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		return pq.SyncUserInstitutions(ctx, userId)
+	})
+}
+
+func (h *UserHandler) getPendingStudentVerification(ctx context.Context, key string) (*pendingStudentVerification, error) {
+	b, err := h.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	pending := pendingStudentVerification{}
+	if err := json.Unmarshal(b, &pending); err != nil {
+		return nil, err
+	}
+	return &pending, nil
+}
@@ -0,0 +1,115 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/cache"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+	"github.com/john-vh/college_testing/backend/util"
+)
+
+const testStudentCode = "123456"
+
+// Only confirmations that fail before reaching the database are exercised, so no store is set
+func newTestStudentHandler(t *testing.T, codeTTL time.Duration) *UserHandler {
+	t.Helper()
+	memoryCache := cache.NewMemoryCache(0)
+	t.Cleanup(memoryCache.Close)
+	return &UserHandler{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cache:  memoryCache,
+		studentVerification: StudentVerificationConfig{
+			Domains:  []string{"edu"},
+			CodeTTL:  codeTTL,
+			ValidFor: time.Hour * 24 * 365,
+		},
+	}
+}
+
+func setTestStudentCode(t *testing.T, h *UserHandler, userId *uuid.UUID) {
+	t.Helper()
+	pending, err := json.Marshal(pendingStudentVerification{
+		Email:    "student@school.edu",
+		CodeHash: util.HashToken(testStudentCode),
+		SentAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.cache.Set(context.Background(), studentVerificationKey(userId), pending, h.studentVerification.CodeTTL); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func confirmStatus(t *testing.T, h *UserHandler, userId *uuid.UUID, code string) int {
+	t.Helper()
+	_, err := h.ConfirmStudentVerification(context.Background(), &sessions.Session{}, userId, &models.StudentVerificationConfirm{Code: code})
+	var serviceErr *services.ServiceError
+	if !errors.As(err, &serviceErr) {
+		t.Fatalf("confirmation returned %v, want a service error", err)
+	}
+	return serviceErr.StatusCode()
+}
+
+func TestStudentCodeAttemptLimit(t *testing.T) {
+	h := newTestStudentHandler(t, time.Hour)
+	userId := uuid.New()
+	setTestStudentCode(t, h, &userId)
+
+	for i := 1; i <= studentCodeMaxAttempts; i++ {
+		if status := confirmStatus(t, h, &userId, "000000"); status != http.StatusBadRequest {
+			t.Fatalf("wrong code on attempt %v returned %v, want %v", i, status, http.StatusBadRequest)
+		}
+	}
+
+	// The last failed attempt discards the code, so even the right one is refused afterwards
+	if status := confirmStatus(t, h, &userId, testStudentCode); status != http.StatusConflict {
+		t.Fatalf("right code after the attempt limit returned %v, want %v", status, http.StatusConflict)
+	}
+	if _, err := h.cache.Get(context.Background(), studentVerificationAttemptsKey(&userId)); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("attempt counter was kept after the code was discarded: %v", err)
+	}
+}
+
+func TestStudentCodeAttemptsArePerUser(t *testing.T) {
+	h := newTestStudentHandler(t, time.Hour)
+	userId, otherId := uuid.New(), uuid.New()
+	setTestStudentCode(t, h, &userId)
+	setTestStudentCode(t, h, &otherId)
+
+	for i := 0; i < studentCodeMaxAttempts; i++ {
+		confirmStatus(t, h, &otherId, "000000")
+	}
+	if status := confirmStatus(t, h, &userId, "000000"); status != http.StatusBadRequest {
+		t.Fatalf("wrong code for another user returned %v, want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestStudentCodeExpiry(t *testing.T) {
+	h := newTestStudentHandler(t, time.Millisecond*50)
+	userId := uuid.New()
+	setTestStudentCode(t, h, &userId)
+
+	if status := confirmStatus(t, h, &userId, "000000"); status != http.StatusBadRequest {
+		t.Fatalf("wrong code before expiry returned %v, want %v", status, http.StatusBadRequest)
+	}
+	time.Sleep(time.Millisecond * 100)
+
+	if status := confirmStatus(t, h, &userId, testStudentCode); status != http.StatusConflict {
+		t.Fatalf("expired code returned %v, want %v", status, http.StatusConflict)
+	}
+	// Attempts expire with the code, so a new code starts with a full budget
+	if _, err := h.cache.Get(context.Background(), studentVerificationAttemptsKey(&userId)); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("attempt counter outlived the code: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/db"
+	"github.com/john-vh/college_testing/backend/models"
+	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/sessions"
+	"github.com/john-vh/college_testing/backend/util"
+)
+
+const (
+	apiTokenPrefix = "th_"
+	// Last use is only recorded at this granularity
+	apiTokenUsedInterval = time.Minute
+)
+
+func (h *UserHandler) GetAPITokens(ctx context.Context, session *sessions.Session, userId *uuid.UUID) ([]models.APIToken, error) {
+	if err := session.RequireInteractive(); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.APIToken, error) {
+		if _, err := h.AuthorizeModifyUser(ctx, pq, session, userId); err != nil {
+			return nil, err
+		}
+		return pq.GetAPITokens(ctx, userId)
+	})
+}
+
+func (h *UserHandler) CreateAPIToken(ctx context.Context, session *sessions.Session, userId *uuid.UUID, data *models.APITokenCreate) (*models.APITokenCreated, error) {
+	if err := session.RequireInteractive(); err != nil {
+		return nil, err
+	}
+
+	if err := models.ValidateData(data); err != nil {
+		return nil, err
+	}
+
+	secret, err := util.RandString(32)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := apiTokenPrefix + secret
+	expiresAt := time.Now().AddDate(0, 0, data.ExpiresInDays)
+
+	token, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.APIToken, error) {
+		if _, err := h.AuthorizeModifyUser(ctx, pq, session, userId); err != nil {
+			return nil, err
+		}
+		return pq.CreateAPIToken(ctx, userId, data, util.HashToken(plaintext), expiresAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h.logger.Info("Created API token", "user_id", userId, "token_id", token.Id, "scopes", token.Scopes)
+	return &models.APITokenCreated{APIToken: *token, Token: plaintext}, nil
+}
+
+func (h *UserHandler) RevokeAPIToken(ctx context.Context, session *sessions.Session, userId *uuid.UUID, tokenId *uuid.UUID) error {
+	if err := session.RequireInteractive(); err != nil {
+		return err
+	}
+
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		if _, err := h.AuthorizeModifyUser(ctx, pq, session, userId); err != nil {
+			return err
+		}
+		if err := pq.RevokeAPIToken(ctx, userId, tokenId); err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		h.logger.Info("Revoked API token", "user_id", userId, "token_id", tokenId)
+		return nil
+	})
+}
+
+// Implements sessions.TokenAuthenticator
+func (h *UserHandler) AuthenticateToken(ctx context.Context, token string) (*models.APIToken, error) {
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.APIToken, error) {
+		apiToken, err := pq.GetAPITokenForHash(ctx, util.HashToken(token))
+		if err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return nil, services.NewUnauthenticatedServiceError(err)
+			}
+			return nil, err
+		}
+		if !apiToken.IsActive() {
+			return nil, services.NewUnauthenticatedServiceError(nil)
+		}
+		usedBefore := time.Now().Add(-apiTokenUsedInterval)
+		if apiToken.LastUsedAt == nil || apiToken.LastUsedAt.Before(usedBefore) {
+			if err := pq.SetAPITokenUsed(ctx, &apiToken.Id, usedBefore); err != nil {
+				return nil, err
+			}
+		}
+		return apiToken, nil
+	})
+}
@@ -4,19 +4,26 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
 
 	"github.com/google/uuid"
+	"github.com/john-vh/college_testing/backend/cache"
 	"github.com/john-vh/college_testing/backend/db"
 	"github.com/john-vh/college_testing/backend/models"
 	"github.com/john-vh/college_testing/backend/services"
+	"github.com/john-vh/college_testing/backend/services/notifications"
 	"github.com/john-vh/college_testing/backend/services/sessions"
 )
 
 type UserHandler struct {
-	logger    *slog.Logger
-	sessions  *sessions.SessionsHandler
-	store     *db.PgxStore
-	handleErr services.ServicesHTTPErrorHandler
+	logger                    *slog.Logger
+	sessions                  *sessions.SessionsHandler
+	store                     *db.PgxStore
+	cache                     cache.Cache
+	notifications             *notifications.NotificationsService
+	notificationsTemplatesDir string
+	studentVerification       StudentVerificationConfig
+	handleErr                 services.ServicesHTTPErrorHandler
 }
 
 func NewUserHandler(
@@ -24,16 +31,28 @@ func NewUserHandler(
 	errHandler services.ServicesHTTPErrorHandler,
 	sessions *sessions.SessionsHandler,
 	store *db.PgxStore,
+	cache cache.Cache,
+	notifications *notifications.NotificationsService,
+	notificationsTemplatesDir string,
+	studentVerification StudentVerificationConfig,
 ) *UserHandler {
 	return &UserHandler{
-		logger:    logger,
-		sessions:  sessions,
-		store:     store,
-		handleErr: errHandler,
+		logger:                    logger,
+		sessions:                  sessions,
+		store:                     store,
+		cache:                     cache,
+		notifications:             notifications,
+		notificationsTemplatesDir: notificationsTemplatesDir,
+		studentVerification:       studentVerification,
+		handleErr:                 errHandler,
 	}
 }
 
 func (h *UserHandler) GetUserById(ctx context.Context, session *sessions.Session, id *uuid.UUID) (*models.User, error) {
+	if err := session.RequireScope(models.TOKEN_SCOPE_USER_READ); err != nil {
+		return nil, err
+	}
+
 	user, err := db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) (*models.User, error) {
 		return h.AuthorizeModifyUser(ctx, pq, session, id)
 	})
@@ -46,6 +65,10 @@ func (h *UserHandler) GetUserById(ctx context.Context, session *sessions.Session
 }
 
 func (h *UserHandler) UpdateUser(ctx context.Context, session *sessions.Session, id *uuid.UUID, data *models.UserUpdate) error {
+	if err := session.RequireInteractive(); err != nil {
+		return err
+	}
+
 	if err := models.ValidateData(data); err != nil {
 		return err
 	}
@@ -65,6 +88,159 @@ func (h *UserHandler) UpdateUser(ctx context.Context, session *sessions.Session,
 	})
 }
 
+func (h *UserHandler) GetAccounts(ctx context.Context, session *sessions.Session, userId *uuid.UUID) ([]models.UserAccount, error) {
+	if err := session.RequireScope(models.TOKEN_SCOPE_USER_READ); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.UserAccount, error) {
+		if _, err := h.AuthorizeModifyUser(ctx, pq, session, userId); err != nil {
+			return nil, err
+		}
+		return pq.GetUserAccounts(ctx, userId)
+	})
+}
+
+func (h *UserHandler) SetPrimaryAccount(ctx context.Context, session *sessions.Session, userId *uuid.UUID, provider string, accountId string) error {
+	if err := session.RequireInteractive(); err != nil {
+		return err
+	}
+
+	h.logger.Info("Setting primary account", "user_id", userId, "account_provider", provider, "account_id", accountId)
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		if _, err := h.AuthorizeModifyUser(ctx, pq, session, userId); err != nil {
+			return err
+		}
+		if err := pq.SetPrimaryAccount(ctx, userId, provider, accountId); err != nil {
+			if errors.Is(err, db.ErrNoRows) {
+				return services.NewNotFoundServiceError(err)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func (h *UserHandler) UnlinkAccount(ctx context.Context, session *sessions.Session, userId *uuid.UUID, provider string, accountId string) error {
+	if err := session.RequireInteractive(); err != nil {
+		return err
+	}
+
+	h.logger.Info("Unlinking account", "user_id", userId, "account_provider", provider, "account_id", accountId)
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		if _, err := h.AuthorizeModifyUser(ctx, pq, session, userId); err != nil {
+			return err
+		}
+
+		accounts, err := pq.GetUserAccounts(ctx, userId)
+		if err != nil {
+			return err
+		}
+		idx := slices.IndexFunc(accounts, func(a models.UserAccount) bool {
+			return a.Provider == provider && a.Id == accountId
+		})
+		if idx < 0 {
+			return services.NewNotFoundServiceError(nil)
+		}
+		if len(accounts) == 1 {
+			return services.NewDataConflictServiceError(nil, "Can not unlink the only linked account")
+		}
+		if accounts[idx].IsPrimary {
+			return services.NewDataConflictServiceError(nil, "Can not unlink the primary account")
+		}
+
+		if err := pq.UnlinkAccount(ctx, userId, provider, accountId); err != nil {
+			return err
+		}
+		return pq.SyncUserInstitutions(ctx, userId)
+	})
+}
+
+func (h *UserHandler) GetSessions(ctx context.Context, session *sessions.Session, userId *uuid.UUID) ([]sessions.SessionInfo, error) {
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_READ_SESSIONS, userId); err != nil {
+		return nil, err
+	}
+
+	userSessions, err := h.sessions.GetUserSessions(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]sessions.SessionInfo, 0, len(userSessions))
+	for _, s := range userSessions {
+		info := s.Info()
+		info.Current = s.Id == session.Id
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (h *UserHandler) RevokeSession(ctx context.Context, session *sessions.Session, userId *uuid.UUID, sessionId string) error {
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_REVOKE_SESSIONS, userId); err != nil {
+		return err
+	}
+
+	h.logger.Info("Revoking session", "user_id", userId, "session_id", sessionId)
+	return h.sessions.RevokeUserSession(ctx, userId, sessionId)
+}
+
+func (h *UserHandler) RevokeAllSessions(ctx context.Context, session *sessions.Session, userId *uuid.UUID) error {
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_REVOKE_SESSIONS, userId); err != nil {
+		return err
+	}
+
+	return h.sessions.RevokeUserSessions(ctx, userId)
+}
+
+func (h *UserHandler) QueryUsers(ctx context.Context, session *sessions.Session, params *models.UserQueryParams) ([]models.User, error) {
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_READ_USERS, nil); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.User, error) {
+		return pq.QueryUsers(ctx, params)
+	})
+}
+
+func (h *UserHandler) GetAccountEmailHistory(ctx context.Context, session *sessions.Session, userId *uuid.UUID) ([]models.AccountEmailChange, error) {
+	if err := h.authorizeUserAction(ctx, session, USER_ACTION_READ_USERS, userId); err != nil {
+		return nil, err
+	}
+
+	return db.WithTxRet(ctx, h.store, func(pq *db.PgxQueries) ([]models.AccountEmailChange, error) {
+		return pq.GetAccountEmailHistory(ctx, userId)
+	})
+}
+
+func (h *UserHandler) authorizeUserAction(ctx context.Context, session *sessions.Session, action UserAction, targetId *uuid.UUID) error {
+	sUserId := session.GetUserId()
+	if sUserId == nil {
+		return services.NewUnauthenticatedServiceError(nil)
+	}
+	if err := session.RequireInteractive(); err != nil {
+		return err
+	}
+
+	return db.WithTx(ctx, h.store, func(pq *db.PgxQueries) error {
+		user, err := pq.GetUserForId(ctx, sUserId)
+		if err != nil {
+			return services.NewUnauthenticatedServiceError(err)
+		}
+		// Actions that do not concern a single user have no target
+		var target *models.User
+		if targetId != nil {
+			target, err = pq.GetUserForId(ctx, targetId)
+			if err != nil {
+				if errors.Is(err, db.ErrNoRows) {
+					return services.NewNotFoundServiceError(err)
+				}
+				return err
+			}
+		}
+		return AuthorizeUserAction(user, action, target)
+	})
+}
+
 func (h *UserHandler) AuthorizeModifyUser(ctx context.Context, pq *db.PgxQueries, session *sessions.Session, userId *uuid.UUID) (*models.User, error) {
 	sUserId := session.GetUserId()
 	if sUserId == nil {
@@ -83,3 +259,63 @@ func (h *UserHandler) AuthorizeModifyUser(ctx context.Context, pq *db.PgxQueries
 
 	return nil, services.NewUnauthorizedServiceError(nil)
 }
+
+type UserAction string
+
+const (
+	USER_ACTION_READ_SESSIONS   UserAction = "user:read_sessions"
+	USER_ACTION_REVOKE_SESSIONS UserAction = "user:revoke_sessions"
+	USER_ACTION_VERIFY_STUDENT  UserAction = "user:verify_student"
+	USER_ACTION_MODERATE        UserAction = "user:moderate"
+	USER_ACTION_MANAGE_ROLES    UserAction = "user:manage_roles"
+	USER_ACTION_READ_USERS      UserAction = "user:read_users"
+	USER_ACTION_IMPERSONATE     UserAction = "user:impersonate"
+	USER_ACTION_READ_AUDIT_LOG  UserAction = "user:read_audit_log"
+)
+
+func AuthorizeUserAction(user *models.User, action UserAction, target *models.User) error {
+	if user == nil {
+		return services.NewUnauthenticatedServiceError(nil)
+	}
+
+	for _, role := range user.Roles {
+		switch role {
+		case models.USER_ROLE_ADMIN:
+			switch action {
+			case USER_ACTION_READ_SESSIONS:
+				return nil
+			case USER_ACTION_REVOKE_SESSIONS:
+				return nil
+			case USER_ACTION_VERIFY_STUDENT:
+				return nil
+			case USER_ACTION_MODERATE:
+				return nil
+			case USER_ACTION_MANAGE_ROLES:
+				return nil
+			case USER_ACTION_READ_USERS:
+				return nil
+			case USER_ACTION_IMPERSONATE:
+				// Only active users without elevated roles may be impersonated
+				if target != nil && target.Id != user.Id && target.Status == models.USER_STATUS_ACTIVE &&
+					!target.HasRole(models.USER_ROLE_ADMIN) && !target.HasRole(models.USER_ROLE_MODERATOR) {
+					return nil
+				}
+			case USER_ACTION_READ_AUDIT_LOG:
+				return nil
+			}
+		case models.USER_ROLE_USER:
+			switch action {
+			case USER_ACTION_READ_SESSIONS:
+				if target != nil && target.Id == user.Id {
+					return nil
+				}
+			case USER_ACTION_REVOKE_SESSIONS:
+				if target != nil && target.Id == user.Id {
+					return nil
+				}
+			}
+		}
+	}
+
+	return services.NewUnauthorizedServiceError(nil)
+}
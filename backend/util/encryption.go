@@ -2,8 +2,11 @@ package util
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"io"
+	"math/big"
 )
 
 func RandString(nByte uint32) (string, error) {
@@ -13,3 +16,22 @@ func RandString(nByte uint32) (string, error) {
 	}
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns a random numeric code of n digits, suitable for one-time codes sent by mail
+func RandDigits(n int) (string, error) {
+	b := make([]byte, n)
+	max := big.NewInt(10)
+	for i := range b {
+		d, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = '0' + byte(d.Int64())
+	}
+	return string(b), nil
+}